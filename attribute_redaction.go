@@ -0,0 +1,196 @@
+package ldclient
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"regexp"
+	"strings"
+	"unicode/utf8"
+)
+
+// AttributeRedactionAction describes how an AttributeRedactionRule transforms an attribute value.
+type AttributeRedactionAction int
+
+const (
+	// RedactionRemove removes the attribute, exactly as if it were named in Config.PrivateAttributeNames.
+	RedactionRemove AttributeRedactionAction = iota
+	// RedactionHash replaces the attribute value with the hex-encoded SHA-256 hash of the value. For string
+	// values, the hash is computed from the string itself; for any other type, it is computed from the
+	// value's JSON representation.
+	RedactionHash
+	// RedactionMask replaces every substring of a string value that matches MaskPattern with asterisks. If
+	// MaskPattern is nil, the whole string is masked. Values that are not strings are not changed.
+	RedactionMask
+	// RedactionRemoveNestedKeys removes properties whose names are in NestedKeys from a custom attribute
+	// whose value is a JSON object, at any depth (including objects within arrays).
+	RedactionRemoveNestedKeys
+)
+
+// AttributeRedactionRule describes a transformation that is applied to user attributes before they are
+// included in analytics events. Rules are configured with Config.AttributeRedactionRules, and apply to
+// index, identify, feature, and custom events alike. They never affect flag evaluations.
+//
+// A rule applies either to a single attribute, specified by Attribute, or to all custom attributes whose
+// names match AttributePattern. The user key is never affected.
+//
+//     config := ld.DefaultConfig
+//     config.AttributeRedactionRules = []ld.AttributeRedactionRule{
+//         {Attribute: "email", Action: ld.RedactionHash},
+//         {Attribute: "phone", Action: ld.RedactionMask, MaskPattern: regexp.MustCompile(`^\d{6}`)},
+//         {AttributePattern: regexp.MustCompile(`^secret_`), Action: ld.RedactionRemove},
+//     }
+type AttributeRedactionRule struct {
+	// Attribute is the name of a built-in or custom attribute.
+	Attribute string
+	// AttributePattern, if not nil, is matched against custom attribute names, and Attribute is ignored.
+	AttributePattern *regexp.Regexp
+	// Action is the transformation to apply.
+	Action AttributeRedactionAction
+	// MaskPattern is used by RedactionMask.
+	MaskPattern *regexp.Regexp
+	// NestedKeys is used by RedactionRemoveNestedKeys.
+	NestedKeys []string
+}
+
+func (r AttributeRedactionRule) matches(attr string, isCustom bool) bool {
+	if attr == "key" {
+		return false
+	}
+	if r.AttributePattern != nil {
+		return isCustom && r.AttributePattern.MatchString(attr)
+	}
+	return r.Attribute == attr
+}
+
+// Applies the configured redaction rules to a user that has already been copied by scrubUser. Any attributes
+// that are removed are added to PrivateAttributes.
+func (uf *userFilter) applyRedactionRules(u *User) {
+	for _, attr := range []struct {
+		name  string
+		value **string
+	}{
+		{"secondary", &u.Secondary},
+		{"ip", &u.Ip},
+		{"country", &u.Country},
+		{"email", &u.Email},
+		{"firstName", &u.FirstName},
+		{"lastName", &u.LastName},
+		{"avatar", &u.Avatar},
+		{"name", &u.Name},
+	} {
+		if isEmpty(*attr.value) {
+			continue
+		}
+		s := **attr.value
+		removed := false
+		for _, rule := range uf.redactionRules {
+			if !rule.matches(attr.name, false) {
+				continue
+			}
+			if rule.Action == RedactionRemove {
+				removed = true
+				break
+			}
+			if v, ok := rule.redactValue(s).(string); ok {
+				s = v
+			}
+		}
+		if removed {
+			*attr.value = nil
+			u.PrivateAttributes = append(u.PrivateAttributes, attr.name)
+		} else if s != **attr.value {
+			*attr.value = &s
+		}
+	}
+
+	if u.Custom == nil {
+		return
+	}
+	custom := make(map[string]interface{}, len(*u.Custom))
+	for k, v := range *u.Custom {
+		removed := false
+		for _, rule := range uf.redactionRules {
+			if !rule.matches(k, true) {
+				continue
+			}
+			if rule.Action == RedactionRemove {
+				removed = true
+				break
+			}
+			v = rule.redactValue(v)
+		}
+		if removed {
+			u.PrivateAttributes = append(u.PrivateAttributes, k)
+		} else {
+			custom[k] = v
+		}
+	}
+	if len(custom) > 0 {
+		u.Custom = &custom
+	} else {
+		u.Custom = nil
+	}
+}
+
+// Returns a transformed copy of an attribute value. The original value is never modified.
+func (r AttributeRedactionRule) redactValue(value interface{}) interface{} {
+	switch r.Action {
+	case RedactionHash:
+		s, ok := value.(string)
+		if !ok {
+			data, err := json.Marshal(value)
+			if err != nil {
+				return nil
+			}
+			s = string(data)
+		}
+		h := sha256.Sum256([]byte(s))
+		return hex.EncodeToString(h[:])
+	case RedactionMask:
+		s, ok := value.(string)
+		if !ok {
+			return value
+		}
+		if r.MaskPattern == nil {
+			return maskString(s)
+		}
+		return r.MaskPattern.ReplaceAllStringFunc(s, maskString)
+	case RedactionRemoveNestedKeys:
+		return removeNestedKeys(value, r.NestedKeys)
+	}
+	return value
+}
+
+func maskString(s string) string {
+	return strings.Repeat("*", utf8.RuneCountInString(s))
+}
+
+func removeNestedKeys(value interface{}, keys []string) interface{} {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		ret := make(map[string]interface{}, len(v))
+		for k, item := range v {
+			if !containsString(keys, k) {
+				ret[k] = removeNestedKeys(item, keys)
+			}
+		}
+		return ret
+	case []interface{}:
+		ret := make([]interface{}, 0, len(v))
+		for _, item := range v {
+			ret = append(ret, removeNestedKeys(item, keys))
+		}
+		return ret
+	}
+	return value
+}
+
+func containsString(values []string, s string) bool {
+	for _, v := range values {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,151 @@
+package ldclient
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"regexp"
+	"sort"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"gopkg.in/launchdarkly/go-sdk-common.v1/ldvalue"
+)
+
+func sha256Hex(s string) string {
+	h := sha256.Sum256([]byte(s))
+	return hex.EncodeToString(h[:])
+}
+
+func TestRedactionRules(t *testing.T) {
+	t.Run("hash built-in attribute", func(t *testing.T) {
+		filter := newUserFilter(Config{AttributeRedactionRules: []AttributeRedactionRule{
+			{Attribute: "email", Action: RedactionHash},
+		}})
+		user := NewUserBuilder("user-key").Email("me@example.com").Name("sam").Build()
+
+		scrubbedUser := *filter.scrubUser(user)
+
+		assert.Equal(t, sha256Hex("me@example.com"), *scrubbedUser.Email)
+		assert.Equal(t, "sam", *scrubbedUser.Name)
+		assert.Nil(t, scrubbedUser.PrivateAttributes)
+		assert.Equal(t, "me@example.com", *user.Email) // original user is not modified
+	})
+
+	t.Run("hash non-string custom attribute", func(t *testing.T) {
+		filter := newUserFilter(Config{AttributeRedactionRules: []AttributeRedactionRule{
+			{Attribute: "age", Action: RedactionHash},
+		}})
+		user := NewUserBuilder("user-key").Custom("age", ldvalue.Int(42)).Build()
+
+		scrubbedUser := *filter.scrubUser(user)
+
+		assert.Equal(t, sha256Hex("42"), (*scrubbedUser.Custom)["age"])
+	})
+
+	t.Run("mask substrings", func(t *testing.T) {
+		filter := newUserFilter(Config{AttributeRedactionRules: []AttributeRedactionRule{
+			{Attribute: "phone", Action: RedactionMask, MaskPattern: regexp.MustCompile(`^\d{6}`)},
+			{Attribute: "name", Action: RedactionMask},
+		}})
+		user := NewUserBuilder("user-key").Name("sam").Custom("phone", ldvalue.String("5551234567")).Build()
+
+		scrubbedUser := *filter.scrubUser(user)
+
+		assert.Equal(t, "******4567", (*scrubbedUser.Custom)["phone"])
+		assert.Equal(t, "***", *scrubbedUser.Name)
+	})
+
+	t.Run("remove nested keys", func(t *testing.T) {
+		filter := newUserFilter(Config{AttributeRedactionRules: []AttributeRedactionRule{
+			{Attribute: "address", Action: RedactionRemoveNestedKeys, NestedKeys: []string{"street", "zip"}},
+		}})
+		address := ldvalue.ObjectBuild().
+			Set("street", ldvalue.String("1 Main St")).
+			Set("city", ldvalue.String("Oakland")).
+			Set("previous", ldvalue.ArrayOf(ldvalue.ObjectBuild().Set("zip", ldvalue.String("94612")).Build())).
+			Build()
+		user := NewUserBuilder("user-key").Custom("address", address).Build()
+
+		scrubbedUser := *filter.scrubUser(user)
+
+		expected := map[string]interface{}{
+			"city":     "Oakland",
+			"previous": []interface{}{map[string]interface{}{}},
+		}
+		assert.Equal(t, expected, (*scrubbedUser.Custom)["address"])
+		original, _ := user.GetCustom("address")
+		assert.Equal(t, "1 Main St", original.GetByKey("street").StringValue())
+	})
+
+	t.Run("remove custom attributes by pattern", func(t *testing.T) {
+		filter := newUserFilter(Config{AttributeRedactionRules: []AttributeRedactionRule{
+			{AttributePattern: regexp.MustCompile(`^secret_`), Action: RedactionRemove},
+		}})
+		user := NewUserBuilder("user-key").
+			Custom("secret_a", ldvalue.String("x")).
+			Custom("secret_b", ldvalue.String("y")).
+			Custom("public", ldvalue.String("z")).
+			Build()
+
+		scrubbedUser := *filter.scrubUser(user)
+
+		sort.Strings(scrubbedUser.PrivateAttributes)
+		assert.Equal(t, []string{"secret_a", "secret_b"}, scrubbedUser.PrivateAttributes)
+		assert.Equal(t, map[string]interface{}{"public": "z"}, *scrubbedUser.Custom)
+	})
+
+	t.Run("pattern does not match built-in attributes", func(t *testing.T) {
+		filter := newUserFilter(Config{AttributeRedactionRules: []AttributeRedactionRule{
+			{AttributePattern: regexp.MustCompile(`.*`), Action: RedactionRemove},
+		}})
+		user := NewUserBuilder("user-key").Email("me@example.com").Build()
+
+		scrubbedUser := *filter.scrubUser(user)
+
+		assert.Equal(t, user, scrubbedUser.User)
+	})
+
+	t.Run("key is never redacted", func(t *testing.T) {
+		filter := newUserFilter(Config{AttributeRedactionRules: []AttributeRedactionRule{
+			{Attribute: "key", Action: RedactionHash},
+		}})
+		user := NewUser("user-key")
+
+		scrubbedUser := *filter.scrubUser(user)
+
+		assert.Equal(t, "user-key", *scrubbedUser.Key)
+	})
+
+	t.Run("rules are applied after private attributes", func(t *testing.T) {
+		filter := newUserFilter(Config{
+			PrivateAttributeNames:   []string{"email"},
+			AttributeRedactionRules: []AttributeRedactionRule{{Attribute: "email", Action: RedactionHash}},
+		})
+		user := NewUserBuilder("user-key").Email("me@example.com").Build()
+
+		scrubbedUser := *filter.scrubUser(user)
+
+		assert.Nil(t, scrubbedUser.Email)
+		assert.Equal(t, []string{"email"}, scrubbedUser.PrivateAttributes)
+	})
+}
+
+func TestRedactionRulesAreAppliedToEvents(t *testing.T) {
+	config := epDefaultConfig
+	config.InlineUsersInEvents = true
+	config.AttributeRedactionRules = []AttributeRedactionRule{{Attribute: "name", Action: RedactionHash}}
+	ep, st := createEventProcessor(config)
+	defer ep.Close()
+
+	hashedUserJSON := map[string]interface{}{"key": "userKey", "name": sha256Hex("Red")}
+	ie := NewIdentifyEvent(epDefaultUser)
+	ce := NewCustomEvent("eventkey", epDefaultUser, nil)
+	ep.SendEvent(ie)
+	ep.SendEvent(ce)
+
+	output := flushAndGetEvents(ep, st)
+	if assert.Equal(t, 2, len(output)) {
+		assertIdentifyEventMatches(t, ie, hashedUserJSON, output[0])
+		assert.Equal(t, hashedUserJSON, output[1]["user"])
+	}
+}
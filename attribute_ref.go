@@ -0,0 +1,134 @@
+package ldclient
+
+import (
+	"bytes"
+	"strconv"
+	"strings"
+)
+
+// An attribute reference is the string that identifies a user attribute in Clause.Attribute, Rollout.BucketBy,
+// or a list of private attribute names.
+//
+// If the string does not begin with "/", it is the name of a top-level built-in or custom attribute, exactly
+// as it always was; no other characters in it are interpreted specially.
+//
+// If the string begins with "/", it is a path of slash-delimited components. The first component is the name
+// of a top-level attribute, and each following component is either a property name within a JSON object or,
+// if the value at that point is an array, a zero-based array index. For instance, "/address/city" refers to
+// the "city" property of the custom attribute "address". A literal "/" or "~" within a component is written
+// as "~1" or "~0" respectively, as in JSON Pointer (RFC 6901).
+
+// Parses an attribute reference into its components. The second return value is false if the reference
+// is not valid: that is, if it is an empty path or contains an invalid escape sequence.
+func parseAttributeRef(ref string) ([]string, bool) {
+	if !strings.HasPrefix(ref, "/") {
+		return []string{ref}, ref != ""
+	}
+	parts := strings.Split(ref[1:], "/")
+	for i, p := range parts {
+		if p == "" {
+			return nil, false
+		}
+		if strings.Contains(p, "~") {
+			unescaped, ok := unescapeAttributeRefComponent(p)
+			if !ok {
+				return nil, false
+			}
+			parts[i] = unescaped
+		}
+	}
+	return parts, true
+}
+
+func unescapeAttributeRefComponent(s string) (string, bool) {
+	var b bytes.Buffer
+	for i := 0; i < len(s); i++ {
+		if s[i] != '~' {
+			b.WriteByte(s[i])
+			continue
+		}
+		if i+1 >= len(s) {
+			return "", false
+		}
+		switch s[i+1] {
+		case '0':
+			b.WriteByte('~')
+		case '1':
+			b.WriteByte('/')
+		default:
+			return "", false
+		}
+		i++
+	}
+	return b.String(), true
+}
+
+// Finds the value at a path within a JSON-like value (as represented by encoding/json: maps, slices,
+// and scalars).
+func getValueAtPath(value interface{}, path []string) (interface{}, bool) {
+	for _, component := range path {
+		switch v := value.(type) {
+		case map[string]interface{}:
+			item, ok := v[component]
+			if !ok {
+				return nil, false
+			}
+			value = item
+		case []interface{}:
+			index, err := strconv.Atoi(component)
+			if err != nil || index < 0 || index >= len(v) {
+				return nil, false
+			}
+			value = v[index]
+		default:
+			return nil, false
+		}
+	}
+	return value, true
+}
+
+// Returns a copy of a JSON-like value with the property at the specified path removed, and true if there
+// was such a property. Only object properties can be removed; a path that ends in an array index, or that
+// does not exist, leaves the value unchanged. The original value is never modified.
+func removeValueAtPath(value interface{}, path []string) (interface{}, bool) {
+	if len(path) == 0 {
+		return value, false
+	}
+	switch v := value.(type) {
+	case map[string]interface{}:
+		item, ok := v[path[0]]
+		if !ok {
+			return value, false
+		}
+		var newItem interface{}
+		if len(path) > 1 {
+			if newItem, ok = removeValueAtPath(item, path[1:]); !ok {
+				return value, false
+			}
+		}
+		ret := make(map[string]interface{}, len(v))
+		for k, x := range v {
+			ret[k] = x
+		}
+		if len(path) == 1 {
+			delete(ret, path[0])
+		} else {
+			ret[path[0]] = newItem
+		}
+		return ret, true
+	case []interface{}:
+		index, err := strconv.Atoi(path[0])
+		if err != nil || index < 0 || index >= len(v) || len(path) == 1 {
+			return value, false
+		}
+		newItem, ok := removeValueAtPath(v[index], path[1:])
+		if !ok {
+			return value, false
+		}
+		ret := make([]interface{}, len(v))
+		copy(ret, v)
+		ret[index] = newItem
+		return ret, true
+	}
+	return value, false
+}
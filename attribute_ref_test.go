@@ -0,0 +1,87 @@
+package ldclient
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"gopkg.in/launchdarkly/go-sdk-common.v1/ldvalue"
+)
+
+func TestParseAttributeRef(t *testing.T) {
+	for _, tt := range []struct {
+		ref      string
+		expected []string
+		ok       bool
+	}{
+		{"name", []string{"name"}, true},
+		{"a/b", []string{"a/b"}, true},
+		{"/name", []string{"name"}, true},
+		{"/address/city", []string{"address", "city"}, true},
+		{"/a~1b/c~0d", []string{"a/b", "c~d"}, true},
+		{"", nil, false},
+		{"/", nil, false},
+		{"/a//b", nil, false},
+		{"/a~2", nil, false},
+		{"/a~", nil, false},
+	} {
+		t.Run(tt.ref, func(t *testing.T) {
+			path, ok := parseAttributeRef(tt.ref)
+			assert.Equal(t, tt.ok, ok)
+			if tt.ok {
+				assert.Equal(t, tt.expected, path)
+			}
+		})
+	}
+}
+
+func TestUserValueOfAttributeRef(t *testing.T) {
+	address := ldvalue.ObjectBuild().
+		Set("city", ldvalue.String("Oakland")).
+		Set("lines", ldvalue.ArrayOf(ldvalue.String("1 Main St"), ldvalue.String("Apt 2"))).
+		Build()
+	user := NewUserBuilder("key").Name("Bob").Custom("address", address).Build()
+
+	for _, tt := range []struct {
+		ref      string
+		expected interface{}
+		found    bool
+	}{
+		{"name", "Bob", true},
+		{"/name", "Bob", true},
+		{"/key", "key", true},
+		{"/address/city", "Oakland", true},
+		{"/address/lines/1", "Apt 2", true},
+		{"/address/lines/2", nil, false},
+		{"/address/lines/x", nil, false},
+		{"/address/zip", nil, false},
+		{"/name/first", nil, false},
+		{"/missing/city", nil, false},
+	} {
+		t.Run(tt.ref, func(t *testing.T) {
+			value, found := user.valueOf(tt.ref)
+			assert.Equal(t, tt.found, found)
+			assert.Equal(t, tt.expected, value)
+		})
+	}
+}
+
+func TestRemoveValueAtPathDoesNotModifyOriginal(t *testing.T) {
+	original := map[string]interface{}{
+		"a": map[string]interface{}{"b": "x", "c": "y"},
+		"d": []interface{}{map[string]interface{}{"e": "z"}},
+	}
+
+	result, ok := removeValueAtPath(original, []string{"a", "b"})
+	assert.True(t, ok)
+	assert.Equal(t, map[string]interface{}{"c": "y"}, result.(map[string]interface{})["a"])
+	assert.Equal(t, "x", original["a"].(map[string]interface{})["b"])
+
+	result, ok = removeValueAtPath(original, []string{"d", "0", "e"})
+	assert.True(t, ok)
+	assert.Equal(t, []interface{}{map[string]interface{}{}}, result.(map[string]interface{})["d"])
+	assert.Equal(t, "z", original["d"].([]interface{})[0].(map[string]interface{})["e"])
+
+	result, ok = removeValueAtPath(original, []string{"a", "missing"})
+	assert.False(t, ok)
+	assert.Equal(t, original, result)
+}
@@ -0,0 +1,65 @@
+// Package storeopts contains the command-line options for selecting a persistent feature store that
+// are shared by the commands in this repository.
+package storeopts
+
+import (
+	"flag"
+
+	ld "gopkg.in/launchdarkly/go-server-sdk.v4"
+	"gopkg.in/launchdarkly/go-server-sdk.v4/ldconsul"
+	"gopkg.in/launchdarkly/go-server-sdk.v4/lddynamodb"
+	"gopkg.in/launchdarkly/go-server-sdk.v4/redis"
+)
+
+// Options describes a Redis, Consul, or DynamoDB feature store.
+type Options struct {
+	Redis    string
+	Consul   string
+	DynamoDB string
+	Prefix   string
+}
+
+// AddFlags registers the -redis, -consul, -dynamodb, and -prefix options.
+func (o *Options) AddFlags(flags *flag.FlagSet) {
+	flags.StringVar(&o.Redis, "redis", "", "use the Redis store at this `URL`")
+	flags.StringVar(&o.Consul, "consul", "", "use the Consul store at this `address`")
+	flags.StringVar(&o.DynamoDB, "dynamodb", "", "use this DynamoDB `table`")
+	flags.StringVar(&o.Prefix, "prefix", "", "key `prefix` for the Redis, Consul, or DynamoDB store")
+}
+
+// Count returns the number of stores that were specified.
+func (o Options) Count() int {
+	n := 0
+	for _, s := range []string{o.Redis, o.Consul, o.DynamoDB} {
+		if s != "" {
+			n++
+		}
+	}
+	return n
+}
+
+// NewFeatureStoreFactory returns a factory for the specified store, or nil if no store was specified.
+// Caching is disabled, since the commands need to see the current state of the database.
+func (o Options) NewFeatureStoreFactory() (ld.FeatureStoreFactory, error) {
+	switch {
+	case o.Redis != "":
+		redisOpts := []redis.FeatureStoreOption{redis.URL(o.Redis), redis.CacheTTL(0)}
+		if o.Prefix != "" {
+			redisOpts = append(redisOpts, redis.Prefix(o.Prefix))
+		}
+		return redis.NewRedisFeatureStoreFactory(redisOpts...)
+	case o.Consul != "":
+		consulOpts := []ldconsul.FeatureStoreOption{ldconsul.Address(o.Consul), ldconsul.CacheTTL(0)}
+		if o.Prefix != "" {
+			consulOpts = append(consulOpts, ldconsul.Prefix(o.Prefix))
+		}
+		return ldconsul.NewConsulFeatureStoreFactory(consulOpts...)
+	case o.DynamoDB != "":
+		dynamoOpts := []lddynamodb.FeatureStoreOption{lddynamodb.CacheTTL(0)}
+		if o.Prefix != "" {
+			dynamoOpts = append(dynamoOpts, lddynamodb.Prefix(o.Prefix))
+		}
+		return lddynamodb.NewDynamoDBFeatureStoreFactory(o.DynamoDB, dynamoOpts...)
+	}
+	return nil, nil
+}
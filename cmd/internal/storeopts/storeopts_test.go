@@ -0,0 +1,31 @@
+package storeopts
+
+import (
+	"flag"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFlagsAreParsed(t *testing.T) {
+	var o Options
+	flags := flag.NewFlagSet("test", flag.ContinueOnError)
+	o.AddFlags(flags)
+	require.NoError(t, flags.Parse([]string{"-redis", "redis://host:6379", "-prefix", "p"}))
+
+	assert.Equal(t, Options{Redis: "redis://host:6379", Prefix: "p"}, o)
+	assert.Equal(t, 1, o.Count())
+	factory, err := o.NewFeatureStoreFactory()
+	assert.NoError(t, err)
+	assert.NotNil(t, factory)
+}
+
+func TestNoStoreSpecified(t *testing.T) {
+	o := Options{Prefix: "p"}
+
+	assert.Equal(t, 0, o.Count())
+	factory, err := o.NewFeatureStoreFactory()
+	assert.NoError(t, err)
+	assert.Nil(t, factory)
+}
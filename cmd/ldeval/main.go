@@ -0,0 +1,290 @@
+// Command ldeval evaluates feature flags for a user without connecting to LaunchDarkly, so that a
+// production evaluation decision can be reproduced offline.
+//
+// The flag data can come from ldfiledata JSON or YAML files, from a persistent feature store that was
+// populated by an SDK or the Relay Proxy, or from a captured stream payload:
+//
+//     ldeval -file flags.json -user '{"key":"user-key"}'
+//     ldeval -redis redis://localhost:6379 -prefix launchdarkly -flag my-flag -user @user.json
+//     ldeval -consul localhost:8500 -user '{"key":"user-key"}'
+//     ldeval -dynamodb my-table -user '{"key":"user-key"}'
+//     ldeval -stream put-event.txt -flag my-flag -trace -user '{"key":"user-key"}'
+//
+// The user is given as JSON, either inline or, if the argument starts with "@", read from a file. The
+// output is a JSON object containing the value, variation index, and reason for each flag; with -trace,
+// it is a complete EvaluationTrace instead. No analytics events are sent. If the evaluation of any flag
+// fails, the error is printed to standard error and the exit status is 1.
+//
+// A stream payload may be the body of a "put" event as it was received from the streaming service (with
+// or without the "event:" and "data:" lines), or the response of the SDK polling endpoint.
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"log"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	ld "gopkg.in/launchdarkly/go-server-sdk.v4"
+	"gopkg.in/launchdarkly/go-server-sdk.v4/cmd/internal/storeopts"
+	"gopkg.in/launchdarkly/go-server-sdk.v4/ldfiledata"
+	"gopkg.in/launchdarkly/go-server-sdk.v4/ldlog"
+)
+
+const loadTimeout = 5 * time.Second
+
+type stringListFlag []string
+
+func (s *stringListFlag) String() string {
+	return strings.Join(*s, ",")
+}
+
+func (s *stringListFlag) Set(value string) error {
+	*s = append(*s, value)
+	return nil
+}
+
+type options struct {
+	files   stringListFlag
+	stream  string
+	store   storeopts.Options
+	user    string
+	flagKey string
+	trace   bool
+	verbose bool
+}
+
+type evaluationResult struct {
+	Value          interface{}                  `json:"value"`
+	VariationIndex *int                         `json:"variationIndex"`
+	Reason         ld.EvaluationReasonContainer `json:"reason"`
+}
+
+func main() {
+	os.Exit(run(os.Args[1:], os.Stdout, os.Stderr))
+}
+
+func run(args []string, stdout, stderr io.Writer) int {
+	var opts options
+	flags := flag.NewFlagSet("ldeval", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	flags.Var(&opts.files, "file", "read flag data from an ldfiledata JSON or YAML `file` (may be repeated)")
+	flags.StringVar(&opts.stream, "stream", "", "read flag data from a captured stream payload `file`")
+	opts.store.AddFlags(flags)
+	flags.StringVar(&opts.user, "user", "", "the user `JSON`, or @file to read it from a file")
+	flags.StringVar(&opts.flagKey, "flag", "", "evaluate only the flag with this `key`")
+	flags.BoolVar(&opts.trace, "trace", false, "print a step-by-step evaluation trace")
+	flags.BoolVar(&opts.verbose, "v", false, "print SDK log output")
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+
+	user, err := parseUser(opts.user)
+	if err != nil {
+		fmt.Fprintln(stderr, err)
+		return 2
+	}
+	config, err := makeConfig(opts, stderr)
+	if err != nil {
+		fmt.Fprintln(stderr, err)
+		return 2
+	}
+	client, err := ld.MakeCustomClient("", config, loadTimeout)
+	if err != nil {
+		fmt.Fprintf(stderr, "unable to load flag data: %s (use -v for details)\n", err)
+		return 1
+	}
+	defer client.Close() // nolint:errcheck
+
+	var output interface{}
+	status := 0
+	if opts.flagKey != "" {
+		output, err = evaluate(client, opts.flagKey, user, opts.trace)
+		if err != nil {
+			fmt.Fprintln(stderr, err)
+			status = 1
+		}
+	} else {
+		var ok bool
+		output, ok = evaluateAll(client, config.FeatureStore, user, opts.trace, stderr)
+		if !ok {
+			status = 1
+		}
+	}
+	bytes, err := json.MarshalIndent(output, "", "  ")
+	if err != nil {
+		fmt.Fprintln(stderr, err)
+		return 1
+	}
+	fmt.Fprintln(stdout, string(bytes))
+	return status
+}
+
+func parseUser(arg string) (ld.User, error) {
+	var user ld.User
+	if arg == "" {
+		return user, errors.New("-user is required")
+	}
+	data := []byte(arg)
+	if strings.HasPrefix(arg, "@") {
+		var err error
+		if data, err = ioutil.ReadFile(arg[1:]); err != nil { // nolint:gosec // G304: ok to read file into variable
+			return user, fmt.Errorf("unable to read user: %s", err)
+		}
+	}
+	if err := json.Unmarshal(data, &user); err != nil {
+		return user, fmt.Errorf("invalid user JSON: %s", err)
+	}
+	return user, nil
+}
+
+func makeConfig(opts options, stderr io.Writer) (ld.Config, error) {
+	config := ld.DefaultConfig
+	config.SendEvents = false
+	config.DiagnosticOptOut = true
+	config.Loggers.SetBaseLogger(log.New(stderr, "", log.LstdFlags))
+	if !opts.verbose {
+		config.Loggers.SetMinLevel(ldlog.None)
+	}
+
+	sources := opts.store.Count()
+	if len(opts.files) > 0 {
+		sources++
+	}
+	if opts.stream != "" {
+		sources++
+	}
+	if sources != 1 {
+		return config, errors.New("exactly one of -file, -stream, -redis, -consul, or -dynamodb is required")
+	}
+
+	var err error
+	switch {
+	case len(opts.files) > 0:
+		config.UpdateProcessorFactory = ldfiledata.NewFileDataSourceFactory(ldfiledata.FilePaths(opts.files...))
+		config.FeatureStore, err = ld.NewInMemoryFeatureStoreFactory()(config)
+		return config, err
+	case opts.stream != "":
+		config.FeatureStore, err = loadStreamPayload(opts.stream)
+	default:
+		var factory ld.FeatureStoreFactory
+		if factory, err = opts.store.NewFeatureStoreFactory(); err == nil {
+			config.FeatureStore, err = factory(config)
+		}
+	}
+	// The data is already in the store, so the client should read it as it would from the Relay Proxy.
+	config.UseLdd = true
+	return config, err
+}
+
+func loadStreamPayload(path string) (ld.FeatureStore, error) {
+	data, err := ioutil.ReadFile(path) // nolint:gosec // G304: ok to read file into variable
+	if err != nil {
+		return nil, fmt.Errorf("unable to read stream payload: %s", err)
+	}
+	allData, err := parseStreamPayload(data)
+	if err != nil {
+		return nil, err
+	}
+	store := ld.NewInMemoryFeatureStore(nil)
+	return store, store.Init(allData)
+}
+
+func parseStreamPayload(data []byte) (map[ld.VersionedDataKind]map[string]ld.VersionedData, error) {
+	var payload struct {
+		allData
+		Data *allData `json:"data"`
+	}
+	if err := json.Unmarshal(extractEventData(data), &payload); err != nil {
+		return nil, fmt.Errorf("invalid stream payload: %s", err)
+	}
+	if payload.Data != nil { // a "put" event has the form {"path": "/", "data": {"flags": ..., "segments": ...}}
+		payload.allData = *payload.Data
+	}
+	allData := map[ld.VersionedDataKind]map[string]ld.VersionedData{
+		ld.Features: {}, //nolint:megacheck // allow deprecated usage
+		ld.Segments: {}, //nolint:megacheck // allow deprecated usage
+	}
+	for key, f := range payload.Flags {
+		allData[ld.Features][key] = f //nolint:megacheck // allow deprecated usage
+	}
+	for key, s := range payload.Segments {
+		allData[ld.Segments][key] = s //nolint:megacheck // allow deprecated usage
+	}
+	return allData, nil
+}
+
+type allData struct {
+	Flags    map[string]*ld.FeatureFlag `json:"flags"`    //nolint:megacheck // allow deprecated usage
+	Segments map[string]*ld.Segment     `json:"segments"` //nolint:megacheck // allow deprecated usage
+}
+
+// extractEventData returns the content of the "data:" lines if the payload is in Server-Sent Events format.
+func extractEventData(payload []byte) []byte {
+	var dataLines []string
+	for _, line := range strings.Split(string(payload), "\n") {
+		line = strings.TrimRight(line, "\r")
+		if strings.HasPrefix(line, "data:") {
+			dataLines = append(dataLines, strings.TrimPrefix(strings.TrimPrefix(line, "data:"), " "))
+		}
+	}
+	if len(dataLines) == 0 {
+		return payload
+	}
+	return []byte(strings.Join(dataLines, "\n"))
+}
+
+// flagKeys returns the keys of all flags in the store. They are read from the store rather than from
+// AllFlagsState, which is empty if the user is invalid, so that such a user gets an error for every flag.
+func flagKeys(store ld.FeatureStore) ([]string, error) {
+	items, err := store.All(ld.Features) //nolint:megacheck // allow deprecated usage
+	if err != nil {
+		return nil, err
+	}
+	keys := make([]string, 0, len(items))
+	for key := range items {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys, nil
+}
+
+// evaluateAll evaluates every flag in the store, printing any evaluation errors to stderr. It returns
+// false if there were errors; the results for those flags are still included.
+func evaluateAll(client *ld.LDClient, store ld.FeatureStore, user ld.User, trace bool,
+	stderr io.Writer) (map[string]interface{}, bool) {
+	all := make(map[string]interface{})
+	keys, err := flagKeys(store)
+	if err != nil {
+		fmt.Fprintf(stderr, "unable to read flags: %s\n", err)
+		return all, false
+	}
+	ok := true
+	for _, key := range keys {
+		var err error
+		if all[key], err = evaluate(client, key, user, trace); err != nil {
+			fmt.Fprintf(stderr, "%s: %s\n", key, err)
+			ok = false
+		}
+	}
+	return all, ok
+}
+
+func evaluate(client *ld.LDClient, key string, user ld.User, trace bool) (interface{}, error) {
+	t, err := client.ExplainEvaluation(key, user)
+	if trace {
+		return t, err
+	}
+	return evaluationResult{
+		Value:          t.Value.UnsafeArbitraryValue(), //nolint:megacheck // allow deprecated usage
+		VariationIndex: t.VariationIndex,
+		Reason:         t.Reason,
+	}, err
+}
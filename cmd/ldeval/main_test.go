@@ -0,0 +1,185 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	ld "gopkg.in/launchdarkly/go-server-sdk.v4"
+)
+
+const testFlagData = `{
+  "flags": {
+    "flag1": {
+      "key": "flag1", "version": 1, "on": true, "variations": ["a", "b"],
+      "targets": [{"values": ["target-user"], "variation": 1}],
+      "fallthrough": {"variation": 0}
+    }
+  },
+  "flagValues": {"flag2": 3}
+}`
+
+func writeTempFile(t *testing.T, dir, name, content string) string {
+	path := filepath.Join(dir, name)
+	require.NoError(t, ioutil.WriteFile(path, []byte(content), 0600))
+	return path
+}
+
+func runAndParse(t *testing.T, args ...string) (int, map[string]interface{}, string) {
+	var stdout, stderr bytes.Buffer
+	status := run(args, &stdout, &stderr)
+	var output map[string]interface{}
+	if stdout.Len() > 0 {
+		require.NoError(t, json.Unmarshal(stdout.Bytes(), &output))
+	}
+	return status, output, stderr.String()
+}
+
+func withTempDir(t *testing.T, action func(dir string)) {
+	dir, err := ioutil.TempDir("", "ldeval-test")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	action(dir)
+}
+
+func TestEvaluateAllFlagsFromFile(t *testing.T) {
+	withTempDir(t, func(dir string) {
+		path := writeTempFile(t, dir, "flags.json", testFlagData)
+
+		status, output, _ := runAndParse(t, "-file", path, "-user", `{"key":"target-user"}`)
+
+		assert.Equal(t, 0, status)
+		assert.Equal(t, map[string]interface{}{
+			"flag1": map[string]interface{}{
+				"value":          "b",
+				"variationIndex": float64(1),
+				"reason":         map[string]interface{}{"kind": "TARGET_MATCH"},
+			},
+			"flag2": map[string]interface{}{
+				"value":          float64(3),
+				"variationIndex": float64(0),
+				"reason":         map[string]interface{}{"kind": "FALLTHROUGH"},
+			},
+		}, output)
+	})
+}
+
+func TestEvaluateOneFlagWithTraceAndUserFile(t *testing.T) {
+	withTempDir(t, func(dir string) {
+		path := writeTempFile(t, dir, "flags.json", testFlagData)
+		userPath := writeTempFile(t, dir, "user.json", `{"key":"other-user"}`)
+
+		status, output, _ := runAndParse(t, "-file", path, "-flag", "flag1", "-trace", "-user", "@"+userPath)
+
+		assert.Equal(t, 0, status)
+		assert.Equal(t, "flag1", output["flagKey"])
+		assert.Equal(t, "a", output["value"])
+		assert.Equal(t, []interface{}{
+			map[string]interface{}{"variation": float64(1), "matched": false},
+		}, output["targets"])
+	})
+}
+
+func TestEvaluateUnknownFlagReturnsErrorStatus(t *testing.T) {
+	withTempDir(t, func(dir string) {
+		path := writeTempFile(t, dir, "flags.json", testFlagData)
+
+		status, output, stderr := runAndParse(t, "-file", path, "-flag", "unknown", "-user", `{"key":"u"}`)
+
+		assert.Equal(t, 1, status)
+		assert.Equal(t, map[string]interface{}{"kind": "ERROR", "errorKind": "FLAG_NOT_FOUND"}, output["reason"])
+		assert.NotEqual(t, "", stderr)
+	})
+}
+
+type storeWithFailingGet struct {
+	ld.FeatureStore
+}
+
+func (s storeWithFailingGet) Get(kind ld.VersionedDataKind, key string) (ld.VersionedData, error) {
+	return nil, errors.New("sorry")
+}
+
+func TestEvaluateAllReportsErrorsForEachFlag(t *testing.T) {
+	store := ld.NewInMemoryFeatureStore(nil)
+	allData, err := parseStreamPayload([]byte(testFlagData))
+	require.NoError(t, err)
+	require.NoError(t, store.Init(allData))
+	config := ld.DefaultConfig
+	config.UseLdd = true
+	config.SendEvents = false
+	config.DiagnosticOptOut = true
+	config.FeatureStore = storeWithFailingGet{store}
+	client, err := ld.MakeCustomClient("", config, 0)
+	require.NoError(t, err)
+	defer client.Close() // nolint:errcheck
+
+	var stderr bytes.Buffer
+	output, ok := evaluateAll(client, config.FeatureStore, ld.NewUser("u"), false, &stderr)
+
+	assert.False(t, ok)
+	assert.Contains(t, output, "flag1")
+	assert.Contains(t, stderr.String(), "flag1: sorry")
+}
+
+func TestEvaluateAllFlagsForUserWithoutKeyReturnsErrorStatus(t *testing.T) {
+	withTempDir(t, func(dir string) {
+		path := writeTempFile(t, dir, "flags.json", testFlagData)
+
+		status, output, stderr := runAndParse(t, "-file", path, "-user", `{}`)
+
+		assert.Equal(t, 1, status)
+		require.Equal(t, 2, len(output))
+		for _, key := range []string{"flag1", "flag2"} {
+			result, _ := output[key].(map[string]interface{})
+			assert.Equal(t, map[string]interface{}{"kind": "ERROR", "errorKind": "USER_NOT_SPECIFIED"}, result["reason"])
+			assert.Contains(t, stderr, key+": ")
+		}
+	})
+}
+
+func TestEvaluateFromStreamPayload(t *testing.T) {
+	payload := "event: put\n" +
+		`data: {"path":"/","data":{"flags":{"flag1":{"key":"flag1","on":false,"offVariation":1,"variations":["a","b"]}},"segments":{}}}` +
+		"\n\n"
+	withTempDir(t, func(dir string) {
+		path := writeTempFile(t, dir, "stream.txt", payload)
+
+		status, output, _ := runAndParse(t, "-stream", path, "-flag", "flag1", "-user", `{"key":"u"}`)
+
+		assert.Equal(t, 0, status)
+		assert.Equal(t, "b", output["value"])
+	})
+}
+
+func TestParseStreamPayloadAcceptsPollingResponse(t *testing.T) {
+	allData, err := parseStreamPayload([]byte(`{"flags":{"flag1":{"key":"flag1"}},"segments":{"seg1":{"key":"seg1"}}}`))
+
+	require.NoError(t, err)
+	assert.Equal(t, "flag1", allData[ld.Features]["flag1"].GetKey()) //nolint:megacheck // allow deprecated usage
+	assert.Equal(t, "seg1", allData[ld.Segments]["seg1"].GetKey())   //nolint:megacheck // allow deprecated usage
+}
+
+func TestInvalidArguments(t *testing.T) {
+	status, _, stderr := runAndParse(t, "-user", `{"key":"u"}`)
+	assert.Equal(t, 2, status)
+	assert.Contains(t, stderr, "exactly one of")
+
+	status, _, stderr = runAndParse(t, "-file", "x.json", "-redis", "redis://localhost", "-user", `{"key":"u"}`)
+	assert.Equal(t, 2, status)
+	assert.Contains(t, stderr, "exactly one of")
+
+	status, _, stderr = runAndParse(t, "-file", "x.json", "-user", `{`)
+	assert.Equal(t, 2, status)
+	assert.Contains(t, stderr, "invalid user JSON")
+
+	status, _, _ = runAndParse(t, "-file", "does-not-exist.json", "-user", `{"key":"u"}`)
+	assert.Equal(t, 1, status)
+}
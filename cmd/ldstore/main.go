@@ -0,0 +1,277 @@
+// Command ldstore copies feature flag data between persistent feature stores and data files, for
+// instance to migrate from Redis to DynamoDB or to seed a store for an application that uses daemon
+// mode (Config.UseLdd).
+//
+// The export subcommand writes all flags and segments from a store to a file in the format used by
+// ldfiledata. The import subcommand replaces all of the data in a store with the contents of one or
+// more such files; with -dry-run, it only prints the differences between the files and the store:
+//
+//     ldstore export -redis redis://localhost:6379 -prefix launchdarkly -o flags.json
+//     ldstore import -dynamodb my-table -prefix launchdarkly -dry-run flags.json
+//     ldstore import -dynamodb my-table -prefix launchdarkly flags.json
+//
+// The source of an export can also be a set of data files (-file), which is a way to merge them or to
+// convert YAML to JSON.
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"log"
+	"os"
+	"sort"
+	"strings"
+
+	ld "gopkg.in/launchdarkly/go-server-sdk.v4"
+	"gopkg.in/launchdarkly/go-server-sdk.v4/cmd/internal/storeopts"
+	"gopkg.in/launchdarkly/go-server-sdk.v4/ldfiledata"
+	"gopkg.in/launchdarkly/go-server-sdk.v4/ldlog"
+)
+
+const usage = `usage:
+  ldstore export (-redis URL | -consul ADDRESS | -dynamodb TABLE | -file FILE...) [-prefix PREFIX] [-o FILE]
+  ldstore import (-redis URL | -consul ADDRESS | -dynamodb TABLE) [-prefix PREFIX] [-dry-run] FILE...`
+
+type stringListFlag []string
+
+func (s *stringListFlag) String() string {
+	return strings.Join(*s, ",")
+}
+
+func (s *stringListFlag) Set(value string) error {
+	*s = append(*s, value)
+	return nil
+}
+
+type storeData map[ld.VersionedDataKind]map[string]ld.VersionedData
+
+// dataKinds lists the kinds of data that are copied, along with their property names in a data file.
+var dataKinds = []struct {
+	kind ld.VersionedDataKind
+	name string
+}{
+	{ld.Features, "flags"},    //nolint:megacheck // allow deprecated usage
+	{ld.Segments, "segments"}, //nolint:megacheck // allow deprecated usage
+}
+
+func main() {
+	os.Exit(run(os.Args[1:], os.Stdout, os.Stderr))
+}
+
+func run(args []string, stdout, stderr io.Writer) int {
+	loggers := ldlog.Loggers{}
+	loggers.SetBaseLogger(log.New(stderr, "", log.LstdFlags))
+	loggers.SetMinLevel(ldlog.Warn)
+	if len(args) > 0 {
+		switch args[0] {
+		case "export":
+			return runExport(args[1:], stdout, stderr, loggers)
+		case "import":
+			return runImport(args[1:], stdout, stderr, loggers)
+		}
+	}
+	fmt.Fprintln(stderr, usage)
+	return 2
+}
+
+func runExport(args []string, stdout, stderr io.Writer, loggers ldlog.Loggers) int {
+	var storeOpts storeopts.Options
+	var files stringListFlag
+	var outPath string
+	flags := flag.NewFlagSet("ldstore export", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	storeOpts.AddFlags(flags)
+	flags.Var(&files, "file", "read data from an ldfiledata JSON or YAML `file` (may be repeated)")
+	flags.StringVar(&outPath, "o", "", "write to this `file` instead of standard output")
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+	if (len(files) > 0) == (storeOpts.Count() > 0) || storeOpts.Count() > 1 || flags.NArg() > 0 {
+		fmt.Fprintln(stderr, usage)
+		return 2
+	}
+
+	var data storeData
+	var err error
+	if len(files) > 0 {
+		data, err = loadFiles(files, loggers)
+	} else {
+		data, err = readStore(storeOpts, loggers)
+	}
+	if err != nil {
+		fmt.Fprintln(stderr, err)
+		return 1
+	}
+
+	fileData := make(map[string]map[string]ld.VersionedData)
+	for _, k := range dataKinds {
+		fileData[k.name] = data[k.kind]
+	}
+	bytes, err := json.MarshalIndent(fileData, "", "  ")
+	if err == nil {
+		bytes = append(bytes, '\n')
+		if outPath == "" {
+			_, err = stdout.Write(bytes)
+		} else {
+			err = ioutil.WriteFile(outPath, bytes, 0644) // nolint:gosec // G306: data files are not secret
+		}
+	}
+	if err != nil {
+		fmt.Fprintln(stderr, err)
+		return 1
+	}
+	return 0
+}
+
+func runImport(args []string, stdout, stderr io.Writer, loggers ldlog.Loggers) int {
+	var storeOpts storeopts.Options
+	var dryRun bool
+	flags := flag.NewFlagSet("ldstore import", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	storeOpts.AddFlags(flags)
+	flags.BoolVar(&dryRun, "dry-run", false, "print the changes that would be made, without making them")
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+	if storeOpts.Count() != 1 || flags.NArg() == 0 {
+		fmt.Fprintln(stderr, usage)
+		return 2
+	}
+
+	newData, err := loadFiles(flags.Args(), loggers)
+	if err != nil {
+		fmt.Fprintln(stderr, err)
+		return 1
+	}
+	store, err := openStore(storeOpts, loggers)
+	if err != nil {
+		fmt.Fprintln(stderr, err)
+		return 1
+	}
+	defer closeStore(store)
+
+	if dryRun {
+		oldData := storeData{}
+		if store.Initialized() {
+			if oldData, err = readAll(store); err != nil {
+				fmt.Fprintln(stderr, err)
+				return 1
+			}
+		}
+		changes := diffData(oldData, newData)
+		if len(changes) == 0 {
+			fmt.Fprintln(stdout, "no changes")
+		}
+		for _, c := range changes {
+			fmt.Fprintln(stdout, c)
+		}
+		return 0
+	}
+
+	if err := store.Init(newData); err != nil {
+		fmt.Fprintf(stderr, "unable to update store: %s\n", err)
+		return 1
+	}
+	fmt.Fprintf(stdout, "imported %d flags and %d segments\n",
+		len(newData[ld.Features]), len(newData[ld.Segments])) //nolint:megacheck // allow deprecated usage
+	return 0
+}
+
+func openStore(storeOpts storeopts.Options, loggers ldlog.Loggers) (ld.FeatureStore, error) {
+	factory, err := storeOpts.NewFeatureStoreFactory()
+	if err != nil {
+		return nil, err
+	}
+	return factory(ld.Config{Loggers: loggers})
+}
+
+func closeStore(store ld.FeatureStore) {
+	if c, ok := store.(io.Closer); ok {
+		_ = c.Close()
+	}
+}
+
+func readStore(storeOpts storeopts.Options, loggers ldlog.Loggers) (storeData, error) {
+	store, err := openStore(storeOpts, loggers)
+	if err != nil {
+		return nil, err
+	}
+	defer closeStore(store)
+	if !store.Initialized() {
+		return nil, errors.New("the store has not been initialized")
+	}
+	return readAll(store)
+}
+
+func readAll(store ld.FeatureStore) (storeData, error) {
+	data := storeData{}
+	for _, k := range dataKinds {
+		items, err := store.All(k.kind)
+		if err != nil {
+			return nil, fmt.Errorf("unable to read %s: %s", k.name, err)
+		}
+		data[k.kind] = items
+	}
+	return data, nil
+}
+
+// loadFiles reads data files with the same logic that the file data source uses, so that any file
+// that the SDK would accept can be imported.
+func loadFiles(paths []string, loggers ldlog.Loggers) (storeData, error) {
+	store := ld.NewInMemoryFeatureStore(nil)
+	factory := ldfiledata.NewFileDataSourceFactory(ldfiledata.FilePaths(paths...))
+	source, err := factory("", ld.Config{FeatureStore: store, Loggers: loggers})
+	if err != nil {
+		return nil, err
+	}
+	readyCh := make(chan struct{})
+	source.Start(readyCh)
+	<-readyCh
+	_ = source.Close()
+	if !source.Initialized() {
+		return nil, errors.New("unable to load data files")
+	}
+	return readAll(store)
+}
+
+// diffData describes the changes that replacing oldData with newData would make, one line per item.
+func diffData(oldData, newData storeData) []string {
+	var changes []string
+	for _, k := range dataKinds {
+		oldItems, newItems := oldData[k.kind], newData[k.kind]
+		keys := make([]string, 0, len(oldItems)+len(newItems))
+		for key := range oldItems {
+			keys = append(keys, key)
+		}
+		for key := range newItems {
+			if _, ok := oldItems[key]; !ok {
+				keys = append(keys, key)
+			}
+		}
+		sort.Strings(keys)
+		for _, key := range keys {
+			oldItem, oldOK := oldItems[key]
+			newItem, newOK := newItems[key]
+			switch {
+			case !oldOK:
+				changes = append(changes, fmt.Sprintf("+ %s/%s (version %d)", k.name, key, newItem.GetVersion()))
+			case !newOK:
+				changes = append(changes, fmt.Sprintf("- %s/%s (version %d)", k.name, key, oldItem.GetVersion()))
+			case !sameJSON(oldItem, newItem):
+				changes = append(changes, fmt.Sprintf("~ %s/%s (version %d -> %d)", k.name, key,
+					oldItem.GetVersion(), newItem.GetVersion()))
+			}
+		}
+	}
+	return changes
+}
+
+func sameJSON(a, b ld.VersionedData) bool {
+	aBytes, aErr := json.Marshal(a)
+	bBytes, bErr := json.Marshal(b)
+	return aErr == nil && bErr == nil && string(aBytes) == string(bBytes)
+}
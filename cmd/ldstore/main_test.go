@@ -0,0 +1,121 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	ld "gopkg.in/launchdarkly/go-server-sdk.v4"
+	"gopkg.in/launchdarkly/go-server-sdk.v4/ldlog"
+)
+
+func ldlogForTest() ldlog.Loggers {
+	loggers := ldlog.Loggers{}
+	loggers.SetMinLevel(ldlog.None)
+	return loggers
+}
+
+func withDataFiles(t *testing.T, files map[string]string, action func(dir string)) {
+	dir, err := ioutil.TempDir("", "ldstore-test")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	for name, content := range files {
+		require.NoError(t, ioutil.WriteFile(filepath.Join(dir, name), []byte(content), 0600))
+	}
+	action(dir)
+}
+
+func TestExportFromFilesMergesAndConvertsToJSON(t *testing.T) {
+	files := map[string]string{
+		"flags.json":    `{"flags": {"flag1": {"key": "flag1", "version": 2}}}`,
+		"segments.yaml": "segments:\n  seg1:\n    key: seg1\n    version: 3\n",
+	}
+	withDataFiles(t, files, func(dir string) {
+		var stdout, stderr bytes.Buffer
+		status := run([]string{"export", "-file", filepath.Join(dir, "flags.json"),
+			"-file", filepath.Join(dir, "segments.yaml")}, &stdout, &stderr)
+
+		require.Equal(t, 0, status, stderr.String())
+		var output map[string]map[string]map[string]interface{}
+		require.NoError(t, json.Unmarshal(stdout.Bytes(), &output))
+		assert.Equal(t, float64(2), output["flags"]["flag1"]["version"])
+		assert.Equal(t, float64(3), output["segments"]["seg1"]["version"])
+	})
+}
+
+func TestExportToOutputFileCanBeLoadedAgain(t *testing.T) {
+	files := map[string]string{"flags.yaml": "flagValues:\n  flag1: true\n"}
+	withDataFiles(t, files, func(dir string) {
+		outPath := filepath.Join(dir, "out.json")
+		var stdout, stderr bytes.Buffer
+		status := run([]string{"export", "-file", filepath.Join(dir, "flags.yaml"), "-o", outPath}, &stdout, &stderr)
+		require.Equal(t, 0, status, stderr.String())
+		assert.Equal(t, "", stdout.String())
+
+		data, err := loadFiles([]string{outPath}, ldlogForTest())
+		require.NoError(t, err)
+		flag := data[ld.Features]["flag1"].(*ld.FeatureFlag) //nolint:megacheck // allow deprecated usage
+		assert.Equal(t, []interface{}{true}, flag.Variations)
+		assert.Equal(t, 0, len(data[ld.Segments])) //nolint:megacheck // allow deprecated usage
+	})
+}
+
+func TestLoadFilesFailsForInvalidFile(t *testing.T) {
+	withDataFiles(t, map[string]string{"bad.json": "{"}, func(dir string) {
+		_, err := loadFiles([]string{filepath.Join(dir, "bad.json")}, ldlogForTest())
+		assert.Error(t, err)
+	})
+}
+
+func TestDiffData(t *testing.T) {
+	oldData := storeData{
+		ld.Features: { //nolint:megacheck // allow deprecated usage
+			"removed":   &ld.FeatureFlag{Key: "removed", Version: 1},          //nolint:megacheck // allow deprecated usage
+			"changed":   &ld.FeatureFlag{Key: "changed", Version: 1},          //nolint:megacheck // allow deprecated usage
+			"unchanged": &ld.FeatureFlag{Key: "unchanged", Version: 1},        //nolint:megacheck // allow deprecated usage
+			"edited":    &ld.FeatureFlag{Key: "edited", Version: 4, On: true}, //nolint:megacheck // allow deprecated usage
+		},
+	}
+	newData := storeData{
+		ld.Features: { //nolint:megacheck // allow deprecated usage
+			"added":     &ld.FeatureFlag{Key: "added", Version: 1},     //nolint:megacheck // allow deprecated usage
+			"changed":   &ld.FeatureFlag{Key: "changed", Version: 2},   //nolint:megacheck // allow deprecated usage
+			"unchanged": &ld.FeatureFlag{Key: "unchanged", Version: 1}, //nolint:megacheck // allow deprecated usage
+			"edited":    &ld.FeatureFlag{Key: "edited", Version: 4},    //nolint:megacheck // allow deprecated usage
+		},
+		ld.Segments: { //nolint:megacheck // allow deprecated usage
+			"seg": &ld.Segment{Key: "seg", Version: 5}, //nolint:megacheck // allow deprecated usage
+		},
+	}
+
+	assert.Equal(t, []string{
+		"+ flags/added (version 1)",
+		"~ flags/changed (version 1 -> 2)",
+		"~ flags/edited (version 4 -> 4)",
+		"- flags/removed (version 1)",
+		"+ segments/seg (version 5)",
+	}, diffData(oldData, newData))
+	assert.Nil(t, diffData(newData, newData))
+}
+
+func TestInvalidArguments(t *testing.T) {
+	for _, args := range [][]string{
+		{},
+		{"unknown"},
+		{"export"},
+		{"export", "-file", "a.json", "-redis", "redis://localhost"},
+		{"export", "-redis", "redis://localhost", "-consul", "localhost"},
+		{"import", "a.json"},
+		{"import", "-redis", "redis://localhost"},
+	} {
+		var stdout, stderr bytes.Buffer
+		assert.Equal(t, 2, run(args, &stdout, &stderr), "args: %v", args)
+		assert.Contains(t, stderr.String(), "usage:")
+	}
+}
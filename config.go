@@ -35,6 +35,9 @@ type Config struct {
 	//
 	// Deprecated: This feature will be removed in a future version of the SDK.
 	SamplingInterval int32
+	// Enables sampling of specific kinds of analytics events. See EventSamplingConfig. Sampling never affects
+	// the counters in summary events, which always reflect every flag evaluation.
+	EventSampling EventSamplingConfig
 	// The polling interval (when streaming is disabled). Values less than the default of MinimumPollInterval
 	// will be set to the default.
 	PollInterval time.Duration
@@ -87,11 +90,25 @@ type Config struct {
 	// Set to true if you need to see the full user details in every analytics event.
 	InlineUsersInEvents bool
 	// Marks a set of user attribute names private. Any users sent to LaunchDarkly with this configuration
-	// active will have attributes with these names removed.
+	// active will have attributes with these names removed. A name that begins with "/" is a slash-delimited
+	// reference to a property nested within a custom attribute, such as "/address/street"; only that property
+	// is removed.
 	PrivateAttributeNames []string
+	// Rules for removing or transforming user attributes in analytics events, such as hashing or masking
+	// their values. These are applied after PrivateAttributeNames and AllAttributesPrivate. See
+	// AttributeRedactionRule.
+	AttributeRedactionRules []AttributeRedactionRule
+	// The path of a JSON file containing a list of FlagOverride objects, which are loaded when the client
+	// is created. If the file cannot be read or parsed, MakeCustomClient returns an error.
+	FlagOverridesFile string
+	// Sets whether feature flags and segments that fail validation (see ValidateData) should be discarded
+	// when they are received from LaunchDarkly or from a file data source. By default, a warning is logged
+	// for each problem, but the data is still stored.
+	RejectInvalidData bool
 	// Sets whether the client should log a warning message whenever a flag cannot be evaluated due to an error
 	// (e.g. there is no flag with that key, or the user properties are invalid). By default, these messages are
 	// not logged, although you can detect such errors programmatically using the VariationDetail methods.
+	// To keep these messages from flooding the log, use Loggers.SetRateLimit.
 	LogEvaluationErrors bool
 	// Sets whether log messages for errors related to a specific user can include the user key. By default, they
 	// will not, since the user key might be considered privileged information.
@@ -105,6 +122,44 @@ type Config struct {
 	// An object that is responsible for recording or sending analytics events. If nil, a
 	// default implementation will be used; a custom implementation can be substituted for testing.
 	EventProcessor EventProcessor
+	// Additional destinations for analytics events. Each batch of events that the default event processor
+	// flushes is also delivered to every sink in this list, in the same format that is sent to LaunchDarkly.
+	// See NewJSONLinesEventSink, NewFileEventSink, and NewMessageWriterEventSink.
+	EventSinks []EventSink
+	// Set to true to deliver analytics events only to EventSinks, and not to LaunchDarkly. Diagnostic
+	// events are also not sent in this case.
+	EventSinksOnly bool
+	// If not empty, this is a directory where the event processor will store analytics event payloads that
+	// could not be delivered to LaunchDarkly because of a network error or a server error. Stored payloads
+	// are delivered in order, oldest first, as soon as a later delivery succeeds; this includes payloads
+	// left over from a previous run of the application that used the same directory. The directory must
+	// not be shared by more than one client instance at a time.
+	EventSpoolDir string
+	// The maximum total size of the payloads kept in EventSpoolDir. If storing a payload would exceed this
+	// limit, the oldest payloads are discarded. If zero, DefaultEventSpoolMaxBytes is used.
+	EventSpoolMaxBytes int64
+	// The maximum length of time a payload is kept in EventSpoolDir before it is discarded. If zero,
+	// DefaultEventSpoolMaxAge is used.
+	EventSpoolMaxAge time.Duration
+	// Set to true to compress analytics event payloads with gzip before sending them to LaunchDarkly.
+	CompressEvents bool
+	// The minimum size in bytes of an event payload that will be compressed, if CompressEvents is true.
+	// Smaller payloads are sent uncompressed, since compressing them saves little. If zero,
+	// DefaultEventCompressionThreshold is used.
+	EventCompressionThreshold int
+	// The number of goroutines that the event processor uses to deliver event payloads, which is the maximum
+	// number of payloads that can be in flight at once. If zero, the default of 5 is used.
+	EventFlushWorkers int
+	// The number of events that can be waiting to be processed by the event processor. If the application
+	// produces events faster than they can be processed and this limit is reached, further events are
+	// dropped. If zero, Capacity is used.
+	EventInboxCapacity int
+	// If not nil, this function is called whenever the event processor drops events because of
+	// backpressure, so that the application can monitor this condition.
+	EventsDroppedHandler EventsDroppedHandler
+	// If not nil, this function is called after each attempt to deliver a payload of analytics events
+	// to LaunchDarkly, with information such as the delivery latency.
+	EventPayloadResultHandler EventPayloadResultHandler
 	// The number of user keys that the event processor can remember at any one time, so that
 	// duplicate user details will not be sent in analytics events.
 	UserKeysCapacity int
@@ -134,11 +189,20 @@ type Config struct {
 	//
 	// If WrapperName is unset, this field will be ignored.
 	WrapperVersion string
+	// Additional headers to send with every HTTP request to LaunchDarkly, including polling, streaming,
+	// analytics event, and diagnostic event requests. This is useful if requests go through a proxy that
+	// requires headers of its own. These are added to the headers that the SDK sets, so they should not
+	// include any of those, such as Authorization or User-Agent.
+	HTTPHeaders http.Header
+	// If not nil, this function is called for every HTTP request to LaunchDarkly, including polling,
+	// streaming, analytics event, and diagnostic event requests, just before the request is sent; for
+	// instance, to add a signature header that a proxy requires. See RequestModifier.
+	RequestModifier RequestModifier
 	// If not nil, this function will be called to create an HTTP client instead of using the default
 	// client. You may use this to specify custom HTTP properties such as a proxy URL or CA certificates.
 	// The SDK may modify the client properties after that point (for instance, to add caching),
-	// but will not replace the underlying Transport, and will not modify any timeout properties you set.
-	// See NewHTTPClientFactory().
+	// but will not replace the underlying Transport (except to wrap it, if RequestModifier is set), and
+	// will not modify any timeout properties you set. See NewHTTPClientFactory().
 	//
 	// Usage:
 	//
@@ -149,9 +213,40 @@ type Config struct {
 	diagnosticsManager *diagnosticsManager
 }
 
+// EventSamplingConfig specifies sampling intervals for analytics events, in the same form as the deprecated
+// Config.SamplingInterval: an interval of N means there is a 1 in N chance that an event will be sent, and
+// an interval of zero or one means all such events are sent.
+//
+//     config := ld.DefaultConfig
+//     config.EventSampling.CustomEvents = map[string]int32{"page-view": 10} // send 10% of "page-view" events
+//     config.EventSampling.IndexEvents = 2                                  // send 50% of index events
+type EventSamplingConfig struct {
+	// Sampling intervals for custom events, keyed by event key.
+	CustomEvents map[string]int32
+	// Sampling intervals for full feature events, keyed by flag key. This applies only to flags that
+	// generate full feature events (see FeatureFlag.TrackEvents); debug events are not sampled.
+	FeatureEvents map[string]int32
+	// Sampling interval for identify events.
+	IdentifyEvents int32
+	// Sampling interval for index events, which carry user details for users seen in other events.
+	IndexEvents int32
+}
+
 // HTTPClientFactory is a function that creates a custom HTTP client.
 type HTTPClientFactory func(Config) http.Client
 
+// RequestModifier is a function that can change an HTTP request to LaunchDarkly before it is sent. See
+// Config.RequestModifier.
+//
+// It is called after all other headers have been set, and is called again each time a request is retried
+// and each time the stream reconnects, so it can compute a new signature every time. The request is a
+// copy, so it is safe to modify its headers. If it needs the request body, it should call req.GetBody
+// rather than reading req.Body.
+//
+// If it returns an error, the request is not sent, and the SDK handles the error as it would a network
+// error.
+type RequestModifier func(req *http.Request) error
+
 // UpdateProcessorFactory is a function that creates an UpdateProcessor.
 type UpdateProcessorFactory func(sdkKey string, config Config) (UpdateProcessor, error)
 
@@ -0,0 +1,154 @@
+package ldclient
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+)
+
+// DefaultContextKind is the context kind of a User. It is used whenever a Context is created from a User,
+// and whenever a Target, Clause, or Rollout does not specify a context kind.
+const DefaultContextKind = "user"
+
+// Context is an evaluation context: a description of the entity, or entities, that a feature flag is
+// being evaluated for. Each entity has a kind (such as "user", "organization", or "device") and a key that
+// is unique within that kind, and may have any other attributes. A context that contains entities of more
+// than one kind is a multi-kind context.
+//
+// The attributes of each entity are represented with the same type as a User, so a User is simply a
+// context of the kind "user". Use NewContextFromUser to convert an existing User, NewContext or
+// NewContextWithAttributes to create a context of another kind, and NewMultiContext to combine them:
+//
+//     org := ld.NewContextWithAttributes("organization", ld.NewUserBuilder("org-key").Name("Acme").Build())
+//     context := ld.NewMultiContext(ld.NewContextFromUser(user), org)
+//     value, err := client.BoolVariationForContext("flag-key", context, false)
+//
+// Targets, clauses, and rollouts in a flag select an entity by its kind; see Target.ContextKind,
+// Clause.ContextKind, and Rollout.ContextKind.
+//
+// Analytics events are user-based, so they describe only the "user" entity of a context. Evaluations for
+// a context that has no "user" entity are included in summary events, but no user details are sent.
+type Context struct {
+	// A single-kind context, which is by far the most common, is stored in kind and user, so that the
+	// Variation methods can create one for a User without allocating anything. individuals and kinds are
+	// only used for a context created by NewMultiContext.
+	kind        string
+	user        User
+	individuals map[string]User
+	kinds       []string // sorted
+}
+
+// NewContext creates a single-kind context with only a key. If kind is empty, DefaultContextKind is used.
+func NewContext(kind string, key string) Context {
+	return NewContextWithAttributes(kind, NewUser(key))
+}
+
+// NewContextWithAttributes creates a single-kind context whose key and other attributes are taken from
+// a User. If kind is empty, DefaultContextKind is used.
+func NewContextWithAttributes(kind string, attributes User) Context {
+	if kind == "" {
+		kind = DefaultContextKind
+	}
+	return Context{kind: kind, user: attributes}
+}
+
+// NewContextFromUser creates a single-kind context of the kind DefaultContextKind from a User.
+func NewContextFromUser(user User) Context {
+	return Context{kind: DefaultContextKind, user: user}
+}
+
+// NewMultiContext creates a multi-kind context that contains all of the entities in the specified
+// contexts. If more than one of them has the same kind, the last one is used.
+func NewMultiContext(contexts ...Context) Context {
+	ret := Context{individuals: make(map[string]User)}
+	add := func(kind string, u User) {
+		if _, exists := ret.individuals[kind]; !exists {
+			ret.kinds = append(ret.kinds, kind)
+		}
+		ret.individuals[kind] = u
+	}
+	for _, c := range contexts {
+		if c.individuals == nil {
+			if c.kind != "" {
+				add(c.kind, c.user)
+			}
+			continue
+		}
+		for _, kind := range c.kinds {
+			add(kind, c.individuals[kind])
+		}
+	}
+	sort.Strings(ret.kinds)
+	return ret
+}
+
+// Kinds returns the kinds of all of the entities in the context, in alphabetical order.
+func (c Context) Kinds() []string {
+	if c.individuals == nil {
+		if c.kind == "" {
+			return []string{}
+		}
+		return []string{c.kind}
+	}
+	return append([]string{}, c.kinds...)
+}
+
+// IsMultiple returns true if the context contains more than one kind of entity.
+func (c Context) IsMultiple() bool {
+	return len(c.kinds) > 1
+}
+
+// Individual returns the attributes of the entity of the specified kind, and true if there is one. If
+// kind is empty, DefaultContextKind is used.
+func (c Context) Individual(kind string) (User, bool) {
+	if kind == "" {
+		kind = DefaultContextKind
+	}
+	if c.individuals == nil {
+		if kind == c.kind {
+			return c.user, true
+		}
+		return User{}, false
+	}
+	u, ok := c.individuals[kind]
+	return u, ok
+}
+
+// Returns an error kind and a description of the problem if the context cannot be used for evaluation,
+// or "" and nil if it can.
+func (c Context) validate() (EvalErrorKind, error) {
+	if c.individuals == nil {
+		if c.kind == "" {
+			return EvalErrorUserNotSpecified, errors.New("context has no entities")
+		}
+		return validateContextKey(c.kind, c.user)
+	}
+	if len(c.kinds) == 0 {
+		return EvalErrorUserNotSpecified, errors.New("context has no entities")
+	}
+	for _, kind := range c.kinds {
+		if errKind, err := validateContextKey(kind, c.individuals[kind]); err != nil {
+			return errKind, err
+		}
+	}
+	return "", nil
+}
+
+func validateContextKey(kind string, u User) (EvalErrorKind, error) {
+	if u.Key != nil {
+		return "", nil
+	}
+	if kind == DefaultContextKind {
+		return EvalErrorUserNotSpecified, errors.New("user.Key cannot be nil")
+	}
+	return EvalErrorUserNotSpecified, fmt.Errorf("key of context kind %q cannot be nil", kind)
+}
+
+// Returns the User that represents this context in analytics events. Events are currently user-based, so
+// this is the "user" entity. If there is none, it returns an empty User: evaluations for the context are
+// still counted in summary events, but any individual feature events have no user, and no index event is
+// sent, so that entities of other kinds are not indexed as if they were users.
+func (c Context) eventUser() User {
+	u, _ := c.Individual(DefaultContextKind)
+	return u
+}
@@ -0,0 +1,93 @@
+package ldclient
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewContextUsesDefaultKindIfKindIsEmpty(t *testing.T) {
+	c := NewContext("", "key")
+
+	assert.Equal(t, []string{DefaultContextKind}, c.Kinds())
+	assert.False(t, c.IsMultiple())
+	u, ok := c.Individual(DefaultContextKind)
+	assert.True(t, ok)
+	assert.Equal(t, "key", *u.Key)
+}
+
+func TestNewContextFromUser(t *testing.T) {
+	user := NewUserBuilder("key").Name("Bob").Build()
+	c := NewContextFromUser(user)
+
+	u, ok := c.Individual("")
+	assert.True(t, ok)
+	assert.Equal(t, user, u)
+	assert.Equal(t, user, c.eventUser())
+}
+
+func TestNewMultiContext(t *testing.T) {
+	org1 := NewContext("org", "org-key-1")
+	org2 := NewContext("org", "org-key-2")
+	c := NewMultiContext(NewContext("user", "user-key"), NewContext("device", "device-key"), org1, org2)
+
+	assert.Equal(t, []string{"device", "org", "user"}, c.Kinds())
+	assert.True(t, c.IsMultiple())
+	u, ok := c.Individual("org")
+	assert.True(t, ok)
+	assert.Equal(t, "org-key-2", *u.Key)
+	_, ok = c.Individual("team")
+	assert.False(t, ok)
+}
+
+func TestMultiContextCanContainMultiContext(t *testing.T) {
+	c := NewMultiContext(NewMultiContext(NewContext("org", "org-key"), NewContext("user", "user-key")),
+		NewContext("device", "device-key"))
+
+	assert.Equal(t, []string{"device", "org", "user"}, c.Kinds())
+	u, ok := c.Individual("org")
+	assert.True(t, ok)
+	assert.Equal(t, "org-key", *u.Key)
+}
+
+func TestContextEventUser(t *testing.T) {
+	c := NewMultiContext(NewContext("org", "org-key"), NewContext("user", "user-key"))
+	assert.Equal(t, "user-key", *c.eventUser().Key)
+
+	c = NewMultiContext(NewContext("org", "org-key"), NewContext("device", "device-key"))
+	assert.Nil(t, c.eventUser().Key)
+
+	assert.Nil(t, NewContext("org", "org-key").eventUser().Key)
+}
+
+func TestContextValidation(t *testing.T) {
+	assertValid := func(c Context) {
+		errKind, err := c.validate()
+		assert.Equal(t, EvalErrorKind(""), errKind)
+		assert.NoError(t, err)
+	}
+	assertInvalid := func(c Context, message string) {
+		errKind, err := c.validate()
+		assert.Equal(t, EvalErrorUserNotSpecified, errKind)
+		if assert.Error(t, err) {
+			assert.Equal(t, message, err.Error())
+		}
+	}
+	assertValid(NewContext("org", "org-key"))
+	assertValid(NewMultiContext(NewContext("org", "org-key"), NewContext("user", "user-key")))
+	assertInvalid(Context{}, "context has no entities")
+	assertInvalid(NewMultiContext(), "context has no entities")
+	assertInvalid(NewContextFromUser(User{}), "user.Key cannot be nil")
+	assertInvalid(NewMultiContext(NewContext("user", "user-key"), NewContextWithAttributes("org", User{})),
+		`key of context kind "org" cannot be nil`)
+}
+
+func TestNewContextFromUserDoesNotAllocate(t *testing.T) {
+	user := NewUser("key")
+	allocs := testing.AllocsPerRun(100, func() {
+		c := NewContextFromUser(user)
+		_, _ = c.Individual(DefaultContextKind)
+		_, _ = c.validate()
+	})
+	assert.Equal(t, float64(0), allocs)
+}
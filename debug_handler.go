@@ -0,0 +1,190 @@
+package ldclient
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+
+	"gopkg.in/launchdarkly/go-server-sdk.v4/internal"
+)
+
+// NewDebugHandler returns an http.Handler that serves information about the state of an LDClient, for
+// operators who are troubleshooting an application. It is meant to be mounted on an administrative port,
+// and must not be exposed to the public, since it reveals the complete configuration of every flag.
+//
+// The handler serves the following paths, relative to wherever it is mounted (use http.StripPrefix
+// if it is not mounted at the root):
+//
+//     GET  /status                - client, data source, feature store, and event processor status,
+//                                   including the last error from the streaming or polling connection
+//     GET  /flags                 - all flags in the feature store
+//     GET  /flags/{key}           - a single flag
+//     GET  /segments              - all segments in the feature store
+//     GET  /segments/{key}        - a single segment
+//     POST /flags/{key}/evaluate  - evaluates a flag for the user whose JSON representation is the
+//                                   request body; add "?trace=true" to get an EvaluationTrace
+//
+// Evaluations performed by the handler do not generate analytics events. As with the Variation methods,
+// they return an error reason if the client is offline.
+//
+//     adminMux.Handle("/ld/", http.StripPrefix("/ld", ld.NewDebugHandler(client)))
+func NewDebugHandler(client *LDClient) http.Handler {
+	return debugHandler{client: client}
+}
+
+type debugHandler struct {
+	client *LDClient
+}
+
+type debugStatus struct {
+	Initialized           bool                 `json:"initialized"`
+	Offline               bool                 `json:"offline"`
+	DataSource            string               `json:"dataSource"`
+	DataSourceInitialized bool                 `json:"dataSourceInitialized"`
+	DataSourceLastError   *dataSourceErrorInfo `json:"dataSourceLastError,omitempty"`
+	StoreInitialized      bool                 `json:"storeInitialized"`
+	StoreAvailable        bool                 `json:"storeAvailable"`
+	Events                *eventProcessorStats `json:"events,omitempty"`
+}
+
+type dataSourceErrorInfo struct {
+	Message string `json:"message"`
+	Time    uint64 `json:"time"`
+}
+
+// Implemented by the streaming and polling processors.
+type dataSourceErrorReporter interface {
+	getLastError() *dataSourceErrorInfo
+}
+
+// Keeps track of the most recent error encountered by a data source, so the debug handler can report it.
+type dataSourceErrorTracker struct {
+	lastError *dataSourceErrorInfo
+	lock      sync.Mutex
+}
+
+func (t *dataSourceErrorTracker) record(err error) {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+	t.lastError = &dataSourceErrorInfo{Message: err.Error(), Time: now()}
+}
+
+func (t *dataSourceErrorTracker) get() *dataSourceErrorInfo {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+	return t.lastError
+}
+
+type debugEvaluationResult struct {
+	Value          interface{}               `json:"value"`
+	VariationIndex *int                      `json:"variationIndex"`
+	Reason         EvaluationReasonContainer `json:"reason"`
+}
+
+func (h debugHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	path := strings.Split(strings.Trim(r.URL.Path, "/"), "/")
+	switch {
+	case len(path) == 1 && path[0] == "status":
+		h.serveGet(w, r, func() (interface{}, error) { return h.getStatus(), nil })
+	case len(path) == 1 && path[0] == "flags":
+		h.serveGet(w, r, func() (interface{}, error) { return h.client.store.All(Features) })
+	case len(path) == 2 && path[0] == "flags":
+		h.serveGet(w, r, func() (interface{}, error) { return h.client.store.Get(Features, path[1]) })
+	case len(path) == 1 && path[0] == "segments":
+		h.serveGet(w, r, func() (interface{}, error) { return h.client.store.All(Segments) })
+	case len(path) == 2 && path[0] == "segments":
+		h.serveGet(w, r, func() (interface{}, error) { return h.client.store.Get(Segments, path[1]) })
+	case len(path) == 3 && path[0] == "flags" && path[2] == "evaluate":
+		if r.Method != http.MethodPost {
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		h.serveEvaluate(w, r, path[1])
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+func (h debugHandler) serveGet(w http.ResponseWriter, r *http.Request, getData func() (interface{}, error)) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	data, err := getData()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if data == nil {
+		http.NotFound(w, r)
+		return
+	}
+	writeDebugJSON(w, data)
+}
+
+func (h debugHandler) serveEvaluate(w http.ResponseWriter, r *http.Request, key string) {
+	var user User
+	if err := json.NewDecoder(r.Body).Decode(&user); err != nil {
+		http.Error(w, fmt.Sprintf("invalid user JSON: %s", err), http.StatusBadRequest)
+		return
+	}
+	trace, _ := h.client.ExplainEvaluation(key, user)
+	if r.URL.Query().Get("trace") == "true" {
+		writeDebugJSON(w, trace)
+		return
+	}
+	writeDebugJSON(w, debugEvaluationResult{
+		Value:          trace.Value.UnsafeArbitraryValue(), //nolint:megacheck // allow deprecated usage
+		VariationIndex: trace.VariationIndex,
+		Reason:         trace.Reason,
+	})
+}
+
+func (h debugHandler) getStatus() debugStatus {
+	status := debugStatus{
+		Initialized:           h.client.Initialized(),
+		Offline:               h.client.IsOffline(),
+		DataSource:            describeDataSource(h.client),
+		DataSourceInitialized: h.client.updateProcessor.Initialized(),
+		StoreInitialized:      h.client.store.Initialized(),
+		StoreAvailable:        true,
+	}
+	if r, ok := h.client.updateProcessor.(dataSourceErrorReporter); ok {
+		status.DataSourceLastError = r.getLastError()
+	}
+	if sp, ok := h.client.store.(internal.FeatureStoreStatusProvider); ok { // not all stores report status
+		status.StoreAvailable = sp.GetStoreStatus().Available
+	}
+	if ep, ok := h.client.eventProcessor.(*defaultEventProcessor); ok {
+		stats := ep.getStats()
+		status.Events = &stats
+	}
+	return status
+}
+
+func describeDataSource(client *LDClient) string {
+	switch client.updateProcessor.(type) {
+	case *streamProcessor:
+		return "streaming"
+	case *pollingProcessor:
+		return "polling"
+	case nullUpdateProcessor:
+		if client.config.UseLdd {
+			return "daemon"
+		}
+		return "none"
+	}
+	return "custom"
+}
+
+func writeDebugJSON(w http.ResponseWriter, data interface{}) {
+	bytes, err := json.Marshal(data)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	_, _ = w.Write(bytes)
+}
@@ -0,0 +1,151 @@
+package ldclient
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/launchdarkly/go-test-helpers/httphelpers"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	shared "gopkg.in/launchdarkly/go-server-sdk.v4/shared_test"
+)
+
+func doDebugRequest(t *testing.T, client *LDClient, method, path, body string) (int, map[string]interface{}) {
+	req, err := http.NewRequest(method, path, strings.NewReader(body))
+	require.NoError(t, err)
+	w := httptest.NewRecorder()
+	NewDebugHandler(client).ServeHTTP(w, req)
+	var data map[string]interface{}
+	if w.Code == http.StatusOK {
+		assert.Equal(t, "application/json", w.Header().Get("Content-Type"))
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &data))
+	}
+	return w.Code, data
+}
+
+func TestDebugHandlerServesFlagsAndSegments(t *testing.T) {
+	client := makeTestClient()
+	defer client.Close()
+	client.store.Upsert(Features, makeTestFlag("flagKey", 1, "a", "b"))
+	client.store.Upsert(Segments, &Segment{Key: "segKey", Version: 2})
+
+	status, data := doDebugRequest(t, client, "GET", "/flags", "")
+	assert.Equal(t, http.StatusOK, status)
+	assert.Contains(t, data, "flagKey")
+
+	status, data = doDebugRequest(t, client, "GET", "/flags/flagKey", "")
+	assert.Equal(t, http.StatusOK, status)
+	assert.Equal(t, "flagKey", data["key"])
+
+	status, _ = doDebugRequest(t, client, "GET", "/flags/unknown", "")
+	assert.Equal(t, http.StatusNotFound, status)
+
+	status, data = doDebugRequest(t, client, "GET", "/segments/segKey", "")
+	assert.Equal(t, http.StatusOK, status)
+	assert.Equal(t, float64(2), data["version"])
+}
+
+func TestDebugHandlerServesStatus(t *testing.T) {
+	client := makeTestClient()
+	defer client.Close()
+	client.store.Init(nil)
+
+	status, data := doDebugRequest(t, client, "GET", "/status", "")
+	assert.Equal(t, http.StatusOK, status)
+	assert.Equal(t, true, data["initialized"])
+	assert.Equal(t, true, data["storeInitialized"])
+	assert.Equal(t, true, data["storeAvailable"])
+	assert.Equal(t, "custom", data["dataSource"])
+	assert.Equal(t, true, data["dataSourceInitialized"])
+	assert.NotContains(t, data, "dataSourceLastError")
+	assert.NotContains(t, data, "events") // test client does not use the default event processor
+}
+
+func TestDebugHandlerStatusIncludesLastDataSourceError(t *testing.T) {
+	handler := httphelpers.HandlerWithStatus(401)
+	httphelpers.WithServer(handler, func(ts *httptest.Server) {
+		cfg := Config{Loggers: shared.NullLoggers(), PollInterval: time.Hour, BaseUri: ts.URL}
+		p := newPollingProcessor(cfg, newRequestor("fake", cfg, nil))
+		closeWhenReady := make(chan struct{})
+		p.Start(closeWhenReady)
+		defer p.Close()
+		<-closeWhenReady
+
+		client := makeTestClient()
+		defer client.Close()
+		client.updateProcessor = p
+
+		_, data := doDebugRequest(t, client, "GET", "/status", "")
+		assert.Equal(t, "polling", data["dataSource"])
+		assert.Equal(t, false, data["dataSourceInitialized"])
+		lastError, ok := data["dataSourceLastError"].(map[string]interface{})
+		require.True(t, ok)
+		assert.Contains(t, lastError["message"], "Invalid SDK key")
+		assert.NotEqual(t, float64(0), lastError["time"])
+	})
+}
+
+func TestDebugHandlerStatusIncludesEventProcessorStats(t *testing.T) {
+	ep, _ := createEventProcessor(epDefaultConfig)
+	defer ep.Close()
+	client := makeTestClientWithConfig(func(c *Config) { c.EventProcessor = ep })
+	defer client.Close()
+
+	_, data := doDebugRequest(t, client, "GET", "/status", "")
+	events, ok := data["events"].(map[string]interface{})
+	require.True(t, ok)
+	assert.Equal(t, float64(epDefaultConfig.Capacity), events["inboxCapacity"])
+	assert.Equal(t, float64(0), events["droppedEvents"])
+}
+
+func TestDebugHandlerEvaluatesFlagWithoutSendingEvents(t *testing.T) {
+	client := makeTestClient()
+	defer client.Close()
+	client.store.Upsert(Features, makeTestFlag("flagKey", 1, "a", "b"))
+
+	status, data := doDebugRequest(t, client, "POST", "/flags/flagKey/evaluate", `{"key":"userkey"}`)
+	assert.Equal(t, http.StatusOK, status)
+	assert.Equal(t, map[string]interface{}{
+		"value":          "b",
+		"variationIndex": float64(1),
+		"reason":         map[string]interface{}{"kind": "FALLTHROUGH"},
+	}, data)
+	assert.Equal(t, 0, len(client.eventProcessor.(*testEventProcessor).events))
+
+	status, data = doDebugRequest(t, client, "POST", "/flags/flagKey/evaluate?trace=true", `{"key":"userkey"}`)
+	assert.Equal(t, http.StatusOK, status)
+	assert.Equal(t, "flagKey", data["flagKey"])
+	assert.Contains(t, data, "fallthrough")
+}
+
+func TestDebugHandlerEvaluationReturnsErrorWhenOffline(t *testing.T) {
+	client := makeTestClientWithConfig(func(c *Config) { c.Offline = true })
+	defer client.Close()
+	client.store.Upsert(Features, makeTestFlag("flagKey", 1, "a", "b"))
+
+	status, data := doDebugRequest(t, client, "POST", "/flags/flagKey/evaluate", `{"key":"userkey"}`)
+	assert.Equal(t, http.StatusOK, status)
+	assert.Nil(t, data["value"])
+	assert.Equal(t, map[string]interface{}{"kind": "ERROR", "errorKind": "CLIENT_NOT_READY"}, data["reason"])
+}
+
+func TestDebugHandlerRejectsBadRequests(t *testing.T) {
+	client := makeTestClient()
+	defer client.Close()
+
+	status, _ := doDebugRequest(t, client, "POST", "/flags/flagKey/evaluate", `not json`)
+	assert.Equal(t, http.StatusBadRequest, status)
+
+	status, _ = doDebugRequest(t, client, "GET", "/flags/flagKey/evaluate", "")
+	assert.Equal(t, http.StatusMethodNotAllowed, status)
+
+	status, _ = doDebugRequest(t, client, "POST", "/flags", "")
+	assert.Equal(t, http.StatusMethodNotAllowed, status)
+
+	status, _ = doDebugRequest(t, client, "GET", "/other", "")
+	assert.Equal(t, http.StatusNotFound, status)
+}
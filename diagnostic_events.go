@@ -77,6 +77,8 @@ type diagnosticPeriodicEvent struct {
 	DeduplicatedUsers int                        `json:"deduplicatedUsers"`
 	EventsInLastBatch int                        `json:"eventsInLastBatch"`
 	StreamInits       []diagnosticStreamInitInfo `json:"streamInits"`
+	SpooledPayloads   int                        `json:"spooledPayloads"`
+	ExpiredPayloads   int                        `json:"expiredPayloads"`
 }
 
 type diagnosticStreamInitInfo struct {
@@ -92,6 +94,8 @@ type diagnosticsManager struct {
 	startTime         uint64
 	dataSinceTime     uint64
 	streamInits       []diagnosticStreamInitInfo
+	spooledPayloads   int
+	expiredPayloads   int
 	periodicEventGate <-chan struct{}
 	lock              sync.Mutex
 }
@@ -149,6 +153,15 @@ func (m *diagnosticsManager) RecordStreamInit(timestamp uint64, failed bool, dur
 	})
 }
 
+// Called by the event spool when it has stored undelivered payloads, or discarded stored payloads
+// because of its size or age limits.
+func (m *diagnosticsManager) RecordEventSpoolActivity(spooled int, expired int) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	m.spooledPayloads += spooled
+	m.expiredPayloads += expired
+}
+
 // Called by DefaultEventProcessor to create the initial diagnostics event that includes the configuration.
 func (m *diagnosticsManager) CreateInitEvent() diagnosticInitEvent {
 	sdkData := diagnosticSDKData{
@@ -243,8 +256,12 @@ func (m *diagnosticsManager) CreateStatsEventAndReset(
 		DroppedEvents:     droppedEvents,
 		DeduplicatedUsers: deduplicatedUsers,
 		StreamInits:       m.streamInits,
+		SpooledPayloads:   m.spooledPayloads,
+		ExpiredPayloads:   m.expiredPayloads,
 	}
 	m.streamInits = nil
+	m.spooledPayloads = 0
+	m.expiredPayloads = 0
 	m.dataSinceTime = timestamp
 	return event
 }
@@ -28,6 +28,9 @@ const (
 	// exist or due to an unexpected error. In this case the result value will be the default value
 	// that the caller passed to the client.
 	EvalReasonError EvalReasonKind = "ERROR"
+	// EvalReasonOverride indicates that the value was set by a FlagOverride, rather than by evaluating
+	// the flag.
+	EvalReasonOverride EvalReasonKind = "OVERRIDE"
 )
 
 // EvalErrorKind defines the possible values of the ErrorKind property of EvaluationReason.
@@ -84,6 +87,22 @@ type EvaluationReason interface {
 	GetErrorKind() EvalErrorKind
 }
 
+// experimentReason is implemented by the reason types for which a rollout can allocate the user to an
+// experiment. It is not part of EvaluationReason, so that existing implementations of that interface
+// outside of the SDK are still valid.
+type experimentReason interface {
+	IsInExperiment() bool
+}
+
+// reasonIsInExperiment returns true if the Kind is EvalReasonRuleMatch or EvalReasonFallthrough and the
+// user was allocated to an experiment by the rule's or the fallthrough's rollout.
+func reasonIsInExperiment(reason EvaluationReason) bool {
+	if r, ok := reason.(experimentReason); ok {
+		return r.IsInExperiment()
+	}
+	return false
+}
+
 type evaluationReasonBase struct {
 	// Kind describes the general category of the reason.
 	Kind EvalReasonKind `json:"kind"`
@@ -176,6 +195,8 @@ type EvaluationReasonRuleMatch struct {
 	RuleIndex int `json:"ruleIndex"`
 	// RuleID is the unique identifier of the rule that was matched.
 	RuleID string `json:"ruleId"`
+	// InExperiment is true if the user was allocated to an experiment by the rule's rollout.
+	InExperiment bool `json:"inExperiment,omitempty"`
 }
 
 func newEvalReasonRuleMatch(ruleIndex int, ruleID string) EvaluationReasonRuleMatch {
@@ -210,6 +231,11 @@ func (r EvaluationReasonRuleMatch) GetErrorKind() EvalErrorKind {
 	return ""
 }
 
+// IsInExperiment returns true if the user was allocated to an experiment by the rule's rollout.
+func (r EvaluationReasonRuleMatch) IsInExperiment() bool {
+	return r.InExperiment
+}
+
 // EvaluationReasonPrerequisiteFailed means that the flag was considered off because it had at
 // least one prerequisite flag that either was off or did not return the desired variation.
 //
@@ -260,6 +286,8 @@ func (r EvaluationReasonPrerequisiteFailed) GetErrorKind() EvalErrorKind {
 // EvaluationReason instead to test for EvalReasonFallthrough.
 type EvaluationReasonFallthrough struct {
 	evaluationReasonBase
+	// InExperiment is true if the user was allocated to an experiment by the fallthrough rollout.
+	InExperiment bool `json:"inExperiment,omitempty"`
 }
 
 var evalReasonFallthroughInstance = EvaluationReasonFallthrough{
@@ -290,6 +318,61 @@ func (r EvaluationReasonFallthrough) GetErrorKind() EvalErrorKind {
 	return ""
 }
 
+// IsInExperiment returns true if the user was allocated to an experiment by the fallthrough rollout.
+func (r EvaluationReasonFallthrough) IsInExperiment() bool {
+	return r.InExperiment
+}
+
+// Returns a copy of a rule match or fallthrough reason with InExperiment set. Other kinds of reasons
+// are returned unchanged.
+func reasonWithInExperiment(reason EvaluationReason) EvaluationReason {
+	switch r := reason.(type) {
+	case EvaluationReasonRuleMatch:
+		r.InExperiment = true
+		return r
+	case EvaluationReasonFallthrough:
+		r.InExperiment = true
+		return r
+	}
+	return reason
+}
+
+// EvaluationReasonOverride means that the value was set by a FlagOverride.
+//
+// Deprecated: This type will be removed in a future version. Use the GetKind() method on
+// EvaluationReason instead to test for EvalReasonOverride.
+type EvaluationReasonOverride struct {
+	evaluationReasonBase
+}
+
+var evalReasonOverrideInstance EvaluationReason = EvaluationReasonOverride{
+	evaluationReasonBase: evaluationReasonBase{Kind: EvalReasonOverride},
+}
+
+func (r EvaluationReasonOverride) String() string {
+	return string(r.GetKind())
+}
+
+// GetRuleIndex for this type always returns -1.
+func (r EvaluationReasonOverride) GetRuleIndex() int {
+	return -1
+}
+
+// GetRuleID for this type always returns an empty string.
+func (r EvaluationReasonOverride) GetRuleID() string {
+	return ""
+}
+
+// GetPrerequisiteKey for this type always returns an empty string.
+func (r EvaluationReasonOverride) GetPrerequisiteKey() string {
+	return ""
+}
+
+// GetErrorKind for this type always returns an empty string.
+func (r EvaluationReasonOverride) GetErrorKind() EvalErrorKind {
+	return ""
+}
+
 // EvaluationReasonError means that the flag could not be evaluated, e.g. because it does not
 // exist or due to an unexpected error.
 //
@@ -411,9 +494,15 @@ func (c *EvaluationReasonContainer) UnmarshalJSON(data []byte) error {
 	case EvalReasonOff:
 		c.Reason = evalReasonOffInstance
 	case EvalReasonFallthrough:
-		c.Reason = evalReasonFallthroughInstance
+		var r EvaluationReasonFallthrough
+		if err := json.Unmarshal(data, &r); err != nil {
+			return err
+		}
+		c.Reason = r
 	case EvalReasonTargetMatch:
 		c.Reason = evalReasonTargetMatchInstance
+	case EvalReasonOverride:
+		c.Reason = evalReasonOverrideInstance
 	case EvalReasonRuleMatch:
 		var r EvaluationReasonRuleMatch
 		if err := json.Unmarshal(data, &r); err != nil {
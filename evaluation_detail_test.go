@@ -77,6 +77,37 @@ func TestFallthroughReasonSerialization(t *testing.T) {
 	assert.Equal(t, reason, r1.Reason)
 }
 
+func TestOverrideReasonSerialization(t *testing.T) {
+	reason := evalReasonOverrideInstance
+	expected := `{"kind":"OVERRIDE"}`
+	actual, err := json.Marshal(reason)
+	assert.NoError(t, err)
+	assert.JSONEq(t, expected, string(actual))
+	assert.Equal(t, "OVERRIDE", reason.String())
+
+	var r1 EvaluationReasonContainer
+	err = json.Unmarshal(actual, &r1)
+	assert.NoError(t, err)
+	assert.Equal(t, reason, r1.Reason)
+}
+
+func TestInExperimentReasonSerialization(t *testing.T) {
+	for _, reason := range []EvaluationReason{
+		reasonWithInExperiment(newEvalReasonRuleMatch(1, "id")),
+		reasonWithInExperiment(evalReasonFallthroughInstance),
+	} {
+		actual, err := json.Marshal(reason)
+		assert.NoError(t, err)
+		assert.Contains(t, string(actual), `"inExperiment":true`)
+
+		var r1 EvaluationReasonContainer
+		err = json.Unmarshal(actual, &r1)
+		assert.NoError(t, err)
+		assert.Equal(t, reason, r1.Reason)
+		assert.True(t, reasonIsInExperiment(r1.Reason))
+	}
+}
+
 func TestErrorReasonSerialization(t *testing.T) {
 	reason := newEvalReasonError(EvalErrorException)
 	expected := `{"kind":"ERROR","errorKind":"EXCEPTION"}`
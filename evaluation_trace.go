@@ -0,0 +1,123 @@
+package ldclient
+
+import (
+	"gopkg.in/launchdarkly/go-sdk-common.v1/ldvalue"
+)
+
+// EvaluationTrace is a step-by-step description of how a feature flag was evaluated, as returned by
+// LDClient.ExplainEvaluation. It is intended for troubleshooting, and can be serialized to JSON.
+//
+// The steps are recorded in the order in which evaluation examines them, and stop where evaluation
+// stops: for instance, if the second rule matches, Rules describes only the first two rules, and
+// Fallthrough is nil. Within a rule, Clauses stops at the first clause that did not match.
+type EvaluationTrace struct {
+	// FlagKey is the key of the flag.
+	FlagKey string `json:"flagKey"`
+	// FlagVersion is the version of the flag, or zero if the flag was not found.
+	FlagVersion int `json:"flagVersion,omitempty"`
+	// On is true if the flag's targeting was turned on.
+	On bool `json:"on"`
+	// Value is the result of the evaluation, or a null value if the flag did not provide one (in which
+	// case the Variation methods return the default value).
+	Value ldvalue.Value `json:"value"`
+	// VariationIndex is the index of the returned value within the flag's list of variations.
+	VariationIndex *int `json:"variationIndex"`
+	// Reason is the same EvaluationReason that the VariationDetail methods return.
+	Reason EvaluationReasonContainer `json:"reason"`
+	// Prerequisites describes the prerequisite flags that were checked.
+	Prerequisites []PrerequisiteTrace `json:"prerequisites,omitempty"`
+	// Targets describes the individual targets that were checked.
+	Targets []TargetTrace `json:"targets,omitempty"`
+	// Rules describes the targeting rules that were checked.
+	Rules []RuleTrace `json:"rules,omitempty"`
+	// Fallthrough describes the fallthrough variation or rollout, if no target or rule matched.
+	Fallthrough *RolloutTrace `json:"fallthrough,omitempty"`
+}
+
+// PrerequisiteTrace describes a prerequisite flag that was checked during an evaluation.
+type PrerequisiteTrace struct {
+	// Key is the key of the prerequisite flag.
+	Key string `json:"key"`
+	// Variation is the variation index that the prerequisite flag must return.
+	Variation int `json:"variation"`
+	// Found is false if the prerequisite flag does not exist.
+	Found bool `json:"found"`
+	// Met is true if the prerequisite flag is on and returned the required variation.
+	Met bool `json:"met"`
+	// Trace describes the evaluation of the prerequisite flag itself.
+	Trace *EvaluationTrace `json:"trace,omitempty"`
+}
+
+// TargetTrace describes an individual target that was checked during an evaluation.
+type TargetTrace struct {
+	// ContextKind is the kind of entity whose key was compared to the target's keys.
+	ContextKind string `json:"contextKind,omitempty"`
+	// Variation is the variation index of the target.
+	Variation int `json:"variation"`
+	// Matched is true if the key was in the target's list.
+	Matched bool `json:"matched"`
+}
+
+// RuleTrace describes a targeting rule that was checked during an evaluation.
+type RuleTrace struct {
+	// Index is the index of the rule (0 being the first).
+	Index int `json:"index"`
+	// ID is the unique identifier of the rule.
+	ID string `json:"id,omitempty"`
+	// Matched is true if all of the rule's clauses matched.
+	Matched bool `json:"matched"`
+	// Clauses describes the clauses that were checked.
+	Clauses []ClauseTrace `json:"clauses"`
+	// Rollout describes the rule's variation or rollout, if the rule matched.
+	Rollout *RolloutTrace `json:"rollout,omitempty"`
+}
+
+// ClauseTrace describes a clause that was checked during an evaluation.
+type ClauseTrace struct {
+	// ContextKind, Attribute, Op, Values, and Negate are copied from the Clause.
+	ContextKind string        `json:"contextKind,omitempty"`
+	Attribute   string        `json:"attribute"`
+	Op          Operator      `json:"op"`
+	Values      []interface{} `json:"values"`
+	Negate      bool          `json:"negate"`
+	// UserValue is the value of the attribute that was tested, or nil if there was no such attribute.
+	// It is always nil for a segmentMatch clause.
+	UserValue interface{} `json:"userValue"`
+	// Matched is true if the clause matched.
+	Matched bool `json:"matched"`
+	// Segments describes each segment that was checked by a segmentMatch clause.
+	Segments []SegmentTrace `json:"segments,omitempty"`
+}
+
+// SegmentTrace describes a segment that was checked during an evaluation.
+type SegmentTrace struct {
+	// Key is the key of the segment.
+	Key string `json:"key"`
+	// Found is false if the segment does not exist.
+	Found bool `json:"found"`
+	// Matched is true if the user belongs to the segment.
+	Matched bool `json:"matched"`
+	// Explanation is the explanation returned by Segment.ContainsUser, if any.
+	Explanation *SegmentExplanation `json:"explanation,omitempty"`
+}
+
+// RolloutTrace describes a fixed variation or a percentage rollout that was used during an evaluation.
+type RolloutTrace struct {
+	// Variation is the selected variation index, or nil if the variation or rollout was malformed.
+	Variation *int `json:"variation"`
+	// ContextKind is the kind of entity that was bucketed, for a rollout.
+	ContextKind string `json:"contextKind,omitempty"`
+	// BucketBy is the attribute that was used to compute the bucket, for a rollout.
+	BucketBy string `json:"bucketBy,omitempty"`
+	// Bucket is the computed bucket value, from 0 to 1, for a rollout. It is nil if the evaluation
+	// context had no entity of the rollout's kind.
+	Bucket *float32 `json:"bucket,omitempty"`
+	// InExperiment is true if the rollout allocated the user to an experiment.
+	InExperiment bool `json:"inExperiment,omitempty"`
+}
+
+func (f FeatureFlag) traceEvaluation(context Context, store FeatureStore) EvaluationTrace {
+	var trace EvaluationTrace
+	f.evaluateDetailWithTrace(context, store, false, &trace)
+	return trace
+}
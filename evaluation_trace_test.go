@@ -0,0 +1,169 @@
+package ldclient
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gopkg.in/launchdarkly/go-sdk-common.v1/ldvalue"
+)
+
+func TestTraceOfFlagThatIsOff(t *testing.T) {
+	f := FeatureFlag{Key: "feature", Version: 3, OffVariation: intPtr(1), Variations: []interface{}{"a", "b"}}
+
+	trace := f.traceEvaluation(NewContextFromUser(flagUser), emptyFeatureStore)
+
+	assert.Equal(t, EvaluationTrace{
+		FlagKey:        "feature",
+		FlagVersion:    3,
+		Value:          ldvalue.String("b"),
+		VariationIndex: intPtr(1),
+		Reason:         EvaluationReasonContainer{evalReasonOffInstance},
+	}, trace)
+}
+
+func TestTraceStopsAtFailedPrerequisite(t *testing.T) {
+	f0 := FeatureFlag{
+		Key:           "feature0",
+		On:            true,
+		OffVariation:  intPtr(1),
+		Prerequisites: []Prerequisite{{"feature1", 1}, {"feature2", 1}},
+		Fallthrough:   VariationOrRollout{Variation: intPtr(0)},
+		Variations:    []interface{}{"fall", "off"},
+	}
+	f1 := FeatureFlag{
+		Key:         "feature1",
+		On:          true,
+		Fallthrough: VariationOrRollout{Variation: intPtr(0)},
+		Variations:  []interface{}{"nogo", "go"},
+	}
+	featureStore := NewInMemoryFeatureStore(nil)
+	featureStore.Upsert(Features, &f1)
+
+	trace := f0.traceEvaluation(NewContextFromUser(flagUser), featureStore)
+
+	assert.Equal(t, newEvalReasonPrerequisiteFailed("feature1"), trace.Reason.Reason)
+	require.Equal(t, 1, len(trace.Prerequisites))
+	p := trace.Prerequisites[0]
+	assert.True(t, p.Found)
+	assert.False(t, p.Met)
+	require.NotNil(t, p.Trace)
+	assert.Equal(t, intPtr(0), p.Trace.VariationIndex)
+	assert.Equal(t, intPtr(0), p.Trace.Fallthrough.Variation)
+	assert.Nil(t, trace.Targets)
+	assert.Nil(t, trace.Rules)
+	assert.Nil(t, trace.Fallthrough)
+}
+
+func TestTraceShowsFailedClauseAndUserValue(t *testing.T) {
+	user := NewUserBuilder("userkey").Name("Bob").Build()
+	f := FeatureFlag{
+		Key:     "feature",
+		On:      true,
+		Targets: []Target{{Values: []string{"someone-else"}, Variation: 1}},
+		Rules: []Rule{
+			{ID: "rule0", Clauses: []Clause{
+				{Attribute: "key", Op: "in", Values: []interface{}{"userkey"}},
+				{Attribute: "name", Op: "in", Values: []interface{}{"Alice"}},
+				{Attribute: "email", Op: "in", Values: []interface{}{"x"}},
+			}, VariationOrRollout: VariationOrRollout{Variation: intPtr(1)}},
+			{ID: "rule1", Clauses: []Clause{
+				{Attribute: "name", Op: "in", Values: []interface{}{"Bob"}},
+			}, VariationOrRollout: VariationOrRollout{Variation: intPtr(1)}},
+		},
+		Fallthrough: VariationOrRollout{Variation: intPtr(0)},
+		Variations:  []interface{}{false, true},
+	}
+
+	trace := f.traceEvaluation(NewContextFromUser(user), emptyFeatureStore)
+
+	assert.Equal(t, newEvalReasonRuleMatch(1, "rule1"), trace.Reason.Reason)
+	assert.Equal(t, []TargetTrace{{Variation: 1, Matched: false}}, trace.Targets)
+	require.Equal(t, 2, len(trace.Rules))
+	r0 := trace.Rules[0]
+	assert.False(t, r0.Matched)
+	require.Equal(t, 2, len(r0.Clauses))
+	assert.True(t, r0.Clauses[0].Matched)
+	assert.False(t, r0.Clauses[1].Matched)
+	assert.Equal(t, "Bob", r0.Clauses[1].UserValue)
+	assert.Nil(t, r0.Rollout)
+	r1 := trace.Rules[1]
+	assert.True(t, r1.Matched)
+	assert.Equal(t, &RolloutTrace{Variation: intPtr(1)}, r1.Rollout)
+	assert.Nil(t, trace.Fallthrough)
+}
+
+func TestTraceShowsSegmentMembership(t *testing.T) {
+	segment := Segment{Key: "segkey", Excluded: []string{"foo"}}
+	f := booleanFlagWithClause(Clause{Op: "segmentMatch", Values: []interface{}{"unknown", "segkey"}})
+	featureStore := NewInMemoryFeatureStore(nil)
+	featureStore.Upsert(Segments, &segment)
+
+	trace := f.traceEvaluation(NewContext("user", "foo"), featureStore)
+
+	require.Equal(t, 1, len(trace.Rules))
+	require.Equal(t, 1, len(trace.Rules[0].Clauses))
+	assert.Equal(t, []SegmentTrace{
+		{Key: "unknown"},
+		{Key: "segkey", Found: true, Explanation: &SegmentExplanation{Kind: "excluded"}},
+	}, trace.Rules[0].Clauses[0].Segments)
+}
+
+func TestTraceShowsRolloutBucket(t *testing.T) {
+	rollout := Rollout{Variations: []WeightedVariation{{Variation: 0, Weight: 60000}, {Variation: 1, Weight: 40000}}}
+	f := FeatureFlag{
+		Key:         "hashKey",
+		Salt:        "saltyA",
+		On:          true,
+		Fallthrough: VariationOrRollout{Rollout: &rollout},
+		Variations:  []interface{}{"a", "b"},
+	}
+
+	trace := f.traceEvaluation(NewContext("user", "userKeyA"), emptyFeatureStore)
+
+	require.NotNil(t, trace.Fallthrough)
+	assert.Equal(t, intPtr(0), trace.Fallthrough.Variation)
+	assert.Equal(t, "key", trace.Fallthrough.BucketBy)
+	require.NotNil(t, trace.Fallthrough.Bucket)
+	assert.InEpsilon(t, 0.42157587, *trace.Fallthrough.Bucket, 0.0000001) // see TestBucketUserByKey
+}
+
+func TestExplainEvaluation(t *testing.T) {
+	flag := makeTestFlag("flagKey", 1, "a", "b")
+	client := makeTestClient()
+	defer client.Close()
+	client.store.Upsert(Features, flag)
+
+	trace, err := client.ExplainEvaluation(flag.Key, evalTestUser)
+	assert.NoError(t, err)
+	assert.Equal(t, ldvalue.String("b"), trace.Value)
+	assert.Equal(t, &RolloutTrace{Variation: intPtr(1)}, trace.Fallthrough)
+	assert.Equal(t, 0, len(client.eventProcessor.(*testEventProcessor).events))
+
+	data, err := json.Marshal(trace)
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"flagKey":"flagKey","flagVersion":1,"on":true,"value":"b","variationIndex":1,`+
+		`"reason":{"kind":"FALLTHROUGH"},"fallthrough":{"variation":1}}`, string(data))
+}
+
+func TestExplainEvaluationForUnknownFlag(t *testing.T) {
+	client := makeTestClient()
+	defer client.Close()
+
+	trace, err := client.ExplainEvaluation("unknown", evalTestUser)
+	assert.Error(t, err)
+	assert.Equal(t, newEvalReasonError(EvalErrorFlagNotFound), trace.Reason.Reason)
+}
+
+func TestExplainEvaluationWhenOffline(t *testing.T) {
+	flag := makeTestFlag("flagKey", 1, "a", "b")
+	client := makeTestClientWithConfig(func(c *Config) { c.Offline = true })
+	defer client.Close()
+	client.store.Upsert(Features, flag)
+
+	trace, err := client.ExplainEvaluation(flag.Key, evalTestUser)
+	assert.NoError(t, err)
+	assert.Equal(t, newEvalReasonError(EvalErrorClientNotReady), trace.Reason.Reason)
+	assert.Nil(t, trace.Fallthrough)
+}
@@ -2,13 +2,16 @@ package ldclient
 
 import (
 	"bytes"
+	"compress/gzip"
 	"encoding/json"
 	"fmt"
+	"io"
 	"io/ioutil"
 	"math/rand"
 	"net/http"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/google/uuid"
@@ -28,12 +31,48 @@ type EventProcessor interface {
 	Close() error
 }
 
+// EventsDroppedReason describes why the event processor discarded analytics events.
+type EventsDroppedReason string
+
+const (
+	// EventsDroppedInboxFull means that events were being produced faster than the event processor could
+	// process them, so they were discarded without being added to the event buffer.
+	EventsDroppedInboxFull EventsDroppedReason = "INBOX_FULL"
+	// EventsDroppedBufferFull means that the event buffer reached Config.Capacity before it could be flushed.
+	EventsDroppedBufferFull EventsDroppedReason = "BUFFER_FULL"
+)
+
+// EventsDroppedHandler is a function that the default event processor calls whenever it discards events
+// because of backpressure. It may be called from any goroutine, including the one that is evaluating a flag,
+// so it should return quickly and must not block.
+type EventsDroppedHandler func(reason EventsDroppedReason, count int)
+
+// EventPayloadResult describes an attempt by the default event processor to deliver a payload of analytics
+// events to LaunchDarkly.
+type EventPayloadResult struct {
+	// EventCount is the number of events in the payload, including any summary event.
+	EventCount int
+	// Latency is the total time spent sending the payload, including any retry.
+	Latency time.Duration
+	// StatusCode is the HTTP status of the last response, or zero if there was a network error.
+	StatusCode int
+	// Success is true if the payload was accepted by LaunchDarkly.
+	Success bool
+}
+
+// EventPayloadResultHandler is a function that the default event processor calls after each attempt to
+// deliver a payload of analytics events. It is called from one of the event processor's flush workers.
+type EventPayloadResultHandler func(EventPayloadResult)
+
 type nullEventProcessor struct{}
 
 type defaultEventProcessor struct {
+	droppedEvents int64 // accessed atomically; must be first for 64-bit alignment on 32-bit platforms
 	inboxCh       chan eventDispatcherMessage
 	inboxFullOnce sync.Once
 	closeOnce     sync.Once
+	sinks         []EventSink
+	droppedFn     EventsDroppedHandler
 	loggers       ldlog.Loggers
 }
 
@@ -53,6 +92,7 @@ type eventBuffer struct {
 	capacity         int
 	capacityExceeded bool
 	droppedEvents    int
+	droppedFn        EventsDroppedHandler
 	loggers          ldlog.Loggers
 }
 
@@ -63,12 +103,14 @@ type flushPayload struct {
 }
 
 type sendEventsTask struct {
-	client        *http.Client
-	eventsURI     string
-	diagnosticURI string
-	sdkKey        string
-	config        Config
-	formatter     eventOutputFormatter
+	client            *http.Client
+	eventsURI         string
+	diagnosticURI     string
+	sdkKey            string
+	config            Config
+	formatter         eventOutputFormatter
+	spool             *eventSpool
+	diagnosticLoggers ldlog.Loggers
 }
 
 // Payload of the inboxCh channel.
@@ -89,14 +131,17 @@ type syncEventsMessage struct {
 }
 
 const (
-	maxFlushWorkers    = 5
-	eventSchemaHeader  = "X-LaunchDarkly-Event-Schema"
-	payloadIDHeader    = "X-LaunchDarkly-Payload-ID"
-	currentEventSchema = "3"
-	defaultURIPath     = "/bulk"
-	diagnosticsURIPath = "/diagnostic"
+	defaultFlushWorkers = 5
+	eventSchemaHeader   = "X-LaunchDarkly-Event-Schema"
+	payloadIDHeader     = "X-LaunchDarkly-Payload-ID"
+	currentEventSchema  = "3"
+	defaultURIPath      = "/bulk"
+	diagnosticsURIPath  = "/diagnostic"
 )
 
+// DefaultEventCompressionThreshold is the default value for Config.EventCompressionThreshold.
+const DefaultEventCompressionThreshold = 1024
+
 func newNullEventProcessor() *nullEventProcessor {
 	return &nullEventProcessor{}
 }
@@ -113,17 +158,48 @@ func (n *nullEventProcessor) Close() error {
 // This is normally only used internally; it is public because the Go SDK code is reused by other LaunchDarkly
 // components.
 func NewDefaultEventProcessor(sdkKey string, config Config, client *http.Client) EventProcessor {
+	config.Loggers = config.Loggers.ForComponent(ldlog.ComponentEvents)
 	if client == nil {
 		client = config.newHTTPClient()
 	}
-	inboxCh := make(chan eventDispatcherMessage, config.Capacity)
+	client = config.withRequestModifier(client)
+	inboxCapacity := config.EventInboxCapacity
+	if inboxCapacity <= 0 {
+		inboxCapacity = config.Capacity
+	}
+	inboxCh := make(chan eventDispatcherMessage, inboxCapacity)
+	ep := &defaultEventProcessor{
+		inboxCh: inboxCh,
+		sinks:   config.EventSinks,
+		loggers: config.Loggers,
+	}
+	appDroppedFn := config.EventsDroppedHandler
+	ep.droppedFn = func(reason EventsDroppedReason, count int) {
+		atomic.AddInt64(&ep.droppedEvents, int64(count))
+		if appDroppedFn != nil {
+			appDroppedFn(reason, count)
+		}
+	}
+	config.EventsDroppedHandler = ep.droppedFn
 	startEventDispatcher(sdkKey, config, client, inboxCh)
 	if config.SamplingInterval > 0 {
 		config.Loggers.Warn("Config.SamplingInterval is deprecated")
 	}
-	return &defaultEventProcessor{
-		inboxCh: inboxCh,
-		loggers: config.Loggers,
+	return ep
+}
+
+// eventProcessorStats is a snapshot of the state of the default event processor.
+type eventProcessorStats struct {
+	InboxLength   int   `json:"inboxLength"`
+	InboxCapacity int   `json:"inboxCapacity"`
+	DroppedEvents int64 `json:"droppedEvents"`
+}
+
+func (ep *defaultEventProcessor) getStats() eventProcessorStats {
+	return eventProcessorStats{
+		InboxLength:   len(ep.inboxCh),
+		InboxCapacity: cap(ep.inboxCh),
+		DroppedEvents: atomic.LoadInt64(&ep.droppedEvents),
 	}
 }
 
@@ -148,6 +224,9 @@ func (ep *defaultEventProcessor) postNonBlockingMessageToInbox(e eventDispatcher
 	ep.inboxFullOnce.Do(func() {
 		ep.loggers.Warn("Events are being produced faster than they can be processed; some events will be dropped")
 	})
+	if _, ok := e.(sendEventMessage); ok {
+		ep.droppedFn(EventsDroppedInboxFull, 1)
+	}
 	return false
 }
 
@@ -160,6 +239,11 @@ func (ep *defaultEventProcessor) Close() error {
 		m := shutdownEventsMessage{replyCh: make(chan struct{})}
 		ep.inboxCh <- m
 		<-m.replyCh
+		for _, sink := range ep.sinks {
+			if c, ok := sink.(io.Closer); ok { // not all EventSinks implement Closer
+				_ = c.Close()
+			}
+		}
 	})
 	return nil
 }
@@ -177,16 +261,36 @@ func startEventDispatcher(
 
 	// Start a fixed-size pool of workers that wait on flushTriggerCh. This is the
 	// maximum number of flushes we can do concurrently.
+	flushWorkers := config.EventFlushWorkers
+	if flushWorkers <= 0 {
+		flushWorkers = defaultFlushWorkers
+	}
 	flushCh := make(chan *flushPayload, 1)
 	var workersGroup sync.WaitGroup
-	for i := 0; i < maxFlushWorkers; i++ {
-		startFlushTask(sdkKey, config, client, flushCh, &workersGroup,
-			func(r *http.Response) { ed.handleResponse(r) })
+	var spool *eventSpool
+	if config.EventSpoolDir != "" && !config.EventSinksOnly {
+		var err error
+		if spool, err = newEventSpool(config); err != nil {
+			config.Loggers.Errorw("Unable to use event spool directory", ldlog.NewField("directory", config.EventSpoolDir),
+				ldlog.Err(err))
+		}
+	}
+	responseFn := func(r *http.Response) { ed.handleResponse(r) }
+	for i := 0; i < flushWorkers; i++ {
+		startFlushTask(sdkKey, config, client, flushCh, &workersGroup, spool, responseFn)
 	}
 	if config.diagnosticsManager != nil {
 		event := config.diagnosticsManager.CreateInitEvent()
 		ed.sendDiagnosticsEvent(event, client, flushCh, &workersGroup)
 	}
+	if spool != nil {
+		// Deliver anything that was left in the spool by a previous run, without waiting for new events.
+		workersGroup.Add(1)
+		go func() {
+			defer workersGroup.Done()
+			newSendEventsTask(sdkKey, config, client, spool).replaySpool(responseFn)
+		}()
+	}
 	go ed.runMainLoop(inboxCh, flushCh, &workersGroup, client)
 }
 
@@ -204,6 +308,7 @@ func (ed *eventDispatcher) runMainLoop(
 		events:     make([]Event, 0, ed.config.Capacity),
 		summarizer: newEventSummarizer(),
 		capacity:   ed.config.Capacity,
+		droppedFn:  ed.config.EventsDroppedHandler,
 		loggers:    ed.config.Loggers,
 	}
 	userKeys := newLruCache(ed.config.UserKeysCapacity)
@@ -288,7 +393,7 @@ func (ed *eventDispatcher) processEvent(evt Event, outbox *eventBuffer, userKeys
 	switch evt := evt.(type) {
 	case FeatureRequestEvent:
 		if ed.shouldSampleEvent() {
-			willAddFullEvent = evt.TrackEvents
+			willAddFullEvent = evt.TrackEvents && shouldSampleWithInterval(ed.config.EventSampling.FeatureEvents[evt.Key])
 			if ed.shouldDebugEvent(&evt) {
 				de := evt
 				de.Debug = true
@@ -296,7 +401,7 @@ func (ed *eventDispatcher) processEvent(evt Event, outbox *eventBuffer, userKeys
 			}
 		}
 	default:
-		willAddFullEvent = ed.shouldSampleEvent()
+		willAddFullEvent = ed.shouldSampleEvent() && ed.shouldSampleEventOfKind(evt)
 	}
 
 	// For each user we haven't seen before, we add an index event - unless this is already
@@ -304,10 +409,13 @@ func (ed *eventDispatcher) processEvent(evt Event, outbox *eventBuffer, userKeys
 	// the user, and can be omitted if that event will contain an inline user.
 	if !(willAddFullEvent && ed.config.InlineUsersInEvents) {
 		user := evt.GetBase().User
-		if noticeUser(userKeys, &user) {
-			ed.deduplicatedUsers++
-		} else {
-			if _, ok := evt.(IdentifyEvent); !ok {
+		_, isIdentify := evt.(IdentifyEvent)
+		// The sampling decision comes first: if the index event is sampled out, the user must not be
+		// noticed, or no index event would be sent for that user until the user keys are flushed.
+		if isIdentify || shouldSampleWithInterval(ed.config.EventSampling.IndexEvents) {
+			if noticeUser(userKeys, &user) {
+				ed.deduplicatedUsers++
+			} else if !isIdentify {
 				indexEvent := IndexEvent{
 					BaseEvent{CreationDate: evt.GetBase().CreationDate, User: user},
 				}
@@ -335,6 +443,22 @@ func (ed *eventDispatcher) shouldSampleEvent() bool {
 	return ed.config.SamplingInterval == 0 || rand.Int31n(ed.config.SamplingInterval) == 0
 }
 
+// Applies the per-kind sampling settings in Config.EventSampling to custom and identify events. Feature
+// and index events are handled separately in processEvent.
+func (ed *eventDispatcher) shouldSampleEventOfKind(evt Event) bool {
+	switch evt := evt.(type) {
+	case CustomEvent:
+		return shouldSampleWithInterval(ed.config.EventSampling.CustomEvents[evt.Key])
+	case IdentifyEvent:
+		return shouldSampleWithInterval(ed.config.EventSampling.IdentifyEvents)
+	}
+	return true
+}
+
+func shouldSampleWithInterval(interval int32) bool {
+	return interval <= 1 || rand.Int31n(interval) == 0
+}
+
 func (ed *eventDispatcher) shouldDebugEvent(evt *FeatureRequestEvent) bool {
 	if evt.DebugEventsUntilDate == nil {
 		return false
@@ -390,7 +514,8 @@ func (ed *eventDispatcher) isDisabled() bool {
 
 func (ed *eventDispatcher) handleResponse(resp *http.Response) {
 	if err := checkForHttpError(resp.StatusCode, resp.Request.URL.String()); err != nil {
-		ed.config.Loggers.Error(httpErrorMessage(resp.StatusCode, "posting events", "some events were dropped"))
+		ed.config.Loggers.Errorw(httpErrorMessage(resp.StatusCode, "posting events", "some events were dropped"),
+			ldlog.StatusCode(resp.StatusCode))
 		if !isHTTPErrorRecoverable(resp.StatusCode) {
 			ed.stateLock.Lock()
 			defer ed.stateLock.Unlock()
@@ -434,6 +559,9 @@ func (b *eventBuffer) addEvent(event Event) {
 			b.loggers.Warn("Exceeded event queue capacity. Increase capacity to avoid dropping events.")
 		}
 		b.droppedEvents++
+		if b.droppedFn != nil {
+			b.droppedFn(EventsDroppedBufferFull, 1)
+		}
 		return
 	}
 	b.capacityExceeded = false
@@ -457,7 +585,12 @@ func (b *eventBuffer) clear() {
 }
 
 func startFlushTask(sdkKey string, config Config, client *http.Client, flushCh <-chan *flushPayload,
-	workersGroup *sync.WaitGroup, responseFn func(*http.Response)) {
+	workersGroup *sync.WaitGroup, spool *eventSpool, responseFn func(*http.Response)) {
+	t := newSendEventsTask(sdkKey, config, client, spool)
+	go t.run(flushCh, responseFn, workersGroup)
+}
+
+func newSendEventsTask(sdkKey string, config Config, client *http.Client, spool *eventSpool) *sendEventsTask {
 	ef := eventOutputFormatter{
 		userFilter:  newUserFilter(config),
 		inlineUsers: config.InlineUsersInEvents,
@@ -467,15 +600,16 @@ func startFlushTask(sdkKey string, config Config, client *http.Client, flushCh <
 	if uri == "" {
 		uri = strings.TrimRight(config.EventsUri, "/") + defaultURIPath
 	}
-	t := sendEventsTask{
-		client:        client,
-		eventsURI:     uri,
-		diagnosticURI: strings.TrimRight(config.EventsUri, "/") + diagnosticsURIPath,
-		sdkKey:        sdkKey,
-		config:        config,
-		formatter:     ef,
+	return &sendEventsTask{
+		client:            client,
+		eventsURI:         uri,
+		diagnosticURI:     strings.TrimRight(config.EventsUri, "/") + diagnosticsURIPath,
+		sdkKey:            sdkKey,
+		config:            config,
+		formatter:         ef,
+		spool:             spool,
+		diagnosticLoggers: config.Loggers.ForComponent(ldlog.ComponentDiagnostics),
 	}
-	go t.run(flushCh, responseFn, workersGroup)
 }
 
 func (t *sendEventsTask) run(flushCh <-chan *flushPayload, responseFn func(*http.Response),
@@ -487,13 +621,18 @@ func (t *sendEventsTask) run(flushCh <-chan *flushPayload, responseFn func(*http
 			break
 		}
 		if payload.diagnosticEvent != nil {
-			t.postEvents(t.diagnosticURI, payload.diagnosticEvent, "diagnostic event")
+			diagnosticTask := *t
+			diagnosticTask.config.Loggers = t.diagnosticLoggers
+			diagnosticTask.postEvents(t.diagnosticURI, payload.diagnosticEvent, "diagnostic event")
 		} else {
 			outputEvents := t.formatter.makeOutputEvents(payload.events, payload.summary)
 			if len(outputEvents) > 0 {
-				resp := t.postEvents(t.eventsURI, outputEvents, fmt.Sprintf("%d events", len(outputEvents)))
-				if resp != nil {
-					responseFn(resp)
+				t.writeToSinks(outputEvents)
+				if !t.config.EventSinksOnly {
+					resp := t.postAnalyticsEvents(outputEvents, responseFn)
+					if resp != nil {
+						responseFn(resp)
+					}
 				}
 			}
 		}
@@ -501,17 +640,102 @@ func (t *sendEventsTask) run(flushCh <-chan *flushPayload, responseFn func(*http
 	}
 }
 
+func (t *sendEventsTask) writeToSinks(outputEvents []interface{}) {
+	if len(t.config.EventSinks) == 0 {
+		return
+	}
+	serializedEvents, err := marshalOutputEvents(outputEvents)
+	if err != nil {
+		t.config.Loggers.Errorf("Unexpected error marshalling event json: %+v", err)
+		return
+	}
+	for _, sink := range t.config.EventSinks {
+		if err := sink.WriteEvents(serializedEvents); err != nil {
+			t.config.Loggers.Warnw("Unexpected error while writing events to event sink", ldlog.Err(err))
+		}
+	}
+}
+
 func (t *sendEventsTask) postEvents(uri string, outputData interface{}, description string) *http.Response {
+	jsonPayload, payloadID, ok := t.marshalPayload(outputData, description)
+	if !ok {
+		return nil
+	}
+	return t.sendPayload(uri, jsonPayload, payloadID)
+}
+
+// Sends a batch of analytics events. If there is an event spool, any previously stored payloads are
+// delivered first, so that payloads arrive in the order they were created; if they cannot all be
+// delivered, this payload is added to the spool behind them instead of being sent. The payload is also
+// stored in the spool if it could not be delivered.
+func (t *sendEventsTask) postAnalyticsEvents(outputEvents []interface{}, responseFn func(*http.Response)) *http.Response {
+	jsonPayload, payloadID, ok := t.marshalPayload(outputEvents, fmt.Sprintf("%d events", len(outputEvents)))
+	if !ok {
+		return nil
+	}
+	if t.spool != nil && !t.replaySpool(responseFn) {
+		t.spool.add(jsonPayload, payloadID)
+		return nil
+	}
+	startTime := time.Now()
+	resp := t.sendPayload(t.eventsURI, jsonPayload, payloadID)
+	result := EventPayloadResult{
+		EventCount: len(outputEvents),
+		Latency:    time.Since(startTime),
+		Success:    resp != nil && resp.StatusCode < 300,
+	}
+	if resp != nil {
+		result.StatusCode = resp.StatusCode
+	}
+	t.config.Loggers.Debugf("Delivery of %d events took %s", result.EventCount, result.Latency)
+	if t.config.EventPayloadResultHandler != nil {
+		t.config.EventPayloadResultHandler(result)
+	}
+	if t.spool != nil && isDeliveryFailureRetryable(resp) {
+		t.spool.add(jsonPayload, payloadID)
+	}
+	return resp
+}
+
+// Attempts to deliver the payloads in the event spool, oldest first, and returns true if the spool is now
+// empty. Responses are passed to responseFn just as they are for new payloads, so that for instance a 401
+// error disables the event processor. A payload that the server rejects as invalid is discarded, since
+// sending it again would not help.
+func (t *sendEventsTask) replaySpool(responseFn func(*http.Response)) bool {
+	return t.spool.replay(func(payload []byte, payloadID string) bool {
+		t.config.Loggers.Debugf("Sending spooled event payload %s", payloadID)
+		resp := t.sendPayload(t.eventsURI, payload, payloadID)
+		if resp == nil {
+			return false
+		}
+		responseFn(resp)
+		switch {
+		case resp.StatusCode < 300:
+			return true
+		case isDeliveryFailureRetryable(resp), !isHTTPErrorRecoverable(resp.StatusCode):
+			return false
+		default:
+			t.config.Loggers.Warnw("Discarding spooled event payload that was rejected", ldlog.StatusCode(resp.StatusCode))
+			return true
+		}
+	})
+}
+
+func (t *sendEventsTask) marshalPayload(outputData interface{}, description string) ([]byte, string, bool) {
 	jsonPayload, marshalErr := json.Marshal(outputData)
 	if marshalErr != nil {
 		t.config.Loggers.Errorf("Unexpected error marshalling event json: %+v", marshalErr)
-		return nil
+		return nil, "", false
 	}
 	payloadUUID, _ := uuid.NewRandom()
 	payloadID := payloadUUID.String() // if NewRandom somehow failed, we'll just proceed with an empty string
 
 	t.config.Loggers.Debugf("Sending %s: %s", description, jsonPayload)
+	return jsonPayload, payloadID, true
+}
 
+func (t *sendEventsTask) sendPayload(uri string, jsonPayload []byte, payloadID string) *http.Response {
+	body, compressed := t.compressPayload(jsonPayload)
 	var resp *http.Response
 	var respErr error
 	for attempt := 0; attempt < 2; attempt++ {
@@ -519,7 +743,7 @@ func (t *sendEventsTask) postEvents(uri string, outputData interface{}, descript
 			t.config.Loggers.Warn("Will retry posting events after 1 second")
 			time.Sleep(1 * time.Second)
 		}
-		req, reqErr := http.NewRequest("POST", uri, bytes.NewReader(jsonPayload))
+		req, reqErr := http.NewRequest("POST", uri, bytes.NewReader(body))
 		if reqErr != nil {
 			t.config.Loggers.Errorf("Unexpected error while creating event request: %+v", reqErr)
 			return nil
@@ -529,6 +753,9 @@ func (t *sendEventsTask) postEvents(uri string, outputData interface{}, descript
 		req.Header.Add("Content-Type", "application/json")
 		req.Header.Add(eventSchemaHeader, currentEventSchema)
 		req.Header.Add(payloadIDHeader, payloadID)
+		if compressed {
+			req.Header.Add("Content-Encoding", "gzip")
+		}
 
 		resp, respErr = t.client.Do(req)
 
@@ -538,10 +765,10 @@ func (t *sendEventsTask) postEvents(uri string, outputData interface{}, descript
 		}
 
 		if respErr != nil {
-			t.config.Loggers.Warnf("Unexpected error while sending events: %+v", respErr)
+			t.config.Loggers.Warnw("Unexpected error while sending events", ldlog.Err(respErr))
 			continue
 		} else if resp.StatusCode >= 400 && isHTTPErrorRecoverable(resp.StatusCode) {
-			t.config.Loggers.Warnf("Received error status %d when sending events", resp.StatusCode)
+			t.config.Loggers.Warnw("Received error status when sending events", ldlog.StatusCode(resp.StatusCode))
 			continue
 		} else {
 			break
@@ -549,3 +776,44 @@ func (t *sendEventsTask) postEvents(uri string, outputData interface{}, descript
 	}
 	return resp
 }
+
+// Returns the request body to use for a payload, and whether it has been gzip-compressed.
+func (t *sendEventsTask) compressPayload(jsonPayload []byte) ([]byte, bool) {
+	if !t.config.CompressEvents {
+		return jsonPayload, false
+	}
+	threshold := t.config.EventCompressionThreshold
+	if threshold <= 0 {
+		threshold = DefaultEventCompressionThreshold
+	}
+	if len(jsonPayload) < threshold {
+		return jsonPayload, false
+	}
+	var buf bytes.Buffer
+	zw := gzip.NewWriter(&buf)
+	_, err := zw.Write(jsonPayload)
+	if err == nil {
+		err = zw.Close()
+	}
+	if err != nil {
+		t.config.Loggers.Errorf("Unexpected error compressing event payload; will send it uncompressed: %+v", err)
+		return jsonPayload, false
+	}
+	return buf.Bytes(), true
+}
+
+// Tests whether a failed event delivery is worth trying again later: that is, whether it failed because of
+// a network error or a server-side condition, rather than because of something wrong with the payload or the
+// SDK key.
+func isDeliveryFailureRetryable(resp *http.Response) bool {
+	if resp == nil {
+		return true
+	}
+	switch {
+	case resp.StatusCode == 408, resp.StatusCode == 429:
+		return true
+	case resp.StatusCode >= 500:
+		return true
+	}
+	return false
+}
@@ -1,12 +1,15 @@
 package ldclient
 
 import (
+	"compress/gzip"
 	"encoding/json"
 	"fmt"
+	"io"
 	"io/ioutil"
 	"net/http"
 	"net/http/httptest"
 	"sort"
+	"sync"
 	"testing"
 	"time"
 
@@ -124,6 +127,25 @@ func TestIndividualFeatureEventIsQueuedWhenTrackEventsIsTrue(t *testing.T) {
 	}
 }
 
+func TestFeatureEventWithoutUserKeyDoesNotGenerateIndexEvent(t *testing.T) {
+	ep, st := createEventProcessor(epDefaultConfig)
+	defer ep.Close()
+
+	flag := FeatureFlag{
+		Key:     "flagkey",
+		Version: 11,
+	}
+	value := ldvalue.String("value")
+	fe := newSuccessfulEvalEvent(&flag, NewContext("org", "org-key").eventUser(), intPtr(2), value, ldvalue.Null(),
+		nil, false, nil)
+	ep.SendEvent(fe)
+
+	output := flushAndGetEvents(ep, st)
+	if assert.Equal(t, 1, len(output)) {
+		assertSummaryEventHasCounter(t, flag, intPtr(2), value, 1, output[0])
+	}
+}
+
 func TestUserDetailsAreScrubbedInIndexEvent(t *testing.T) {
 	config := epDefaultConfig
 	config.AllAttributesPrivate = true
@@ -470,6 +492,105 @@ func TestCustomEventCanContainInlineUser(t *testing.T) {
 	}
 }
 
+// An interval this large means that, for practical purposes, an event will never be sampled.
+const neverSampledInterval = int32(1 << 30)
+
+func TestCustomEventCanBeSampledOutByKey(t *testing.T) {
+	config := epDefaultConfig
+	config.EventSampling.CustomEvents = map[string]int32{"rare": neverSampledInterval}
+	ep, st := createEventProcessor(config)
+	defer ep.Close()
+
+	ce0 := NewCustomEvent("rare", epDefaultUser, nil)
+	ce1 := NewCustomEvent("common", epDefaultUser, nil)
+	ep.SendEvent(ce0)
+	ep.SendEvent(ce1)
+
+	output := flushAndGetEvents(ep, st)
+	if assert.Equal(t, 2, len(output)) {
+		assertIndexEventMatches(t, ce0, userJson, output[0])
+		assert.Equal(t, "custom", output[1]["kind"])
+		assert.Equal(t, "common", output[1]["key"])
+	}
+}
+
+func TestFeatureEventCanBeSampledOutByFlagKeyButIsStillSummarized(t *testing.T) {
+	config := epDefaultConfig
+	config.EventSampling.FeatureEvents = map[string]int32{"flagkey": neverSampledInterval}
+	ep, st := createEventProcessor(config)
+	defer ep.Close()
+
+	flag := FeatureFlag{
+		Key:         "flagkey",
+		Version:     11,
+		TrackEvents: true,
+	}
+	value := ldvalue.String("value")
+	fe := newSuccessfulEvalEvent(&flag, epDefaultUser, intPtr(2), value, ldvalue.Null(), nil, false, nil)
+	ep.SendEvent(fe)
+	ep.SendEvent(fe)
+
+	output := flushAndGetEvents(ep, st)
+	if assert.Equal(t, 2, len(output)) {
+		assertIndexEventMatches(t, fe, userJson, output[0])
+		assertSummaryEventHasCounter(t, flag, intPtr(2), value, 2, output[1])
+	}
+}
+
+func TestIdentifyEventCanBeSampledOut(t *testing.T) {
+	config := epDefaultConfig
+	config.EventSampling.IdentifyEvents = neverSampledInterval
+	ep, st := createEventProcessor(config)
+	defer ep.Close()
+
+	ep.SendEvent(NewIdentifyEvent(epDefaultUser))
+	ep.Flush()
+	ep.waitUntilInactive()
+
+	assert.Nil(t, st.getNextRequest())
+}
+
+func TestIndexEventCanBeSampledOut(t *testing.T) {
+	config := epDefaultConfig
+	config.EventSampling.IndexEvents = neverSampledInterval
+	ep, st := createEventProcessor(config)
+	defer ep.Close()
+
+	ce := NewCustomEvent("eventkey", epDefaultUser, nil)
+	ep.SendEvent(ce)
+
+	output := flushAndGetEvents(ep, st)
+	if assert.Equal(t, 1, len(output)) {
+		assert.Equal(t, "custom", output[0]["kind"])
+	}
+}
+
+func TestSampledOutIndexEventDoesNotMarkUserAsSeen(t *testing.T) {
+	config := epDefaultConfig
+	config.EventSampling.IndexEvents = neverSampledInterval
+	ed := &eventDispatcher{config: config}
+	outbox := eventBuffer{summarizer: newEventSummarizer(), capacity: config.Capacity, loggers: config.Loggers}
+	userKeys := newLruCache(config.UserKeysCapacity)
+
+	ed.processEvent(NewCustomEvent("eventkey", epDefaultUser, nil), &outbox, &userKeys)
+
+	assert.False(t, userKeys.add(*epDefaultUser.Key)) // the user was not already in the cache
+	assert.Equal(t, 0, ed.deduplicatedUsers)
+}
+
+func TestSamplingIntervalOfOneSendsAllEvents(t *testing.T) {
+	config := epDefaultConfig
+	config.EventSampling.IdentifyEvents = 1
+	ep, st := createEventProcessor(config)
+	defer ep.Close()
+
+	ep.SendEvent(NewIdentifyEvent(epDefaultUser))
+	ep.SendEvent(NewIdentifyEvent(epDefaultUser))
+
+	output := flushAndGetEvents(ep, st)
+	assert.Equal(t, 2, len(output))
+}
+
 func TestClosingEventProcessorForcesSynchronousFlush(t *testing.T) {
 	ep, st := createEventProcessor(epDefaultConfig)
 	defer ep.Close()
@@ -670,6 +791,172 @@ func TestEventPostingUsesHTTPClientFactory(t *testing.T) {
 	assert.Equal(t, "/bulk/transformed", postedURL)
 }
 
+type capturedEventPost struct {
+	contentEncoding string
+	body            []byte
+}
+
+func startEventCaptureServer(t *testing.T) (*httptest.Server, <-chan capturedEventPost) {
+	posts := make(chan capturedEventPost, 10)
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		post := capturedEventPost{contentEncoding: r.Header.Get("Content-Encoding")}
+		var reader io.Reader = r.Body
+		if post.contentEncoding == "gzip" {
+			zr, err := gzip.NewReader(r.Body)
+			if !assert.NoError(t, err) {
+				w.WriteHeader(400)
+				return
+			}
+			reader = zr
+		}
+		post.body, _ = ioutil.ReadAll(reader)
+		posts <- post
+		w.WriteHeader(202)
+	}))
+	return ts, posts
+}
+
+func sendIdentifyEventsToServer(ts *httptest.Server, config Config, count int) {
+	config.EventsUri = ts.URL
+	config.Loggers = shared.NullLoggers()
+	ep := NewDefaultEventProcessor(sdkKey, config, nil)
+	defer ep.Close()
+	for i := 0; i < count; i++ {
+		ep.SendEvent(NewIdentifyEvent(NewUser(fmt.Sprintf("user%d", i))))
+	}
+}
+
+func TestEventPayloadIsCompressedIfAboveThreshold(t *testing.T) {
+	ts, posts := startEventCaptureServer(t)
+	defer ts.Close()
+
+	config := epDefaultConfig
+	config.CompressEvents = true
+	config.EventCompressionThreshold = 100
+	sendIdentifyEventsToServer(ts, config, 10)
+
+	post := <-posts
+	assert.Equal(t, "gzip", post.contentEncoding)
+	var output []map[string]interface{}
+	if assert.NoError(t, json.Unmarshal(post.body, &output)) && assert.Equal(t, 10, len(output)) {
+		assert.Equal(t, "identify", output[0]["kind"])
+		assert.Equal(t, "user9", output[9]["key"])
+	}
+}
+
+func TestEventPayloadIsNotCompressedIfBelowThreshold(t *testing.T) {
+	ts, posts := startEventCaptureServer(t)
+	defer ts.Close()
+
+	config := epDefaultConfig
+	config.CompressEvents = true
+	sendIdentifyEventsToServer(ts, config, 1)
+
+	post := <-posts
+	assert.Equal(t, "", post.contentEncoding)
+	var output []map[string]interface{}
+	if assert.NoError(t, json.Unmarshal(post.body, &output)) {
+		assert.Equal(t, 1, len(output))
+	}
+}
+
+func TestEventPayloadIsNotCompressedByDefault(t *testing.T) {
+	ts, posts := startEventCaptureServer(t)
+	defer ts.Close()
+
+	config := epDefaultConfig
+	config.EventCompressionThreshold = 1
+	sendIdentifyEventsToServer(ts, config, 10)
+
+	post := <-posts
+	assert.Equal(t, "", post.contentEncoding)
+	var output []map[string]interface{}
+	if assert.NoError(t, json.Unmarshal(post.body, &output)) {
+		assert.Equal(t, 10, len(output))
+	}
+}
+
+type droppedEventsRecorder struct {
+	counts map[EventsDroppedReason]int
+	lock   sync.Mutex
+}
+
+func (r *droppedEventsRecorder) handle(reason EventsDroppedReason, count int) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	r.counts[reason] += count
+}
+
+func TestEventsDroppedHandlerIsCalledWhenInboxIsFull(t *testing.T) {
+	recorder := &droppedEventsRecorder{counts: make(map[EventsDroppedReason]int)}
+	// This processor has no dispatcher reading from the inbox, so the inbox will stay full.
+	ep := &defaultEventProcessor{
+		inboxCh:   make(chan eventDispatcherMessage, 1),
+		droppedFn: recorder.handle,
+		loggers:   shared.NullLoggers(),
+	}
+
+	ep.SendEvent(NewIdentifyEvent(epDefaultUser))
+	ep.SendEvent(NewIdentifyEvent(epDefaultUser))
+	ep.SendEvent(NewIdentifyEvent(epDefaultUser))
+
+	assert.Equal(t, map[EventsDroppedReason]int{EventsDroppedInboxFull: 2}, recorder.counts)
+}
+
+func TestEventsDroppedHandlerIsCalledWhenBufferIsFull(t *testing.T) {
+	recorder := &droppedEventsRecorder{counts: make(map[EventsDroppedReason]int)}
+	config := epDefaultConfig
+	config.Capacity = 1
+	config.EventInboxCapacity = 10
+	config.EventsDroppedHandler = recorder.handle
+	ep, _ := createEventProcessor(config)
+	defer ep.Close()
+
+	ep.SendEvent(NewIdentifyEvent(epDefaultUser))
+	ep.SendEvent(NewIdentifyEvent(epDefaultUser))
+	ep.waitUntilInactive()
+
+	recorder.lock.Lock()
+	defer recorder.lock.Unlock()
+	assert.Equal(t, map[EventsDroppedReason]int{EventsDroppedBufferFull: 1}, recorder.counts)
+}
+
+func TestEventPayloadResultHandlerReceivesDeliveryResult(t *testing.T) {
+	results := make(chan EventPayloadResult, 10)
+	config := epDefaultConfig
+	config.EventPayloadResultHandler = func(r EventPayloadResult) { results <- r }
+	config.EventFlushWorkers = 1
+	ep, _ := createEventProcessor(config)
+	defer ep.Close()
+
+	ep.SendEvent(NewIdentifyEvent(epDefaultUser))
+	ep.Flush()
+	ep.waitUntilInactive()
+
+	r := <-results
+	assert.Equal(t, 1, r.EventCount)
+	assert.Equal(t, 200, r.StatusCode)
+	assert.True(t, r.Success)
+	assert.True(t, r.Latency >= 0)
+}
+
+func TestEventPayloadResultHandlerReportsFailure(t *testing.T) {
+	results := make(chan EventPayloadResult, 10)
+	config := epDefaultConfig
+	config.EventPayloadResultHandler = func(r EventPayloadResult) { results <- r }
+	ep, st := createEventProcessor(config)
+	defer ep.Close()
+
+	st.statusCode = 401
+	ep.SendEvent(NewIdentifyEvent(epDefaultUser))
+	ep.Flush()
+	ep.waitUntilInactive()
+
+	r := <-results
+	assert.Equal(t, 401, r.StatusCode)
+	assert.False(t, r.Success)
+}
+
 func TestPanicInSerializationOfOneUserDoesNotDropEvents(t *testing.T) {
 	user1 := NewUserBuilder("user1").Name("Bandit").Build()
 	user2 := NewUserBuilder("user2").Name("Tinker").Build()
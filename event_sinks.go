@@ -0,0 +1,138 @@
+package ldclient
+
+import (
+	"encoding/json"
+	"io"
+	"os"
+	"sync"
+)
+
+// EventSink is an additional destination for analytics event data.
+//
+// The default event processor delivers each flushed batch of events to every sink in Config.EventSinks,
+// using the same output format that is sent to LaunchDarkly (summary events and index events included).
+// This allows an application to feed the events into its own storage, either in addition to or instead
+// of LaunchDarkly; see Config.EventSinksOnly.
+//
+// WriteEvents may be called concurrently from several goroutines, so implementations must be thread-safe.
+// If a sink also implements io.Closer, its Close method is called when the event processor is closed.
+type EventSink interface {
+	// WriteEvents receives one batch of events, each of which is a serialized JSON object. An error
+	// returned here is logged, but does not affect delivery to LaunchDarkly or to other sinks.
+	WriteEvents(events []json.RawMessage) error
+}
+
+// EventMessage is a single analytics event as passed to an EventMessageWriter.
+type EventMessage struct {
+	// Key is the key of the user the event refers to, if any. Message queues such as Kafka can use
+	// this as the partitioning key, so that all events for a user are kept in order.
+	Key []byte
+	// Value is the serialized JSON representation of the event.
+	Value []byte
+}
+
+// EventMessageWriter is an interface for message queue producers, such as a Kafka writer, that can be
+// used as an event destination with NewMessageWriterEventSink.
+type EventMessageWriter interface {
+	// WriteMessages publishes a batch of messages.
+	WriteMessages(messages ...EventMessage) error
+}
+
+type jsonLinesEventSink struct {
+	writer io.Writer
+	closer io.Closer
+	lock   sync.Mutex
+}
+
+type messageWriterEventSink struct {
+	writer EventMessageWriter
+}
+
+// NewJSONLinesEventSink creates an EventSink that writes each event as a single line of JSON to the
+// specified writer. For instance, to write events to standard output:
+//
+//     config := ld.DefaultConfig
+//     config.EventSinks = []ld.EventSink{ld.NewJSONLinesEventSink(os.Stdout)}
+//
+// The writer is not closed when the event processor shuts down.
+func NewJSONLinesEventSink(writer io.Writer) EventSink {
+	return &jsonLinesEventSink{writer: writer}
+}
+
+// NewFileEventSink creates an EventSink that appends events to a file in JSON-lines format, creating
+// the file if it does not already exist. The file is closed when the event processor shuts down.
+func NewFileEventSink(path string) (EventSink, error) {
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0644)
+	if err != nil {
+		return nil, err
+	}
+	return &jsonLinesEventSink{writer: f, closer: f}, nil
+}
+
+// NewMessageWriterEventSink creates an EventSink that publishes each event as a separate message to
+// a message queue producer, using the event's user key (if any) as the message key.
+func NewMessageWriterEventSink(writer EventMessageWriter) EventSink {
+	return messageWriterEventSink{writer: writer}
+}
+
+func (s *jsonLinesEventSink) WriteEvents(events []json.RawMessage) error {
+	buf := make([]byte, 0, 1024)
+	for _, e := range events {
+		buf = append(buf, e...)
+		buf = append(buf, '\n')
+	}
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	_, err := s.writer.Write(buf)
+	return err
+}
+
+func (s *jsonLinesEventSink) Close() error {
+	if s.closer == nil {
+		return nil
+	}
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	return s.closer.Close()
+}
+
+func (s messageWriterEventSink) WriteEvents(events []json.RawMessage) error {
+	messages := make([]EventMessage, 0, len(events))
+	for _, e := range events {
+		messages = append(messages, EventMessage{Key: getOutputEventUserKey(e), Value: e})
+	}
+	return s.writer.WriteMessages(messages...)
+}
+
+// Finds the user key in a serialized output event, which may be either in "userKey" or in "user".
+func getOutputEventUserKey(event json.RawMessage) []byte {
+	var fields struct {
+		UserKey *string `json:"userKey"`
+		User    *struct {
+			Key *string `json:"key"`
+		} `json:"user"`
+	}
+	if err := json.Unmarshal(event, &fields); err != nil {
+		return nil
+	}
+	if fields.UserKey != nil {
+		return []byte(*fields.UserKey)
+	}
+	if fields.User != nil && fields.User.Key != nil {
+		return []byte(*fields.User.Key)
+	}
+	return nil
+}
+
+// Serializes each output event separately, for delivery to event sinks.
+func marshalOutputEvents(outputEvents []interface{}) ([]json.RawMessage, error) {
+	ret := make([]json.RawMessage, 0, len(outputEvents))
+	for _, e := range outputEvents {
+		data, err := json.Marshal(e)
+		if err != nil {
+			return nil, err
+		}
+		ret = append(ret, data)
+	}
+	return ret, nil
+}
@@ -0,0 +1,166 @@
+package ldclient
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"os"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type testEventSink struct {
+	batches [][]json.RawMessage
+	err     error
+	closed  bool
+	lock    sync.Mutex
+}
+
+func (s *testEventSink) WriteEvents(events []json.RawMessage) error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	s.batches = append(s.batches, events)
+	return s.err
+}
+
+func (s *testEventSink) Close() error {
+	s.closed = true
+	return nil
+}
+
+func (s *testEventSink) getEvents() []map[string]interface{} {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	var ret []map[string]interface{}
+	for _, b := range s.batches {
+		for _, e := range b {
+			var m map[string]interface{}
+			_ = json.Unmarshal(e, &m)
+			ret = append(ret, m)
+		}
+	}
+	return ret
+}
+
+type testMessageWriter struct {
+	messages []EventMessage
+}
+
+func (w *testMessageWriter) WriteMessages(messages ...EventMessage) error {
+	w.messages = append(w.messages, messages...)
+	return nil
+}
+
+func TestEventsAreWrittenToSinksAndLaunchDarkly(t *testing.T) {
+	sink := &testEventSink{}
+	config := epDefaultConfig
+	config.EventSinks = []EventSink{sink}
+	ep, st := createEventProcessor(config)
+	defer ep.Close()
+
+	ie := NewIdentifyEvent(epDefaultUser)
+	ep.SendEvent(ie)
+
+	output := flushAndGetEvents(ep, st)
+	if assert.Equal(t, 1, len(output)) {
+		assertIdentifyEventMatches(t, ie, userJson, output[0])
+	}
+	sinkOutput := sink.getEvents()
+	if assert.Equal(t, 1, len(sinkOutput)) {
+		assertIdentifyEventMatches(t, ie, userJson, sinkOutput[0])
+	}
+}
+
+func TestEventsAreNotSentToLaunchDarklyIfEventSinksOnly(t *testing.T) {
+	sink := &testEventSink{}
+	config := epDefaultConfig
+	config.EventSinks = []EventSink{sink}
+	config.EventSinksOnly = true
+	ep, st := createEventProcessor(config)
+	defer ep.Close()
+
+	ie := NewIdentifyEvent(epDefaultUser)
+	ep.SendEvent(ie)
+	ep.Flush()
+	ep.waitUntilInactive()
+
+	assert.Nil(t, st.getNextRequest())
+	assert.Equal(t, 1, len(sink.getEvents()))
+}
+
+func TestSinkErrorDoesNotPreventDelivery(t *testing.T) {
+	badSink := &testEventSink{err: errors.New("sad")}
+	goodSink := &testEventSink{}
+	config := epDefaultConfig
+	config.EventSinks = []EventSink{badSink, goodSink}
+	ep, st := createEventProcessor(config)
+	defer ep.Close()
+
+	ep.SendEvent(NewIdentifyEvent(epDefaultUser))
+
+	output := flushAndGetEvents(ep, st)
+	assert.Equal(t, 1, len(output))
+	assert.Equal(t, 1, len(goodSink.getEvents()))
+}
+
+func TestSinksAreClosedWithEventProcessor(t *testing.T) {
+	sink := &testEventSink{}
+	config := epDefaultConfig
+	config.EventSinks = []EventSink{sink}
+	ep, _ := createEventProcessor(config)
+
+	ep.Close()
+
+	assert.True(t, sink.closed)
+}
+
+func TestJSONLinesEventSinkWritesOneEventPerLine(t *testing.T) {
+	var buf bytes.Buffer
+	sink := NewJSONLinesEventSink(&buf)
+
+	err := sink.WriteEvents([]json.RawMessage{json.RawMessage(`{"kind":"a"}`), json.RawMessage(`{"kind":"b"}`)})
+	require.NoError(t, err)
+
+	assert.Equal(t, "{\"kind\":\"a\"}\n{\"kind\":\"b\"}\n", buf.String())
+}
+
+func TestFileEventSinkAppendsToFile(t *testing.T) {
+	f, err := ioutil.TempFile("", "events")
+	require.NoError(t, err)
+	_ = f.Close()
+	defer os.Remove(f.Name())
+
+	sink, err := NewFileEventSink(f.Name())
+	require.NoError(t, err)
+	require.NoError(t, sink.WriteEvents([]json.RawMessage{json.RawMessage(`{"kind":"a"}`)}))
+	require.NoError(t, sink.WriteEvents([]json.RawMessage{json.RawMessage(`{"kind":"b"}`)}))
+	require.NoError(t, sink.(*jsonLinesEventSink).Close())
+
+	data, err := ioutil.ReadFile(f.Name())
+	require.NoError(t, err)
+	assert.Equal(t, []string{`{"kind":"a"}`, `{"kind":"b"}`, ""}, strings.Split(string(data), "\n"))
+}
+
+func TestMessageWriterEventSinkUsesUserKeyAsMessageKey(t *testing.T) {
+	writer := &testMessageWriter{}
+	sink := NewMessageWriterEventSink(writer)
+
+	events := []json.RawMessage{
+		json.RawMessage(`{"kind":"feature","userKey":"a"}`),
+		json.RawMessage(`{"kind":"index","user":{"key":"b"}}`),
+		json.RawMessage(`{"kind":"summary"}`),
+	}
+	require.NoError(t, sink.WriteEvents(events))
+
+	if assert.Equal(t, 3, len(writer.messages)) {
+		assert.Equal(t, []byte("a"), writer.messages[0].Key)
+		assert.Equal(t, []byte("b"), writer.messages[1].Key)
+		assert.Nil(t, writer.messages[2].Key)
+		assert.Equal(t, []byte(events[0]), writer.messages[0].Value)
+	}
+}
@@ -0,0 +1,210 @@
+package ldclient
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"gopkg.in/launchdarkly/go-server-sdk.v4/ldlog"
+)
+
+// Default limits for the event spool, used if Config.EventSpoolDir is set but the corresponding
+// limit properties are not.
+const (
+	DefaultEventSpoolMaxBytes = 10 * 1024 * 1024
+	DefaultEventSpoolMaxAge   = 24 * time.Hour
+)
+
+const (
+	eventSpoolFileSuffix = ".json"
+	eventSpoolTempSuffix = ".tmp"
+)
+
+// eventSpool is a directory of analytics event payloads that could not be delivered. Each payload is
+// kept in its own file, whose name begins with a zero-padded timestamp so that a directory listing
+// returns the payloads in the order they were spooled. Because the payloads are on disk, anything that
+// is still in the spool when the process exits will be delivered by the next process that uses the
+// same directory.
+type eventSpool struct {
+	dir                string
+	maxBytes           int64
+	maxAge             time.Duration
+	diagnosticsManager *diagnosticsManager
+	loggers            ldlog.Loggers
+	lock               sync.Mutex
+	replayLock         sync.Mutex
+}
+
+type spooledPayload struct {
+	path      string
+	payloadID string
+	createdAt time.Time
+	size      int64
+}
+
+func newEventSpool(config Config) (*eventSpool, error) {
+	if err := os.MkdirAll(config.EventSpoolDir, 0700); err != nil {
+		return nil, err
+	}
+	s := &eventSpool{
+		dir:                config.EventSpoolDir,
+		maxBytes:           config.EventSpoolMaxBytes,
+		maxAge:             config.EventSpoolMaxAge,
+		diagnosticsManager: config.diagnosticsManager,
+		loggers:            config.Loggers,
+	}
+	if s.maxBytes <= 0 {
+		s.maxBytes = DefaultEventSpoolMaxBytes
+	}
+	if s.maxAge <= 0 {
+		s.maxAge = DefaultEventSpoolMaxAge
+	}
+	return s, nil
+}
+
+// add stores an undelivered payload, discarding the oldest payloads if necessary to stay within the
+// size limit.
+func (s *eventSpool) add(payload []byte, payloadID string) {
+	if int64(len(payload)) > s.maxBytes {
+		s.loggers.Warnf("Event payload of %d bytes is larger than the event spool limit; it will be dropped", len(payload))
+		return
+	}
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	items := s.listAndExpire()
+	total := int64(len(payload))
+	for _, item := range items {
+		total += item.size
+	}
+	expired := 0
+	for len(items) > 0 && total > s.maxBytes {
+		if err := os.Remove(items[0].path); err == nil || os.IsNotExist(err) {
+			expired++
+		}
+		total -= items[0].size
+		items = items[1:]
+	}
+	if expired > 0 {
+		s.loggers.Warnf("Event spool is full; discarded %d oldest payloads", expired)
+		s.recordActivity(0, expired)
+	}
+
+	name := fmt.Sprintf("%020d-%s", time.Now().UnixNano(), payloadID)
+	tempPath := filepath.Join(s.dir, name+eventSpoolTempSuffix)
+	if err := ioutil.WriteFile(tempPath, payload, 0600); err != nil {
+		s.loggers.Errorf("Unable to write event payload to spool directory: %s", err)
+		return
+	}
+	if err := os.Rename(tempPath, filepath.Join(s.dir, name+eventSpoolFileSuffix)); err != nil {
+		s.loggers.Errorf("Unable to write event payload to spool directory: %s", err)
+		_ = os.Remove(tempPath)
+		return
+	}
+	s.loggers.Infof("Spooled undelivered event payload %s for later delivery", payloadID)
+	s.recordActivity(1, 0)
+}
+
+// replay attempts to deliver all spooled payloads, oldest first, using the specified function, which
+// returns true if the payload can be removed from the spool. It stops at the first payload that cannot be
+// removed. It returns true if the spool is now empty, or false if some payloads remain.
+//
+// Only one goroutine replays the spool at a time; any others wait for it to finish, and then replay
+// whatever is left. Payloads that are added during a replay are delivered by that replay too, so when
+// replay returns true, no older payload is still waiting behind the caller's new one.
+func (s *eventSpool) replay(sendFn func(payload []byte, payloadID string) bool) bool {
+	s.replayLock.Lock()
+	defer s.replayLock.Unlock()
+
+	skipped := make(map[string]bool)
+	for {
+		s.lock.Lock()
+		items := s.listAndExpire()
+		s.lock.Unlock()
+
+		sentAny := false
+		for _, item := range items {
+			if skipped[item.path] {
+				continue
+			}
+			payload, err := ioutil.ReadFile(item.path)
+			if err != nil {
+				if !os.IsNotExist(err) { // it might have been removed by add() to make room
+					s.loggers.Errorf("Unable to read spooled event payload: %s", err)
+					skipped[item.path] = true
+				}
+				continue
+			}
+			if !sendFn(payload, item.payloadID) {
+				return false
+			}
+			if err := os.Remove(item.path); err != nil && !os.IsNotExist(err) {
+				s.loggers.Errorf("Unable to remove delivered event payload from spool directory: %s", err)
+				return false // don't risk delivering the same payload over and over
+			}
+			sentAny = true
+		}
+		if !sentAny {
+			return true
+		}
+	}
+}
+
+// Returns the current spool contents in order, after removing any payloads that are past the age limit.
+// The caller must hold the lock.
+func (s *eventSpool) listAndExpire() []spooledPayload {
+	files, err := ioutil.ReadDir(s.dir)
+	if err != nil {
+		s.loggers.Errorf("Unable to read event spool directory: %s", err)
+		return nil
+	}
+	cutoff := time.Now().Add(-s.maxAge)
+	items := make([]spooledPayload, 0, len(files))
+	expired := 0
+	for _, f := range files {
+		item, ok := parseSpooledPayloadName(f.Name())
+		if !ok {
+			continue
+		}
+		item.path = filepath.Join(s.dir, f.Name())
+		item.size = f.Size()
+		if item.createdAt.Before(cutoff) {
+			if err := os.Remove(item.path); err == nil {
+				expired++
+			}
+			continue
+		}
+		items = append(items, item)
+	}
+	if expired > 0 {
+		s.loggers.Warnf("Discarded %d spooled event payloads that were too old to deliver", expired)
+		s.recordActivity(0, expired)
+	}
+	return items
+}
+
+func (s *eventSpool) recordActivity(spooled, expired int) {
+	if s.diagnosticsManager != nil {
+		s.diagnosticsManager.RecordEventSpoolActivity(spooled, expired)
+	}
+}
+
+func parseSpooledPayloadName(name string) (spooledPayload, bool) {
+	if !strings.HasSuffix(name, eventSpoolFileSuffix) {
+		return spooledPayload{}, false
+	}
+	parts := strings.SplitN(strings.TrimSuffix(name, eventSpoolFileSuffix), "-", 2)
+	if len(parts) != 2 {
+		return spooledPayload{}, false
+	}
+	nanos, err := strconv.ParseInt(parts[0], 10, 64)
+	if err != nil {
+		return spooledPayload{}, false
+	}
+	return spooledPayload{payloadID: parts[1], createdAt: time.Unix(0, nanos)}, true
+}
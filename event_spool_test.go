@@ -0,0 +1,277 @@
+package ldclient
+
+import (
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	shared "gopkg.in/launchdarkly/go-server-sdk.v4/shared_test"
+)
+
+func withEventSpoolDir(t *testing.T, action func(dir string)) {
+	dir, err := ioutil.TempDir("", "event-spool")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	action(dir)
+}
+
+func listSpoolFiles(t *testing.T, dir string) []string {
+	files, err := ioutil.ReadDir(dir)
+	require.NoError(t, err)
+	var names []string
+	for _, f := range files {
+		names = append(names, f.Name())
+	}
+	return names
+}
+
+func makeTestEventSpool(t *testing.T, dir string, maxBytes int64, maxAge time.Duration) *eventSpool {
+	config := Config{
+		EventSpoolDir:      dir,
+		EventSpoolMaxBytes: maxBytes,
+		EventSpoolMaxAge:   maxAge,
+		Loggers:            shared.NullLoggers(),
+		diagnosticsManager: newDiagnosticsManager(newDiagnosticId(sdkKey), Config{}, 0, time.Now(), nil),
+	}
+	s, err := newEventSpool(config)
+	require.NoError(t, err)
+	return s
+}
+
+func TestUndeliverablePayloadIsSpooledAndReplayedAfterRecovery(t *testing.T) {
+	withEventSpoolDir(t, func(dir string) {
+		config := epDefaultConfig
+		config.EventSpoolDir = dir
+		ep, st := createEventProcessor(config)
+		defer ep.Close()
+
+		st.statusCode = 503
+		ep.SendEvent(NewIdentifyEvent(epDefaultUser))
+		ep.Flush()
+		ep.waitUntilInactive()
+
+		failed := st.getNextRequest()
+		require.NotNil(t, failed)
+		assert.NotNil(t, st.getNextRequest()) // retry
+		assert.Equal(t, 1, len(listSpoolFiles(t, dir)))
+
+		st.statusCode = 200
+		ep.SendEvent(NewIdentifyEvent(epDefaultUser))
+		ep.Flush()
+		ep.waitUntilInactive()
+
+		replayed := st.getNextRequest()
+		current := st.getNextRequest()
+		require.NotNil(t, replayed)
+		require.NotNil(t, current)
+		assert.NotEqual(t, failed.Header.Get(payloadIDHeader), current.Header.Get(payloadIDHeader))
+		assert.Equal(t, failed.Header.Get(payloadIDHeader), replayed.Header.Get(payloadIDHeader))
+		assert.Equal(t, 0, len(listSpoolFiles(t, dir)))
+	})
+}
+
+func TestPayloadIsNotSpooledAfterUnrecoverableError(t *testing.T) {
+	withEventSpoolDir(t, func(dir string) {
+		config := epDefaultConfig
+		config.EventSpoolDir = dir
+		ep, st := createEventProcessor(config)
+		defer ep.Close()
+
+		st.statusCode = 401
+		ep.SendEvent(NewIdentifyEvent(epDefaultUser))
+		ep.Flush()
+		ep.waitUntilInactive()
+
+		assert.Equal(t, 0, len(listSpoolFiles(t, dir)))
+	})
+}
+
+func TestSpooledPayloadsFromPreviousRunAreReplayedInOrder(t *testing.T) {
+	withEventSpoolDir(t, func(dir string) {
+		s := makeTestEventSpool(t, dir, 0, 0)
+		s.add([]byte(`[{"kind":"custom","key":"a"}]`), "id-a")
+		s.add([]byte(`[{"kind":"custom","key":"b"}]`), "id-b")
+
+		config := epDefaultConfig
+		config.EventSpoolDir = dir
+		ep, st := createEventProcessor(config)
+		defer ep.Close()
+		ep.waitUntilInactive() // spooled payloads are delivered at startup, without waiting for new events
+
+		assert.Equal(t, "id-a", st.getNextRequest().Header.Get(payloadIDHeader))
+		assert.Equal(t, "id-b", st.getNextRequest().Header.Get(payloadIDHeader))
+		assert.Equal(t, 0, len(listSpoolFiles(t, dir)))
+	})
+}
+
+func TestReplayStopsAtFirstFailure(t *testing.T) {
+	withEventSpoolDir(t, func(dir string) {
+		s := makeTestEventSpool(t, dir, 0, 0)
+		s.add([]byte("[]"), "id-a")
+		s.add([]byte("[]"), "id-b")
+
+		var sent []string
+		s.replay(func(payload []byte, payloadID string) bool {
+			sent = append(sent, payloadID)
+			return false
+		})
+
+		assert.Equal(t, []string{"id-a"}, sent)
+		assert.Equal(t, 2, len(listSpoolFiles(t, dir)))
+	})
+}
+
+func TestConcurrentReplayWaitsAndPayloadsAddedDuringReplayAreDelivered(t *testing.T) {
+	withEventSpoolDir(t, func(dir string) {
+		s := makeTestEventSpool(t, dir, 0, 0)
+		s.add([]byte("[]"), "id-a")
+
+		sentCh := make(chan string, 10)
+		releaseCh := make(chan struct{})
+		firstResultCh := make(chan bool, 1)
+		go func() {
+			firstResultCh <- s.replay(func(payload []byte, payloadID string) bool {
+				sentCh <- payloadID
+				<-releaseCh
+				return true
+			})
+		}()
+		require.Equal(t, "id-a", <-sentCh)
+
+		s.add([]byte("[]"), "id-b")
+		secondResultCh := make(chan bool, 1)
+		go func() {
+			secondResultCh <- s.replay(func(payload []byte, payloadID string) bool {
+				sentCh <- "second:" + payloadID
+				return true
+			})
+		}()
+		select {
+		case <-secondResultCh:
+			assert.Fail(t, "second replay should wait for the first one")
+		case <-time.After(100 * time.Millisecond):
+		}
+
+		close(releaseCh)
+		assert.True(t, <-firstResultCh)
+		assert.True(t, <-secondResultCh)
+		assert.Equal(t, "id-b", <-sentCh)
+		assert.Equal(t, 0, len(sentCh))
+		assert.Equal(t, 0, len(listSpoolFiles(t, dir)))
+	})
+}
+
+func TestOldestPayloadsAreDiscardedWhenSpoolIsFull(t *testing.T) {
+	withEventSpoolDir(t, func(dir string) {
+		s := makeTestEventSpool(t, dir, 10, 0)
+		s.add([]byte("aaaa"), "id-a")
+		s.add([]byte("bbbb"), "id-b")
+		s.add([]byte("cccc"), "id-c")
+
+		var sent []string
+		s.replay(func(payload []byte, payloadID string) bool {
+			sent = append(sent, string(payload))
+			return true
+		})
+
+		assert.Equal(t, []string{"bbbb", "cccc"}, sent)
+		event := s.diagnosticsManager.CreateStatsEventAndReset(0, 0, 0)
+		assert.Equal(t, 3, event.SpooledPayloads)
+		assert.Equal(t, 1, event.ExpiredPayloads)
+	})
+}
+
+func TestPayloadsPastMaxAgeAreDiscarded(t *testing.T) {
+	withEventSpoolDir(t, func(dir string) {
+		s := makeTestEventSpool(t, dir, 0, time.Hour)
+		s.add([]byte("new"), "id-new")
+		oldName := "00000000000000000001-id-old" + eventSpoolFileSuffix
+		require.NoError(t, ioutil.WriteFile(filepath.Join(dir, oldName), []byte("old"), 0600))
+
+		var sent []string
+		s.replay(func(payload []byte, payloadID string) bool {
+			sent = append(sent, payloadID)
+			return true
+		})
+
+		assert.Equal(t, []string{"id-new"}, sent)
+		event := s.diagnosticsManager.CreateStatsEventAndReset(0, 0, 0)
+		assert.Equal(t, 1, event.ExpiredPayloads)
+		assert.Equal(t, 0, len(listSpoolFiles(t, dir)))
+	})
+}
+
+func createEventProcessorWithStatus(config Config, statusCode int) (*defaultEventProcessor, *stubTransport) {
+	st := &stubTransport{statusCode: statusCode, messageSent: make(chan *http.Request, 100)}
+	ep := NewDefaultEventProcessor(sdkKey, config, &http.Client{Transport: st})
+	return ep.(*defaultEventProcessor), st
+}
+
+func TestNewPayloadIsSpooledBehindPayloadsThatCannotBeReplayed(t *testing.T) {
+	withEventSpoolDir(t, func(dir string) {
+		s := makeTestEventSpool(t, dir, 0, 0)
+		s.add([]byte(`[{"kind":"custom","key":"a"}]`), "id-a")
+
+		config := epDefaultConfig
+		config.EventSpoolDir = dir
+		ep, st := createEventProcessorWithStatus(config, 503)
+		defer ep.Close()
+		ep.waitUntilInactive()
+		assert.Equal(t, "id-a", st.getNextRequest().Header.Get(payloadIDHeader))
+		assert.Equal(t, "id-a", st.getNextRequest().Header.Get(payloadIDHeader)) // retry
+
+		ep.SendEvent(NewIdentifyEvent(epDefaultUser))
+		ep.Flush()
+		ep.waitUntilInactive()
+
+		assert.Equal(t, "id-a", st.getNextRequest().Header.Get(payloadIDHeader))
+		assert.Equal(t, "id-a", st.getNextRequest().Header.Get(payloadIDHeader))
+		assert.Nil(t, st.getNextRequest()) // the new payload was not sent ahead of the older one
+		files := listSpoolFiles(t, dir)
+		require.Equal(t, 2, len(files))
+		assert.True(t, strings.HasSuffix(files[0], "-id-a"+eventSpoolFileSuffix))
+	})
+}
+
+func TestUnauthorizedResponseDuringReplayDisablesEventProcessor(t *testing.T) {
+	withEventSpoolDir(t, func(dir string) {
+		s := makeTestEventSpool(t, dir, 0, 0)
+		s.add([]byte(`[{"kind":"custom","key":"a"}]`), "id-a")
+
+		config := epDefaultConfig
+		config.EventSpoolDir = dir
+		ep, st := createEventProcessorWithStatus(config, 401)
+		defer ep.Close()
+		ep.waitUntilInactive()
+		assert.Equal(t, "id-a", st.getNextRequest().Header.Get(payloadIDHeader))
+
+		ep.SendEvent(NewIdentifyEvent(epDefaultUser))
+		ep.Flush()
+		ep.waitUntilInactive()
+
+		assert.Nil(t, st.getNextRequest())
+		assert.Equal(t, 1, len(listSpoolFiles(t, dir))) // kept in case a later run has a valid SDK key
+	})
+}
+
+func TestRejectedPayloadIsDiscardedDuringReplay(t *testing.T) {
+	withEventSpoolDir(t, func(dir string) {
+		s := makeTestEventSpool(t, dir, 0, 0)
+		s.add([]byte(`[{"kind":"custom","key":"a"}]`), "id-a")
+
+		config := epDefaultConfig
+		config.EventSpoolDir = dir
+		ep, st := createEventProcessorWithStatus(config, 400)
+		defer ep.Close()
+		ep.waitUntilInactive()
+
+		assert.Equal(t, "id-a", st.getNextRequest().Header.Get(payloadIDHeader))
+		assert.Equal(t, 0, len(listSpoolFiles(t, dir)))
+	})
+}
@@ -96,6 +96,23 @@ func newUnknownFlagEvent(key string, user User, defaultVal ldvalue.Value, reason
 	return fre
 }
 
+func newOverrideEvent(key string, user User, value, defaultVal ldvalue.Value, reason EvaluationReason,
+	includeReason bool) FeatureRequestEvent {
+	fre := FeatureRequestEvent{
+		BaseEvent: BaseEvent{
+			CreationDate: now(),
+			User:         user,
+		},
+		Key:     key,
+		Value:   value.UnsafeArbitraryValue(),      //nolint:megacheck // allow deprecated usage
+		Default: defaultVal.UnsafeArbitraryValue(), //nolint:megacheck // allow deprecated usage
+	}
+	if includeReason {
+		fre.Reason.Reason = reason
+	}
+	return fre
+}
+
 func newSuccessfulEvalEvent(flag *FeatureFlag, user User, variation *int, value, defaultVal ldvalue.Value,
 	reason EvaluationReason, includeReason bool, prereqOf *string) FeatureRequestEvent {
 	requireExperimentData := isExperiment(flag, reason)
@@ -123,6 +140,9 @@ func isExperiment(flag *FeatureFlag, reason EvaluationReason) bool {
 	if reason == nil {
 		return false
 	}
+	if reasonIsInExperiment(reason) {
+		return true
+	}
 	switch reason.GetKind() {
 	case EvalReasonFallthrough:
 		return flag.TrackEventsFallthrough
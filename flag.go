@@ -110,38 +110,69 @@ type VariationOrRollout struct {
 	Rollout   *Rollout `json:"rollout,omitempty" bson:"rollout,omitempty"`
 }
 
-// Rollout describes how users will be bucketed into variations during a percentage rollout.
+// Rollout describes how users will be bucketed into variations during a percentage rollout. BucketBy
+// may be either an attribute name or a slash-delimited reference to a value nested within a custom
+// attribute, such as "/address/city". ContextKind selects the entity of an evaluation context that
+// is bucketed; if it is empty, the "user" entity is used.
+//
+// If Kind is RolloutKindExperiment, the rollout is an experiment: users are always bucketed by key,
+// and users who land in a variation that is not Untracked are reported as being in the experiment. If
+// Seed is set, it is used in place of the flag key and salt when computing buckets, so that the same
+// user lands in the same bucket in every flag that uses that seed.
 //
 // Deprecated: this type is for internal use and will be moved to another package in a future version.
 type Rollout struct {
-	Variations []WeightedVariation `json:"variations" bson:"variations"`
-	BucketBy   *string             `json:"bucketBy,omitempty" bson:"bucketBy,omitempty"`
+	Kind        RolloutKind         `json:"kind,omitempty" bson:"kind,omitempty"`
+	Variations  []WeightedVariation `json:"variations" bson:"variations"`
+	BucketBy    *string             `json:"bucketBy,omitempty" bson:"bucketBy,omitempty"`
+	ContextKind string              `json:"contextKind,omitempty" bson:"contextKind,omitempty"`
+	Seed        *int                `json:"seed,omitempty" bson:"seed,omitempty"`
 }
 
-// Clause describes an individual cluuse within a targeting rule.
+// RolloutKind describes the purpose of a Rollout.
+//
+// Deprecated: this type is for internal use and will be moved to another package in a future version.
+type RolloutKind string
+
+const (
+	// RolloutKindRollout is an ordinary percentage rollout. This is the default if Rollout.Kind is empty.
+	RolloutKindRollout RolloutKind = "rollout"
+	// RolloutKindExperiment is a rollout that allocates users to an experiment.
+	RolloutKindExperiment RolloutKind = "experiment"
+)
+
+// Clause describes an individual cluuse within a targeting rule. Attribute may be either an attribute
+// name or a slash-delimited reference to a value nested within a custom attribute, such as "/address/city".
+// ContextKind selects the entity of an evaluation context whose attribute is tested; if it is empty, the
+// "user" entity is used. A clause never matches a context that has no entity of that kind.
 //
 // Deprecated: this type is for internal use and will be moved to another package in a future version.
 type Clause struct {
-	Attribute string        `json:"attribute" bson:"attribute"`
-	Op        Operator      `json:"op" bson:"op"`
-	Values    []interface{} `json:"values" bson:"values"` // An array, interpreted as an OR of values
-	Negate    bool          `json:"negate" bson:"negate"`
+	Attribute   string        `json:"attribute" bson:"attribute"`
+	Op          Operator      `json:"op" bson:"op"`
+	Values      []interface{} `json:"values" bson:"values"` // An array, interpreted as an OR of values
+	Negate      bool          `json:"negate" bson:"negate"`
+	ContextKind string        `json:"contextKind,omitempty" bson:"contextKind,omitempty"`
 }
 
-// WeightedVariation describes a fraction of users who will receive a specific variation.
+// WeightedVariation describes a fraction of users who will receive a specific variation. In an
+// experiment, users who receive an Untracked variation are not part of the experiment population.
 //
 // Deprecated: this type is for internal use and will be moved to another package in a future version.
 type WeightedVariation struct {
-	Variation int `json:"variation" bson:"variation"`
-	Weight    int `json:"weight" bson:"weight"` // Ranges from 0 to 100000
+	Variation int  `json:"variation" bson:"variation"`
+	Weight    int  `json:"weight" bson:"weight"` // Ranges from 0 to 100000
+	Untracked bool `json:"untracked,omitempty" bson:"untracked,omitempty"`
 }
 
-// Target describes a set of users who will receive a specific variation.
+// Target describes a set of users who will receive a specific variation. ContextKind selects the entity
+// of an evaluation context whose key is compared to Values; if it is empty, the "user" entity is used.
 //
 // Deprecated: this type is for internal use and will be moved to another package in a future version.
 type Target struct {
-	Values    []string `json:"values" bson:"values"`
-	Variation int      `json:"variation" bson:"variation"`
+	Values      []string `json:"values" bson:"values"`
+	Variation   int      `json:"variation" bson:"variation"`
+	ContextKind string   `json:"contextKind,omitempty" bson:"contextKind,omitempty"`
 }
 
 // Prerequisite describes a requirement that another feature flag return a specific variation.
@@ -152,7 +183,7 @@ type Prerequisite struct {
 	Variation int    `json:"variation"`
 }
 
-func bucketUser(user User, key, attr, salt string) float32 {
+func bucketUser(user User, key, attr, salt string, seed *int) float32 {
 	uValue, found := user.valueOf(attr)
 	if !found {
 		return 0
@@ -167,8 +198,13 @@ func bucketUser(user User, key, attr, salt string) float32 {
 		idHash = idHash + "." + *user.Secondary
 	}
 
+	prefix := key + "." + salt
+	if seed != nil {
+		prefix = strconv.Itoa(*seed)
+	}
+
 	h := sha1.New() // nolint:gas // just used for insecure hashing
-	_, _ = io.WriteString(h, key+"."+salt+"."+idHash)
+	_, _ = io.WriteString(h, prefix+"."+idHash)
 	hash := hex.EncodeToString(h.Sum(nil))[:15]
 
 	intVal, _ := strconv.ParseInt(hash, 16, 64)
@@ -209,14 +245,40 @@ type EvalResult struct {
 //
 // Deprecated: this method is for internal use and will be moved to another package in a future version.
 func (f FeatureFlag) EvaluateDetail(user User, store FeatureStore, sendReasonsInEvents bool) (EvaluationDetail, []FeatureRequestEvent) {
+	return f.evaluateDetailForContext(NewContextFromUser(user), store, sendReasonsInEvents)
+}
+
+func (f FeatureFlag) evaluateDetailForContext(context Context, store FeatureStore, sendReasonsInEvents bool) (EvaluationDetail, []FeatureRequestEvent) {
+	return f.evaluateDetailWithTrace(context, store, sendReasonsInEvents, nil)
+}
+
+// Same as evaluateDetailForContext, but if trace is non-nil, each step of the evaluation is recorded in it.
+func (f FeatureFlag) evaluateDetailWithTrace(context Context, store FeatureStore, sendReasonsInEvents bool,
+	trace *EvaluationTrace) (EvaluationDetail, []FeatureRequestEvent) {
+	if trace != nil {
+		trace.FlagKey = f.Key
+		trace.FlagVersion = f.Version
+		trace.On = f.On
+	}
+	var detail EvaluationDetail
+	var prereqEvents []FeatureRequestEvent
 	if f.On {
-		prereqErrorReason, prereqEvents := f.checkPrerequisites(user, store, sendReasonsInEvents)
+		var prereqErrorReason EvaluationReason
+		prereqErrorReason, prereqEvents = f.checkPrerequisites(context, store, sendReasonsInEvents, trace)
 		if prereqErrorReason != nil {
-			return f.getOffValue(prereqErrorReason), prereqEvents
+			detail = f.getOffValue(prereqErrorReason)
+		} else {
+			detail = f.evaluateInternal(context, store, trace)
 		}
-		return f.evaluateInternal(user, store), prereqEvents
+	} else {
+		detail = f.getOffValue(evalReasonOffInstance)
 	}
-	return f.getOffValue(evalReasonOffInstance), nil
+	if trace != nil {
+		trace.Value = detail.JSONValue
+		trace.VariationIndex = detail.VariationIndex
+		trace.Reason = EvaluationReasonContainer{detail.Reason}
+	}
+	return detail, prereqEvents
 }
 
 // Evaluate returns the variation selected for a user.
@@ -255,13 +317,19 @@ func (f FeatureFlag) EvaluateExplain(user User, store FeatureStore) (*EvalResult
 }
 
 // Returns nil if all prerequisites are OK, otherwise constructs an error reason that describes the failure
-func (f FeatureFlag) checkPrerequisites(user User, store FeatureStore, sendReasonsInEvents bool) (EvaluationReason, []FeatureRequestEvent) {
+func (f FeatureFlag) checkPrerequisites(context Context, store FeatureStore, sendReasonsInEvents bool,
+	trace *EvaluationTrace) (EvaluationReason, []FeatureRequestEvent) {
 	if len(f.Prerequisites) == 0 {
 		return nil, nil
 	}
 
 	events := make([]FeatureRequestEvent, 0, len(f.Prerequisites))
 	for _, prereq := range f.Prerequisites {
+		var prereqTrace *PrerequisiteTrace
+		if trace != nil {
+			trace.Prerequisites = append(trace.Prerequisites, PrerequisiteTrace{Key: prereq.Key, Variation: prereq.Variation})
+			prereqTrace = &trace.Prerequisites[len(trace.Prerequisites)-1]
+		}
 		data, err := store.Get(Features, prereq.Key)
 		if err != nil || data == nil {
 			return newEvalReasonPrerequisiteFailed(prereq.Key), events
@@ -269,15 +337,24 @@ func (f FeatureFlag) checkPrerequisites(user User, store FeatureStore, sendReaso
 		prereqFeatureFlag, _ := data.(*FeatureFlag)
 		prereqOK := true
 
-		prereqResult, moreEvents := prereqFeatureFlag.EvaluateDetail(user, store, sendReasonsInEvents)
+		var prereqFlagTrace *EvaluationTrace
+		if prereqTrace != nil {
+			prereqTrace.Found = true
+			prereqFlagTrace = &EvaluationTrace{}
+			prereqTrace.Trace = prereqFlagTrace
+		}
+		prereqResult, moreEvents := prereqFeatureFlag.evaluateDetailWithTrace(context, store, sendReasonsInEvents, prereqFlagTrace)
 		if !prereqFeatureFlag.On || prereqResult.VariationIndex == nil || *prereqResult.VariationIndex != prereq.Variation {
 			// Note that if the prerequisite flag is off, we don't consider it a match no matter what its
 			// off variation was. But we still need to evaluate it in order to generate an event.
 			prereqOK = false
 		}
+		if prereqTrace != nil {
+			prereqTrace.Met = prereqOK
+		}
 
 		events = append(events, moreEvents...)
-		prereqEvent := newSuccessfulEvalEvent(prereqFeatureFlag, user, prereqResult.VariationIndex,
+		prereqEvent := newSuccessfulEvalEvent(prereqFeatureFlag, context.eventUser(), prereqResult.VariationIndex,
 			prereqResult.JSONValue, ldvalue.Null(), prereqResult.Reason, sendReasonsInEvents, &f.Key)
 		if sendReasonsInEvents {
 			prereqEvent.Reason.Reason = prereqResult.Reason
@@ -291,25 +368,47 @@ func (f FeatureFlag) checkPrerequisites(user User, store FeatureStore, sendReaso
 	return nil, events
 }
 
-func (f FeatureFlag) evaluateInternal(user User, store FeatureStore) EvaluationDetail {
+func (f FeatureFlag) evaluateInternal(context Context, store FeatureStore, trace *EvaluationTrace) EvaluationDetail {
 	// Check to see if targets match
 	for _, target := range f.Targets {
-		for _, value := range target.Values {
-			if value == *user.Key {
-				return f.getVariation(target.Variation, evalReasonTargetMatchInstance)
-			}
+		matched := false
+		if user, ok := context.Individual(target.ContextKind); ok && user.Key != nil {
+			matched = containsString(target.Values, *user.Key)
+		}
+		if trace != nil {
+			trace.Targets = append(trace.Targets,
+				TargetTrace{ContextKind: target.ContextKind, Variation: target.Variation, Matched: matched})
+		}
+		if matched {
+			return f.getVariation(target.Variation, evalReasonTargetMatchInstance)
 		}
 	}
 
 	// Now walk through the rules and see if any match
 	for ruleIndex, rule := range f.Rules {
-		if rule.matchesUser(store, user) {
+		var ruleTrace *RuleTrace
+		if trace != nil {
+			trace.Rules = append(trace.Rules, RuleTrace{Index: ruleIndex, ID: rule.ID})
+			ruleTrace = &trace.Rules[len(trace.Rules)-1]
+		}
+		if rule.matchesContext(store, context, ruleTrace) {
 			reason := newEvalReasonRuleMatch(ruleIndex, rule.ID)
-			return f.getValueForVariationOrRollout(rule.VariationOrRollout, user, reason)
+			var rolloutTrace *RolloutTrace
+			if ruleTrace != nil {
+				ruleTrace.Matched = true
+				rolloutTrace = &RolloutTrace{}
+				ruleTrace.Rollout = rolloutTrace
+			}
+			return f.getValueForVariationOrRollout(rule.VariationOrRollout, context, reason, rolloutTrace)
 		}
 	}
 
-	return f.getValueForVariationOrRollout(f.Fallthrough, user, evalReasonFallthroughInstance)
+	var rolloutTrace *RolloutTrace
+	if trace != nil {
+		rolloutTrace = &RolloutTrace{}
+		trace.Fallthrough = rolloutTrace
+	}
+	return f.getValueForVariationOrRollout(f.Fallthrough, context, evalReasonFallthroughInstance, rolloutTrace)
 }
 
 func (f FeatureFlag) getVariation(index int, reason EvaluationReason) EvaluationDetail {
@@ -332,25 +431,54 @@ func (f FeatureFlag) getOffValue(reason EvaluationReason) EvaluationDetail {
 	return f.getVariation(*f.OffVariation, reason)
 }
 
-func (f FeatureFlag) getValueForVariationOrRollout(vr VariationOrRollout, user User, reason EvaluationReason) EvaluationDetail {
-	index := vr.variationIndexForUser(user, f.Key, f.Salt)
+func (f FeatureFlag) getValueForVariationOrRollout(vr VariationOrRollout, context Context, reason EvaluationReason,
+	trace *RolloutTrace) EvaluationDetail {
+	index, inExperiment := vr.variationIndexForContext(context, f.Key, f.Salt, trace)
+	if trace != nil {
+		trace.Variation = index
+		trace.InExperiment = inExperiment
+	}
 	if index == nil {
 		return EvaluationDetail{Reason: newEvalReasonError(EvalErrorMalformedFlag)}
 	}
+	if inExperiment {
+		reason = reasonWithInExperiment(reason)
+	}
 	return f.getVariation(*index, reason)
 }
 
-func (r Rule) matchesUser(store FeatureStore, user User) bool {
+func (r Rule) matchesContext(store FeatureStore, context Context, trace *RuleTrace) bool {
 	for _, clause := range r.Clauses {
-		if !clause.matchesUser(store, user) {
+		var clauseTrace *ClauseTrace
+		if trace != nil {
+			trace.Clauses = append(trace.Clauses, ClauseTrace{ContextKind: clause.ContextKind, Attribute: clause.Attribute,
+				Op: clause.Op, Values: clause.Values, Negate: clause.Negate})
+			clauseTrace = &trace.Clauses[len(trace.Clauses)-1]
+		}
+		matched := clause.matchesContext(store, context, clauseTrace)
+		if clauseTrace != nil {
+			clauseTrace.Matched = matched
+		}
+		if !matched {
 			return false
 		}
 	}
 	return true
 }
 
-func (c Clause) matchesUserNoSegments(user User) bool {
+func (c Clause) matchesContextNoSegments(context Context, trace *ClauseTrace) bool {
+	user, ok := context.Individual(c.ContextKind)
+	if !ok {
+		return false
+	}
+	return c.matchesUserNoSegments(user, trace)
+}
+
+func (c Clause) matchesUserNoSegments(user User, trace *ClauseTrace) bool {
 	uValue, found := user.valueOf(c.Attribute)
+	if trace != nil {
+		trace.UserValue = uValue
+	}
 
 	if !found {
 		return false
@@ -374,7 +502,7 @@ func (c Clause) matchesUserNoSegments(user User) bool {
 	return c.maybeNegate(matchAny(matchFn, uValue, c.Values))
 }
 
-func (c Clause) matchesUser(store FeatureStore, user User) bool {
+func (c Clause) matchesContext(store FeatureStore, context Context, trace *ClauseTrace) bool {
 	// In the case of a segment match operator, we check if the user is in any of the segments,
 	// and possibly negate
 	if c.Op == OperatorSegmentMatch {
@@ -383,17 +511,25 @@ func (c Clause) matchesUser(store FeatureStore, user User) bool {
 				data, _ := store.Get(Segments, vStr)
 				// If segment is not found or the store got an error, data will be nil and we'll just fall through
 				// the next block. Unfortunately we have no access to a logger here so this failure is silent.
-				if segment, segmentOk := data.(*Segment); segmentOk {
-					if matches, _ := segment.ContainsUser(user); matches {
-						return c.maybeNegate(true)
-					}
+				segment, segmentOk := data.(*Segment)
+				var matches bool
+				var explanation *SegmentExplanation
+				if segmentOk {
+					matches, explanation = segment.containsContext(context)
+				}
+				if trace != nil {
+					trace.Segments = append(trace.Segments,
+						SegmentTrace{Key: vStr, Found: segmentOk, Matched: matches, Explanation: explanation})
+				}
+				if matches {
+					return c.maybeNegate(true)
 				}
 			}
 		}
 		return c.maybeNegate(false)
 	}
 
-	return c.matchesUserNoSegments(user)
+	return c.matchesContextNoSegments(context, trace)
 }
 
 func (c Clause) maybeNegate(b bool) bool {
@@ -413,30 +549,51 @@ func matchAny(fn opFn, value interface{}, values []interface{}) bool {
 }
 
 func (r VariationOrRollout) variationIndexForUser(user User, key, salt string) *int {
+	index, _ := r.variationIndexForContext(NewContextFromUser(user), key, salt, nil)
+	return index
+}
+
+// Returns the variation index, and true if the context is part of an experiment. If trace is non-nil, the
+// bucketing details of a rollout are recorded in it.
+func (r VariationOrRollout) variationIndexForContext(context Context, key, salt string, trace *RolloutTrace) (*int, bool) {
 	if r.Variation != nil {
-		return r.Variation
+		return r.Variation, false
 	}
 	if r.Rollout == nil {
 		// This is an error (malformed flag); either Variation or Rollout must be non-nil.
-		return nil
+		return nil, false
 	}
 
+	isExperiment := r.Rollout.Kind == RolloutKindExperiment
 	bucketBy := userKey
-	if r.Rollout.BucketBy != nil {
+	if r.Rollout.BucketBy != nil && !isExperiment {
 		bucketBy = *r.Rollout.BucketBy
 	}
 
-	var bucket = bucketUser(user, key, bucketBy, salt)
+	// If the context has no entity of the rollout's kind, it goes in the first bucket, and is never
+	// part of an experiment.
+	var bucket float32
+	user, hasKind := context.Individual(r.Rollout.ContextKind)
+	if hasKind {
+		bucket = bucketUser(user, key, bucketBy, salt, r.Rollout.Seed)
+	}
+	if trace != nil {
+		trace.ContextKind = r.Rollout.ContextKind
+		trace.BucketBy = bucketBy
+		if hasKind {
+			trace.Bucket = &bucket
+		}
+	}
 	var sum float32
 
 	if len(r.Rollout.Variations) == 0 {
 		// This is an error (malformed flag); there must be at least one weighted variation.
-		return nil
+		return nil, false
 	}
 	for _, wv := range r.Rollout.Variations {
 		sum += float32(wv.Weight) / 100000.0
 		if bucket < sum {
-			return &wv.Variation
+			return &wv.Variation, isExperiment && hasKind && !wv.Untracked
 		}
 	}
 	// The user's bucket value was greater than or equal to the end of the last bucket. This could happen due
@@ -444,6 +601,6 @@ func (r VariationOrRollout) variationIndexForUser(user User, key, salt string) *
 	// data could contain buckets that don't actually add up to 100000. Rather than returning an error in
 	// this case (or changing the scaling, which would potentially change the results for *all* users), we
 	// will simply put the user in the last bucket.
-	v := r.Rollout.Variations[len(r.Rollout.Variations)-1].Variation
-	return &v
+	last := r.Rollout.Variations[len(r.Rollout.Variations)-1]
+	return &last.Variation, isExperiment && hasKind && !last.Untracked
 }
@@ -0,0 +1,135 @@
+package ldclient
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"sync"
+	"time"
+
+	"gopkg.in/launchdarkly/go-sdk-common.v1/ldvalue"
+)
+
+// FlagOverride pins a feature flag to a specific value, regardless of the flag's configuration in
+// LaunchDarkly. Overrides are set with LDClient.SetFlagOverride or loaded from Config.FlagOverridesFile,
+// and are checked before the flag is looked up in the feature store, so they also apply to flags that
+// do not exist and to a client in offline mode. An evaluation that uses an override has the reason
+// kind EvalReasonOverride.
+//
+// An override applies to all users, unless UserKeys is not empty, in which case it applies only to
+// users with those keys. Overrides never apply to a user without a key, which is still an error. It is in effect from StartTime (or immediately, if StartTime is zero) until
+// EndTime (or indefinitely, if EndTime is zero).
+//
+// In Config.FlagOverridesFile, overrides are represented as a JSON array of objects, with times in
+// RFC 3339 format:
+//
+//     [
+//         { "flagKey": "flag1", "value": true },
+//         { "flagKey": "flag2", "value": "blue", "userKeys": [ "user1", "user2" ],
+//           "startTime": "2020-06-01T09:00:00Z", "endTime": "2020-06-01T17:00:00Z" }
+//     ]
+type FlagOverride struct {
+	// FlagKey is the key of the flag to override.
+	FlagKey string `json:"flagKey"`
+	// Value is the value that evaluations of the flag will return.
+	Value ldvalue.Value `json:"value"`
+	// UserKeys, if not empty, limits the override to users with these keys.
+	UserKeys []string `json:"userKeys,omitempty"`
+	// StartTime, if not zero, is the time when the override takes effect.
+	StartTime time.Time `json:"startTime"`
+	// EndTime, if not zero, is the time when the override expires.
+	EndTime time.Time `json:"endTime"`
+}
+
+func (o FlagOverride) appliesTo(user User, now time.Time) bool {
+	if !o.StartTime.IsZero() && now.Before(o.StartTime) {
+		return false
+	}
+	if !o.EndTime.IsZero() && !now.Before(o.EndTime) {
+		return false
+	}
+	if len(o.UserKeys) == 0 {
+		return true
+	}
+	return user.Key != nil && containsString(o.UserKeys, *user.Key)
+}
+
+type flagOverrides struct {
+	overrides map[string][]FlagOverride
+	lock      sync.RWMutex
+}
+
+func newFlagOverrides() *flagOverrides {
+	return &flagOverrides{overrides: make(map[string][]FlagOverride)}
+}
+
+func loadFlagOverridesFile(path string) ([]FlagOverride, error) {
+	data, err := ioutil.ReadFile(path) // nolint:gosec // G304: ok to read file into variable
+	if err != nil {
+		return nil, err
+	}
+	var overrides []FlagOverride
+	if err := json.Unmarshal(data, &overrides); err != nil {
+		return nil, fmt.Errorf("unable to parse flag overrides file %s: %s", path, err)
+	}
+	for _, o := range overrides {
+		if o.FlagKey == "" {
+			return nil, fmt.Errorf("flag override in %s has no flagKey", path)
+		}
+	}
+	return overrides, nil
+}
+
+func (f *flagOverrides) add(o FlagOverride) {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	f.overrides[o.FlagKey] = append(f.overrides[o.FlagKey], o)
+}
+
+func (f *flagOverrides) clear(flagKey string) {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	delete(f.overrides, flagKey)
+}
+
+// Returns the override that applies to this flag and user, if any. If several apply, the one that was
+// added last is used. Expired overrides are discarded.
+func (f *flagOverrides) find(flagKey string, user User, now time.Time) (FlagOverride, bool) {
+	f.lock.RLock()
+	list := f.overrides[flagKey]
+	f.lock.RUnlock()
+	if len(list) == 0 {
+		return FlagOverride{}, false
+	}
+	expired := false
+	for i := len(list) - 1; i >= 0; i-- {
+		o := list[i]
+		if !o.EndTime.IsZero() && !now.Before(o.EndTime) {
+			expired = true
+			continue
+		}
+		if o.appliesTo(user, now) {
+			return o, true
+		}
+	}
+	if expired {
+		f.removeExpired(flagKey, now)
+	}
+	return FlagOverride{}, false
+}
+
+func (f *flagOverrides) removeExpired(flagKey string, now time.Time) {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	var remaining []FlagOverride
+	for _, o := range f.overrides[flagKey] {
+		if o.EndTime.IsZero() || now.Before(o.EndTime) {
+			remaining = append(remaining, o)
+		}
+	}
+	if len(remaining) == 0 {
+		delete(f.overrides, flagKey)
+	} else {
+		f.overrides[flagKey] = remaining
+	}
+}
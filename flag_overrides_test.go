@@ -0,0 +1,177 @@
+package ldclient
+
+import (
+	"io/ioutil"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gopkg.in/launchdarkly/go-sdk-common.v1/ldvalue"
+)
+
+func TestOverrideIsAppliedBeforeFeatureStore(t *testing.T) {
+	flag := makeTestFlag("flagKey", 1, "a", "b")
+	client := makeTestClient()
+	defer client.Close()
+	client.store.Upsert(Features, flag)
+
+	client.SetFlagOverride(FlagOverride{FlagKey: flag.Key, Value: ldvalue.String("c")})
+
+	value, detail, err := client.StringVariationDetail(flag.Key, evalTestUser, "x")
+	assert.NoError(t, err)
+	assert.Equal(t, "c", value)
+	assert.Nil(t, detail.VariationIndex)
+	assert.Equal(t, evalReasonOverrideInstance, detail.Reason)
+
+	events := client.eventProcessor.(*testEventProcessor).events
+	require.Equal(t, 1, len(events))
+	e := events[0].(FeatureRequestEvent)
+	assert.Equal(t, "c", e.Value)
+	assert.Equal(t, "x", e.Default)
+	assert.Equal(t, evalReasonOverrideInstance, e.Reason.Reason)
+}
+
+func TestOverrideAppliesToUnknownFlag(t *testing.T) {
+	client := makeTestClient()
+	defer client.Close()
+
+	client.SetFlagOverride(FlagOverride{FlagKey: "unknown", Value: ldvalue.Bool(true)})
+
+	value, err := client.BoolVariation("unknown", evalTestUser, false)
+	assert.NoError(t, err)
+	assert.True(t, value)
+}
+
+func TestOverrideWithWrongTypeReturnsError(t *testing.T) {
+	client := makeTestClient()
+	defer client.Close()
+
+	client.SetFlagOverride(FlagOverride{FlagKey: "flagKey", Value: ldvalue.String("c")})
+
+	value, detail, _ := client.BoolVariationDetail("flagKey", evalTestUser, false)
+	assert.False(t, value)
+	assert.Equal(t, newEvalReasonError(EvalErrorWrongType), detail.Reason)
+}
+
+func TestOverrideAppliesInOfflineMode(t *testing.T) {
+	client := makeTestClientWithConfig(func(c *Config) { c.Offline = true })
+	defer client.Close()
+
+	client.SetFlagOverride(FlagOverride{FlagKey: "flagKey", Value: ldvalue.String("c")})
+
+	value, detail, err := client.StringVariationDetail("flagKey", evalTestUser, "x")
+	assert.NoError(t, err)
+	assert.Equal(t, "c", value)
+	assert.Equal(t, evalReasonOverrideInstance, detail.Reason)
+	value, detail, _ = client.StringVariationDetail("otherKey", evalTestUser, "x")
+	assert.Equal(t, "x", value)
+	assert.Equal(t, newEvalReasonError(EvalErrorClientNotReady), detail.Reason)
+}
+
+func TestOverrideIsAppliedInAllFlagsState(t *testing.T) {
+	flag0 := makeTestFlag("flag0", 1, "a", "b")
+	flag1 := makeTestFlag("flag1", 1, "a", "b")
+	client := makeTestClient()
+	defer client.Close()
+	client.store.Upsert(Features, flag0)
+	client.store.Upsert(Features, flag1)
+
+	client.SetFlagOverride(FlagOverride{FlagKey: flag0.Key, Value: ldvalue.String("c")})
+
+	state := client.AllFlagsState(evalTestUser, WithReasons)
+	assert.Equal(t, map[string]interface{}{"flag0": "c", "flag1": "b"}, state.ToValuesMap())
+	assert.Equal(t, evalReasonOverrideInstance, state.flagMetadata["flag0"].Reason)
+	assert.Nil(t, state.flagMetadata["flag0"].Variation)
+}
+
+func TestOverrideForSpecificUsers(t *testing.T) {
+	client := makeTestClient()
+	defer client.Close()
+
+	client.SetFlagOverride(FlagOverride{FlagKey: "flagKey", Value: ldvalue.String("c"), UserKeys: []string{"a", "b"}})
+
+	value, _ := client.StringVariation("flagKey", NewUser("b"), "x")
+	assert.Equal(t, "c", value)
+	value, _ = client.StringVariation("flagKey", NewUser("z"), "x")
+	assert.Equal(t, "x", value)
+}
+
+func TestOverrideDoesNotApplyToUserWithoutKey(t *testing.T) {
+	client := makeTestClient()
+	defer client.Close()
+
+	client.SetFlagOverride(FlagOverride{FlagKey: "flagKey", Value: ldvalue.String("c")})
+	client.store.Upsert(Features, makeTestFlag("flagKey", 1, "a", "b"))
+
+	value, detail, err := client.StringVariationDetail("flagKey", User{}, "x")
+	assert.Error(t, err)
+	assert.Equal(t, "x", value)
+	assert.Equal(t, newEvalReasonError(EvalErrorUserNotSpecified), detail.Reason)
+}
+
+func TestOverrideTimeWindow(t *testing.T) {
+	now := time.Now()
+	overrides := newFlagOverrides()
+	overrides.add(FlagOverride{FlagKey: "flagKey", Value: ldvalue.String("past"), EndTime: now.Add(-time.Minute)})
+	overrides.add(FlagOverride{FlagKey: "flagKey", Value: ldvalue.String("future"), StartTime: now.Add(time.Minute)})
+
+	_, found := overrides.find("flagKey", evalTestUser, now)
+	assert.False(t, found)
+	assert.Equal(t, 1, len(overrides.overrides["flagKey"])) // expired override was discarded
+
+	o, found := overrides.find("flagKey", evalTestUser, now.Add(2*time.Minute))
+	assert.True(t, found)
+	assert.Equal(t, ldvalue.String("future"), o.Value)
+}
+
+func TestLastAddedOverrideWins(t *testing.T) {
+	client := makeTestClient()
+	defer client.Close()
+
+	client.SetFlagOverride(FlagOverride{FlagKey: "flagKey", Value: ldvalue.String("first")})
+	client.SetFlagOverride(FlagOverride{FlagKey: "flagKey", Value: ldvalue.String("second")})
+
+	value, _ := client.StringVariation("flagKey", evalTestUser, "x")
+	assert.Equal(t, "second", value)
+
+	client.ClearFlagOverrides("flagKey")
+	value, _ = client.StringVariation("flagKey", evalTestUser, "x")
+	assert.Equal(t, "x", value)
+}
+
+func TestOverridesCanBeLoadedFromFile(t *testing.T) {
+	f, err := ioutil.TempFile("", "overrides")
+	require.NoError(t, err)
+	defer os.Remove(f.Name())
+	_, _ = f.WriteString(`[
+		{ "flagKey": "flag1", "value": true },
+		{ "flagKey": "flag2", "value": "blue", "userKeys": [ "userkey" ], "endTime": "2999-01-01T00:00:00Z" }
+	]`)
+	f.Close()
+
+	client := makeTestClientWithConfig(func(c *Config) { c.FlagOverridesFile = f.Name() })
+	require.NotNil(t, client)
+	defer client.Close()
+
+	value1, _ := client.BoolVariation("flag1", evalTestUser, false)
+	assert.True(t, value1)
+	value2, _ := client.StringVariation("flag2", evalTestUser, "x")
+	assert.Equal(t, "blue", value2)
+}
+
+func TestInvalidOverridesFileCausesClientCreationToFail(t *testing.T) {
+	f, err := ioutil.TempFile("", "overrides")
+	require.NoError(t, err)
+	defer os.Remove(f.Name())
+	_, _ = f.WriteString(`[{ "value": true }]`)
+	f.Close()
+
+	config := DefaultConfig
+	config.Offline = true
+	config.FlagOverridesFile = f.Name()
+	client, err := MakeCustomClient("sdkKey", config, 0)
+	assert.Error(t, err)
+	assert.Nil(t, client)
+}
@@ -333,7 +333,7 @@ func TestFlagMatchesUserFromTargets(t *testing.T) {
 		Key:          "feature",
 		On:           true,
 		OffVariation: intPtr(1),
-		Targets:      []Target{Target{[]string{"whoever", "userkey"}, 2}},
+		Targets:      []Target{Target{Values: []string{"whoever", "userkey"}, Variation: 2}},
 		Fallthrough:  VariationOrRollout{Variation: intPtr(0)},
 		Variations:   []interface{}{"fall", "off", "on"},
 	}
@@ -419,6 +419,64 @@ func TestClauseCanMatchCustomAttribute(t *testing.T) {
 	assert.Equal(t, true, result.Value)
 }
 
+func TestClauseCanMatchNestedAttribute(t *testing.T) {
+	clause := Clause{
+		Attribute: "/address/city",
+		Op:        "in",
+		Values:    []interface{}{"Oakland"},
+	}
+	f := booleanFlagWithClause(clause)
+	address := ldvalue.ObjectBuild().Set("city", ldvalue.String("Oakland")).Build()
+	user := NewUserBuilder("key").Custom("address", address).Build()
+
+	result, _ := f.EvaluateDetail(user, emptyFeatureStore, false)
+	assert.Equal(t, true, result.Value)
+}
+
+func TestClauseCanMatchNestedArrayElement(t *testing.T) {
+	clause := Clause{
+		Attribute: "/pets/1/kind",
+		Op:        "in",
+		Values:    []interface{}{"cat"},
+	}
+	f := booleanFlagWithClause(clause)
+	pets := ldvalue.ArrayOf(
+		ldvalue.ObjectBuild().Set("kind", ldvalue.String("dog")).Build(),
+		ldvalue.ObjectBuild().Set("kind", ldvalue.String("cat")).Build(),
+	)
+	user := NewUserBuilder("key").Custom("pets", pets).Build()
+
+	result, _ := f.EvaluateDetail(user, emptyFeatureStore, false)
+	assert.Equal(t, true, result.Value)
+}
+
+func TestClauseReturnsFalseForMissingNestedAttribute(t *testing.T) {
+	clause := Clause{
+		Attribute: "/address/city",
+		Op:        "in",
+		Values:    []interface{}{"Oakland"},
+		Negate:    true,
+	}
+	f := booleanFlagWithClause(clause)
+	user := NewUserBuilder("key").Custom("address", ldvalue.String("Oakland")).Build()
+
+	result, _ := f.EvaluateDetail(user, emptyFeatureStore, false)
+	assert.Equal(t, false, result.Value)
+}
+
+func TestClauseWithPlainAttributeNameContainingSlashIsNotAPath(t *testing.T) {
+	clause := Clause{
+		Attribute: "a/b",
+		Op:        "in",
+		Values:    []interface{}{"x"},
+	}
+	f := booleanFlagWithClause(clause)
+	user := NewUserBuilder("key").Custom("a/b", ldvalue.String("x")).Build()
+
+	result, _ := f.EvaluateDetail(user, emptyFeatureStore, false)
+	assert.Equal(t, true, result.Value)
+}
+
 func TestClauseReturnsFalseForMissingAttribute(t *testing.T) {
 	clause := Clause{
 		Attribute: "legs",
@@ -560,40 +618,174 @@ func TestVariationIndexForUser(t *testing.T) {
 
 func TestBucketUserByKey(t *testing.T) {
 	user := NewUser("userKeyA")
-	bucket := bucketUser(user, "hashKey", "key", "saltyA")
+	bucket := bucketUser(user, "hashKey", "key", "saltyA", nil)
 	assert.InEpsilon(t, 0.42157587, bucket, 0.0000001)
 
 	user = NewUser("userKeyB")
-	bucket = bucketUser(user, "hashKey", "key", "saltyA")
+	bucket = bucketUser(user, "hashKey", "key", "saltyA", nil)
 	assert.InEpsilon(t, 0.6708485, bucket, 0.0000001)
 
 	user = NewUser("userKeyC")
-	bucket = bucketUser(user, "hashKey", "key", "saltyA")
+	bucket = bucketUser(user, "hashKey", "key", "saltyA", nil)
 	assert.InEpsilon(t, 0.10343106, bucket, 0.0000001)
 }
 
 func TestBucketUserByIntAttr(t *testing.T) {
 	user := NewUserBuilder("userKeyD").Custom("intAttr", ldvalue.Int(33333)).Build()
-	bucket := bucketUser(user, "hashKey", "intAttr", "saltyA")
+	bucket := bucketUser(user, "hashKey", "intAttr", "saltyA", nil)
 	assert.InEpsilon(t, 0.54771423, bucket, 0.0000001)
 
 	user = NewUserBuilder("userKeyD").Custom("stringAttr", ldvalue.String("33333")).Build()
-	bucket2 := bucketUser(user, "hashKey", "stringAttr", "saltyA")
+	bucket2 := bucketUser(user, "hashKey", "stringAttr", "saltyA", nil)
 	assert.InEpsilon(t, bucket, bucket2, 0.0000001)
 }
 
 func TestBucketUserByFloatAttrNotAllowed(t *testing.T) {
 	user := NewUserBuilder("userKeyE").Custom("floatAttr", ldvalue.Float64(999.999)).Build()
-	bucket := bucketUser(user, "hashKey", "floatAttr", "saltyA")
+	bucket := bucketUser(user, "hashKey", "floatAttr", "saltyA", nil)
 	assert.InDelta(t, 0.0, bucket, 0.0000001)
 }
 
 func TestBucketUserByFloatAttrThatIsReallyAnIntIsAllowed(t *testing.T) {
 	user := NewUserBuilder("userKeyE").Custom("floatAttr", ldvalue.Float64(33333)).Build()
-	bucket := bucketUser(user, "hashKey", "floatAttr", "saltyA")
+	bucket := bucketUser(user, "hashKey", "floatAttr", "saltyA", nil)
 	assert.InEpsilon(t, 0.54771423, bucket, 0.0000001)
 }
 
+func TestBucketUserByNestedAttr(t *testing.T) {
+	user := NewUserBuilder("userKeyD").Custom("intAttr", ldvalue.Int(33333)).Build()
+	bucket := bucketUser(user, "hashKey", "intAttr", "saltyA", nil)
+
+	nested := ldvalue.ObjectBuild().Set("intAttr", ldvalue.Int(33333)).Build()
+	user = NewUserBuilder("userKeyD").Custom("obj", nested).Build()
+	bucket2 := bucketUser(user, "hashKey", "/obj/intAttr", "saltyA", nil)
+	assert.InEpsilon(t, bucket, bucket2, 0.0000001)
+}
+
+func TestTargetCanMatchContextOfNonDefaultKind(t *testing.T) {
+	f := FeatureFlag{
+		Key:         "feature",
+		On:          true,
+		Targets:     []Target{{Values: []string{"org-key"}, Variation: 2, ContextKind: "org"}},
+		Fallthrough: VariationOrRollout{Variation: intPtr(0)},
+		Variations:  []interface{}{"fall", "off", "on"},
+	}
+
+	result, _ := f.evaluateDetailForContext(NewMultiContext(NewContext("user", "user-key"), NewContext("org", "org-key")),
+		emptyFeatureStore, false)
+	assert.Equal(t, "on", result.Value)
+	assert.Equal(t, evalReasonTargetMatchInstance, result.Reason)
+
+	result, _ = f.evaluateDetailForContext(NewContext("user", "org-key"), emptyFeatureStore, false)
+	assert.Equal(t, "fall", result.Value)
+}
+
+func TestClauseCanMatchAttributeOfNonDefaultKind(t *testing.T) {
+	clause := Clause{ContextKind: "org", Attribute: "name", Op: "in", Values: []interface{}{"Acme"}}
+	f := booleanFlagWithClause(clause)
+	org := NewContextWithAttributes("org", NewUserBuilder("org-key").Name("Acme").Build())
+
+	result, _ := f.evaluateDetailForContext(NewMultiContext(NewContext("user", "user-key"), org), emptyFeatureStore, false)
+	assert.Equal(t, true, result.Value)
+
+	user := NewUserBuilder("user-key").Name("Acme").Build()
+	result, _ = f.EvaluateDetail(user, emptyFeatureStore, false)
+	assert.Equal(t, false, result.Value)
+}
+
+func TestClauseForMissingContextKindIsFalseEvenIfNegated(t *testing.T) {
+	clause := Clause{ContextKind: "org", Attribute: "name", Op: "in", Values: []interface{}{"Acme"}, Negate: true}
+	f := booleanFlagWithClause(clause)
+
+	result, _ := f.evaluateDetailForContext(NewContext("user", "user-key"), emptyFeatureStore, false)
+	assert.Equal(t, false, result.Value)
+}
+
+func TestRolloutBucketsByContextKind(t *testing.T) {
+	wv1 := WeightedVariation{Variation: 0, Weight: 60000.0}
+	wv2 := WeightedVariation{Variation: 1, Weight: 40000.0}
+	rollout := Rollout{Variations: []WeightedVariation{wv1, wv2}, ContextKind: "org"}
+	rule := Rule{VariationOrRollout: VariationOrRollout{Rollout: &rollout}}
+
+	// "userKeyB" buckets into the second variation; see TestVariationIndexForUser
+	context := NewMultiContext(NewContext("user", "userKeyA"), NewContext("org", "userKeyB"))
+	variationIndex, _ := rule.variationIndexForContext(context, "hashKey", "saltyA", nil)
+	assert.Equal(t, intPtr(1), variationIndex)
+
+	variationIndex, _ = rule.variationIndexForContext(NewContext("user", "userKeyB"), "hashKey", "saltyA", nil)
+	assert.Equal(t, intPtr(0), variationIndex)
+}
+
+func TestExperimentRolloutSetsInExperimentForTrackedVariations(t *testing.T) {
+	rollout := Rollout{
+		Kind: RolloutKindExperiment,
+		Variations: []WeightedVariation{
+			{Variation: 0, Weight: 60000, Untracked: true},
+			{Variation: 1, Weight: 40000},
+		},
+	}
+	f := FeatureFlag{
+		Key:         "hashKey",
+		Salt:        "saltyA",
+		On:          true,
+		Fallthrough: VariationOrRollout{Rollout: &rollout},
+		Variations:  []interface{}{"a", "b"},
+	}
+
+	// see TestVariationIndexForUser for the buckets of these keys
+	result, _ := f.EvaluateDetail(NewUser("userKeyB"), emptyFeatureStore, false)
+	assert.Equal(t, "b", result.Value)
+	assert.True(t, reasonIsInExperiment(result.Reason))
+	assert.Equal(t, EvalReasonFallthrough, result.Reason.GetKind())
+
+	result, _ = f.EvaluateDetail(NewUser("userKeyA"), emptyFeatureStore, false)
+	assert.Equal(t, "a", result.Value)
+	assert.False(t, reasonIsInExperiment(result.Reason))
+}
+
+func TestExperimentRolloutIgnoresBucketBy(t *testing.T) {
+	bucketBy := "name"
+	wv1 := WeightedVariation{Variation: 0, Weight: 60000.0}
+	wv2 := WeightedVariation{Variation: 1, Weight: 40000.0}
+	rollout := Rollout{Kind: RolloutKindExperiment, BucketBy: &bucketBy, Variations: []WeightedVariation{wv1, wv2}}
+	vr := VariationOrRollout{Rollout: &rollout}
+
+	user := NewUserBuilder("userKeyB").Name("userKeyA").Build()
+	variationIndex, inExperiment := vr.variationIndexForContext(NewContextFromUser(user), "hashKey", "saltyA", nil)
+	assert.Equal(t, intPtr(1), variationIndex)
+	assert.True(t, inExperiment)
+}
+
+func TestRolloutWithSeedIgnoresFlagKeyAndSalt(t *testing.T) {
+	user := NewUser("userKeyA")
+	seed := 61
+	bucket1 := bucketUser(user, "hashKey", "key", "saltyA", &seed)
+	bucket2 := bucketUser(user, "otherKey", "key", "otherSalt", &seed)
+	assert.Equal(t, bucket1, bucket2)
+	assert.NotEqual(t, bucketUser(user, "hashKey", "key", "saltyA", nil), bucket1)
+
+	otherSeed := 62
+	assert.NotEqual(t, bucket1, bucketUser(user, "hashKey", "key", "saltyA", &otherSeed))
+}
+
+func TestSegmentMatchUsesUserEntityOfContext(t *testing.T) {
+	segment := Segment{
+		Key:      "segkey",
+		Included: []string{"foo"},
+	}
+	clause := Clause{Attribute: "", Op: "segmentMatch", Values: []interface{}{"segkey"}}
+	f := booleanFlagWithClause(clause)
+	featureStore := NewInMemoryFeatureStore(nil)
+	featureStore.Upsert(Segments, &segment)
+
+	result, _ := f.evaluateDetailForContext(NewMultiContext(NewContext("org", "bar"), NewContext("user", "foo")),
+		featureStore, false)
+	assert.Equal(t, true, result.Value)
+
+	result, _ = f.evaluateDetailForContext(NewContext("org", "foo"), featureStore, false)
+	assert.Equal(t, false, result.Value)
+}
+
 func booleanFlagWithClause(clause Clause) FeatureFlag {
 	return FeatureFlag{
 		Key: "feature",
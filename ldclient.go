@@ -32,6 +32,7 @@ type LDClient struct {
 	eventProcessor  EventProcessor
 	updateProcessor UpdateProcessor
 	store           FeatureStore
+	overrides       *flagOverrides
 }
 
 // Logger is a generic logger interface.
@@ -100,6 +101,14 @@ func MakeCustomClient(sdkKey string, config Config, waitFor time.Duration) (*LDC
 	config.Loggers.Init()
 	config.Loggers.Infof("Starting LaunchDarkly client %s", Version)
 
+	var initialOverrides []FlagOverride
+	if config.FlagOverridesFile != "" {
+		var err error
+		if initialOverrides, err = loadFlagOverridesFile(config.FlagOverridesFile); err != nil {
+			return nil, err
+		}
+	}
+
 	if config.FeatureStore == nil {
 		factory := config.FeatureStoreFactory
 		if factory == nil {
@@ -115,12 +124,16 @@ func MakeCustomClient(sdkKey string, config Config, waitFor time.Duration) (*LDC
 	defaultHTTPClient := config.newHTTPClient()
 
 	client := LDClient{
-		sdkKey: sdkKey,
-		config: config,
-		store:  config.FeatureStore,
+		sdkKey:    sdkKey,
+		config:    config,
+		store:     config.FeatureStore,
+		overrides: newFlagOverrides(),
+	}
+	for _, o := range initialOverrides {
+		client.overrides.add(o)
 	}
 
-	if !config.DiagnosticOptOut && config.SendEvents && !config.Offline {
+	if !config.DiagnosticOptOut && config.SendEvents && !config.Offline && !config.EventSinksOnly {
 		id := newDiagnosticId(sdkKey)
 		config.diagnosticsManager = newDiagnosticsManager(id, config, waitFor, time.Now(), nil)
 	}
@@ -342,6 +355,9 @@ func (client *LDClient) AllFlags(user User) map[string]interface{} {
 //
 // The most common use case for this method is to bootstrap a set of client-side feature flags
 // from a back-end service.
+//
+// Flag overrides (see SetFlagOverride) are applied to the flags in the feature store, with the same
+// results as the Variation methods.
 func (client *LDClient) AllFlagsState(user User, options ...FlagsStateOption) FeatureFlagsState {
 	valid := true
 	if client.IsOffline() {
@@ -378,7 +394,10 @@ func (client *LDClient) AllFlagsState(user User, options ...FlagsStateOption) Fe
 			if clientSideOnly && !flag.ClientSide {
 				continue
 			}
-			result, _ := flag.EvaluateDetail(user, client.store, false)
+			result, overridden := client.evaluateOverride(flag.Key, NewContextFromUser(user))
+			if !overridden {
+				result, _ = flag.EvaluateDetail(user, client.store, false)
+			}
 			var reason EvaluationReason
 			if withReasons {
 				reason = result.Reason
@@ -395,14 +414,14 @@ func (client *LDClient) AllFlagsState(user User, options ...FlagsStateOption) Fe
 // Returns defaultVal if there is an error, if the flag doesn't exist, or the feature is turned off and
 // has no off variation.
 func (client *LDClient) BoolVariation(key string, user User, defaultVal bool) (bool, error) {
-	detail, err := client.variation(key, user, ldvalue.Bool(defaultVal), true, false)
+	detail, err := client.variation(key, NewContextFromUser(user), ldvalue.Bool(defaultVal), true, false)
 	return detail.JSONValue.BoolValue(), err
 }
 
 // BoolVariationDetail is the same as BoolVariation, but also returns further information about how
 // the value was calculated. The "reason" data will also be included in analytics events.
 func (client *LDClient) BoolVariationDetail(key string, user User, defaultVal bool) (bool, EvaluationDetail, error) {
-	detail, err := client.variation(key, user, ldvalue.Bool(defaultVal), true, true)
+	detail, err := client.variation(key, NewContextFromUser(user), ldvalue.Bool(defaultVal), true, true)
 	return detail.JSONValue.BoolValue(), detail, err
 }
 
@@ -413,14 +432,14 @@ func (client *LDClient) BoolVariationDetail(key string, user User, defaultVal bo
 //
 // If the flag variation has a numeric value that is not an integer, it is rounded toward zero (truncated).
 func (client *LDClient) IntVariation(key string, user User, defaultVal int) (int, error) {
-	detail, err := client.variation(key, user, ldvalue.Int(defaultVal), true, false)
+	detail, err := client.variation(key, NewContextFromUser(user), ldvalue.Int(defaultVal), true, false)
 	return detail.JSONValue.IntValue(), err
 }
 
 // IntVariationDetail is the same as IntVariation, but also returns further information about how
 // the value was calculated. The "reason" data will also be included in analytics events.
 func (client *LDClient) IntVariationDetail(key string, user User, defaultVal int) (int, EvaluationDetail, error) {
-	detail, err := client.variation(key, user, ldvalue.Int(defaultVal), true, true)
+	detail, err := client.variation(key, NewContextFromUser(user), ldvalue.Int(defaultVal), true, true)
 	return detail.JSONValue.IntValue(), detail, err
 }
 
@@ -429,14 +448,14 @@ func (client *LDClient) IntVariationDetail(key string, user User, defaultVal int
 // Returns defaultVal if there is an error, if the flag doesn't exist, or the feature is turned off and
 // has no off variation.
 func (client *LDClient) Float64Variation(key string, user User, defaultVal float64) (float64, error) {
-	detail, err := client.variation(key, user, ldvalue.Float64(defaultVal), true, false)
+	detail, err := client.variation(key, NewContextFromUser(user), ldvalue.Float64(defaultVal), true, false)
 	return detail.JSONValue.Float64Value(), err
 }
 
 // Float64VariationDetail is the same as Float64Variation, but also returns further information about how
 // the value was calculated. The "reason" data will also be included in analytics events.
 func (client *LDClient) Float64VariationDetail(key string, user User, defaultVal float64) (float64, EvaluationDetail, error) {
-	detail, err := client.variation(key, user, ldvalue.Float64(defaultVal), true, true)
+	detail, err := client.variation(key, NewContextFromUser(user), ldvalue.Float64(defaultVal), true, true)
 	return detail.JSONValue.Float64Value(), detail, err
 }
 
@@ -445,14 +464,14 @@ func (client *LDClient) Float64VariationDetail(key string, user User, defaultVal
 // Returns defaultVal if there is an error, if the flag doesn't exist, or the feature is turned off and has
 // no off variation.
 func (client *LDClient) StringVariation(key string, user User, defaultVal string) (string, error) {
-	detail, err := client.variation(key, user, ldvalue.String(defaultVal), true, false)
+	detail, err := client.variation(key, NewContextFromUser(user), ldvalue.String(defaultVal), true, false)
 	return detail.JSONValue.StringValue(), err
 }
 
 // StringVariationDetail is the same as StringVariation, but also returns further information about how
 // the value was calculated. The "reason" data will also be included in analytics events.
 func (client *LDClient) StringVariationDetail(key string, user User, defaultVal string) (string, EvaluationDetail, error) {
-	detail, err := client.variation(key, user, ldvalue.String(defaultVal), true, true)
+	detail, err := client.variation(key, NewContextFromUser(user), ldvalue.String(defaultVal), true, true)
 	return detail.JSONValue.StringValue(), detail, err
 }
 
@@ -460,7 +479,7 @@ func (client *LDClient) StringVariationDetail(key string, user User, defaultVal
 //
 // Deprecated: See JSONVariation.
 func (client *LDClient) JsonVariation(key string, user User, defaultVal json.RawMessage) (json.RawMessage, error) {
-	detail, err := client.variation(key, user, ldvalue.Raw(defaultVal), false, false)
+	detail, err := client.variation(key, NewContextFromUser(user), ldvalue.Raw(defaultVal), false, false)
 	return detail.JSONValue.AsRaw(), err
 }
 
@@ -468,7 +487,7 @@ func (client *LDClient) JsonVariation(key string, user User, defaultVal json.Raw
 //
 // Deprecated: See JSONVariationDetail.
 func (client *LDClient) JsonVariationDetail(key string, user User, defaultVal json.RawMessage) (json.RawMessage, EvaluationDetail, error) {
-	detail, err := client.variation(key, user, ldvalue.Raw(defaultVal), false, true)
+	detail, err := client.variation(key, NewContextFromUser(user), ldvalue.Raw(defaultVal), false, true)
 	return detail.JSONValue.AsRaw(), detail, err
 }
 
@@ -494,23 +513,99 @@ func (client *LDClient) JsonVariationDetail(key string, user User, defaultVal js
 //
 // Returns defaultVal if there is an error, if the flag doesn't exist, or the feature is turned off.
 func (client *LDClient) JSONVariation(key string, user User, defaultVal ldvalue.Value) (ldvalue.Value, error) {
-	detail, err := client.variation(key, user, defaultVal, false, false)
+	detail, err := client.variation(key, NewContextFromUser(user), defaultVal, false, false)
 	return detail.JSONValue, err
 }
 
 // JSONVariationDetail is the same as JSONVariation, but also returns further information about how
 // the value was calculated. The "reason" data will also be included in analytics events.
 func (client *LDClient) JSONVariationDetail(key string, user User, defaultVal ldvalue.Value) (ldvalue.Value, EvaluationDetail, error) {
-	detail, err := client.variation(key, user, defaultVal, false, true)
+	detail, err := client.variation(key, NewContextFromUser(user), defaultVal, false, true)
 	return detail.JSONValue, detail, err
 }
 
-// Generic method for evaluating a feature flag for a given user.
-func (client *LDClient) variation(key string, user User, defaultVal ldvalue.Value, checkType bool, sendReasonsInEvents bool) (EvaluationDetail, error) {
-	if client.IsOffline() {
-		return NewEvaluationError(defaultVal, EvalErrorClientNotReady), nil
+// BoolVariationForContext is the same as BoolVariation, but evaluates the flag for an evaluation context
+// that may contain entities of several kinds. See Context.
+func (client *LDClient) BoolVariationForContext(key string, context Context, defaultVal bool) (bool, error) {
+	detail, err := client.variation(key, context, ldvalue.Bool(defaultVal), true, false)
+	return detail.JSONValue.BoolValue(), err
+}
+
+// BoolVariationDetailForContext is the same as BoolVariationDetail, but evaluates the flag for an
+// evaluation context. See Context.
+func (client *LDClient) BoolVariationDetailForContext(key string, context Context, defaultVal bool) (bool, EvaluationDetail, error) {
+	detail, err := client.variation(key, context, ldvalue.Bool(defaultVal), true, true)
+	return detail.JSONValue.BoolValue(), detail, err
+}
+
+// IntVariationForContext is the same as IntVariation, but evaluates the flag for an evaluation context.
+// See Context.
+func (client *LDClient) IntVariationForContext(key string, context Context, defaultVal int) (int, error) {
+	detail, err := client.variation(key, context, ldvalue.Int(defaultVal), true, false)
+	return detail.JSONValue.IntValue(), err
+}
+
+// IntVariationDetailForContext is the same as IntVariationDetail, but evaluates the flag for an
+// evaluation context. See Context.
+func (client *LDClient) IntVariationDetailForContext(key string, context Context, defaultVal int) (int, EvaluationDetail, error) {
+	detail, err := client.variation(key, context, ldvalue.Int(defaultVal), true, true)
+	return detail.JSONValue.IntValue(), detail, err
+}
+
+// Float64VariationForContext is the same as Float64Variation, but evaluates the flag for an evaluation
+// context. See Context.
+func (client *LDClient) Float64VariationForContext(key string, context Context, defaultVal float64) (float64, error) {
+	detail, err := client.variation(key, context, ldvalue.Float64(defaultVal), true, false)
+	return detail.JSONValue.Float64Value(), err
+}
+
+// Float64VariationDetailForContext is the same as Float64VariationDetail, but evaluates the flag for an
+// evaluation context. See Context.
+func (client *LDClient) Float64VariationDetailForContext(key string, context Context, defaultVal float64) (float64, EvaluationDetail, error) {
+	detail, err := client.variation(key, context, ldvalue.Float64(defaultVal), true, true)
+	return detail.JSONValue.Float64Value(), detail, err
+}
+
+// StringVariationForContext is the same as StringVariation, but evaluates the flag for an evaluation
+// context. See Context.
+func (client *LDClient) StringVariationForContext(key string, context Context, defaultVal string) (string, error) {
+	detail, err := client.variation(key, context, ldvalue.String(defaultVal), true, false)
+	return detail.JSONValue.StringValue(), err
+}
+
+// StringVariationDetailForContext is the same as StringVariationDetail, but evaluates the flag for an
+// evaluation context. See Context.
+func (client *LDClient) StringVariationDetailForContext(key string, context Context, defaultVal string) (string, EvaluationDetail, error) {
+	detail, err := client.variation(key, context, ldvalue.String(defaultVal), true, true)
+	return detail.JSONValue.StringValue(), detail, err
+}
+
+// JSONVariationForContext is the same as JSONVariation, but evaluates the flag for an evaluation context.
+// See Context.
+func (client *LDClient) JSONVariationForContext(key string, context Context, defaultVal ldvalue.Value) (ldvalue.Value, error) {
+	detail, err := client.variation(key, context, defaultVal, false, false)
+	return detail.JSONValue, err
+}
+
+// JSONVariationDetailForContext is the same as JSONVariationDetail, but evaluates the flag for an
+// evaluation context. See Context.
+func (client *LDClient) JSONVariationDetailForContext(key string, context Context, defaultVal ldvalue.Value) (ldvalue.Value, EvaluationDetail, error) {
+	detail, err := client.variation(key, context, defaultVal, false, true)
+	return detail.JSONValue, detail, err
+}
+
+// Generic method for evaluating a feature flag for a given context.
+func (client *LDClient) variation(key string, context Context, defaultVal ldvalue.Value, checkType bool, sendReasonsInEvents bool) (EvaluationDetail, error) {
+	// Overrides do not depend on the feature store, so they apply even in offline mode.
+	result, overridden := client.evaluateOverride(key, context)
+	var flag *FeatureFlag
+	var err error
+	if !overridden {
+		if client.IsOffline() {
+			return NewEvaluationError(defaultVal, EvalErrorClientNotReady), nil
+		}
+		result, flag, err = client.evaluateFlag(key, context, defaultVal, sendReasonsInEvents)
 	}
-	result, flag, err := client.evaluateInternal(key, user, defaultVal, sendReasonsInEvents)
 	if err != nil {
 		result.Value = defaultVal.UnsafeArbitraryValue() //nolint // allow deprecated usage
 		result.JSONValue = defaultVal
@@ -522,10 +617,12 @@ func (client *LDClient) variation(key string, user User, defaultVal ldvalue.Valu
 	}
 
 	var evt FeatureRequestEvent
-	if flag == nil {
-		evt = newUnknownFlagEvent(key, user, defaultVal, result.Reason, sendReasonsInEvents) //nolint
+	if result.Reason != nil && result.Reason.GetKind() == EvalReasonOverride {
+		evt = newOverrideEvent(key, context.eventUser(), result.JSONValue, defaultVal, result.Reason, sendReasonsInEvents)
+	} else if flag == nil {
+		evt = newUnknownFlagEvent(key, context.eventUser(), defaultVal, result.Reason, sendReasonsInEvents) //nolint
 	} else {
-		evt = newSuccessfulEvalEvent(flag, user, result.VariationIndex, result.JSONValue, defaultVal,
+		evt = newSuccessfulEvalEvent(flag, context.eventUser(), result.VariationIndex, result.JSONValue, defaultVal,
 			result.Reason, sendReasonsInEvents, nil)
 	}
 	client.eventProcessor.SendEvent(evt)
@@ -533,18 +630,96 @@ func (client *LDClient) variation(key string, user User, defaultVal ldvalue.Valu
 	return result, err
 }
 
+// SetFlagOverride adds an override that pins a flag to a specific value for some or all users, for an
+// optional time window. If more than one override for the same flag applies to a user, the one that
+// was added last is used. See FlagOverride.
+func (client *LDClient) SetFlagOverride(override FlagOverride) {
+	client.overrides.add(override)
+}
+
+// ClearFlagOverrides removes all overrides for a flag, including any that were loaded from
+// Config.FlagOverridesFile.
+func (client *LDClient) ClearFlagOverrides(flagKey string) {
+	client.overrides.clear(flagKey)
+}
+
+// ExplainEvaluation evaluates a feature flag for a user in the same way as the Variation methods, and
+// returns a step-by-step description of the evaluation: which prerequisites, targets, rules, and
+// clauses were checked, the attribute values that were tested, segment membership, and the bucket
+// values computed for rollouts. It does not send any analytics events. See EvaluationTrace.
+//
+// If the flag cannot be evaluated, the error is the same one that the Variation methods would return,
+// and the trace's Reason describes the error.
+func (client *LDClient) ExplainEvaluation(key string, user User) (EvaluationTrace, error) {
+	return client.ExplainEvaluationForContext(key, NewContextFromUser(user))
+}
+
+// ExplainEvaluationForContext is the same as ExplainEvaluation, but evaluates the flag for an evaluation
+// context. See Context.
+func (client *LDClient) ExplainEvaluationForContext(key string, context Context) (EvaluationTrace, error) {
+	trace := EvaluationTrace{FlagKey: key}
+	errorTrace := func(errKind EvalErrorKind, err error) (EvaluationTrace, error) {
+		trace.Reason = EvaluationReasonContainer{newEvalReasonError(errKind)}
+		return trace, err
+	}
+
+	if result, ok := client.evaluateOverride(key, context); ok {
+		trace.Value = result.JSONValue
+		trace.Reason = EvaluationReasonContainer{result.Reason}
+		return trace, nil
+	}
+	if client.IsOffline() {
+		return errorTrace(EvalErrorClientNotReady, nil)
+	}
+	if !client.Initialized() && !client.store.Initialized() {
+		return errorTrace(EvalErrorClientNotReady, ErrClientNotInitialized)
+	}
+	data, err := client.store.Get(Features, key)
+	if err != nil {
+		return errorTrace(EvalErrorException, err)
+	}
+	flag, ok := data.(*FeatureFlag)
+	if !ok {
+		return errorTrace(EvalErrorFlagNotFound, fmt.Errorf("unknown feature key: %s", key))
+	}
+	if errKind, err := context.validate(); err != nil {
+		return errorTrace(errKind, fmt.Errorf("%s when evaluating flag: %s", err, key))
+	}
+	return flag.traceEvaluation(context, client.store), nil
+}
+
 // Evaluate returns the value of a feature for a specified user.
 //
 // Deprecated: Use one of the Variation methods (JSONVariation if you do not need a specific type).
 func (client *LDClient) Evaluate(key string, user User, defaultVal interface{}) (interface{}, *int, error) {
-	result, _, err := client.evaluateInternal(key, user, ldvalue.UnsafeUseArbitraryValue(defaultVal), false) //nolint // allow deprecated usage
-	return result.JSONValue.UnsafeArbitraryValue(), result.VariationIndex, err                               //nolint // allow deprecated usage
+	result, _, err := client.evaluateInternal(key, NewContextFromUser(user), ldvalue.UnsafeUseArbitraryValue(defaultVal), false) //nolint // allow deprecated usage
+	return result.JSONValue.UnsafeArbitraryValue(), result.VariationIndex, err                                                   //nolint // allow deprecated usage
 }
 
 // Performs all the steps of evaluation except for sending the feature request event (the main one;
 // events for prerequisites will be sent).
-func (client *LDClient) evaluateInternal(key string, user User, defaultVal ldvalue.Value, sendReasonsInEvents bool) (EvaluationDetail, *FeatureFlag, error) {
-	if user.Key != nil && *user.Key == "" {
+func (client *LDClient) evaluateInternal(key string, context Context, defaultVal ldvalue.Value, sendReasonsInEvents bool) (EvaluationDetail, *FeatureFlag, error) {
+	if result, ok := client.evaluateOverride(key, context); ok {
+		return result, nil, nil
+	}
+	return client.evaluateFlag(key, context, defaultVal, sendReasonsInEvents)
+}
+
+// Returns the result of the flag override that applies to this context, if any. An invalid context,
+// such as a user without a key, is left for evaluateFlag to report.
+func (client *LDClient) evaluateOverride(key string, context Context) (EvaluationDetail, bool) {
+	if _, err := context.validate(); err != nil {
+		return EvaluationDetail{}, false
+	}
+	if override, ok := client.overrides.find(key, context.eventUser(), time.Now()); ok {
+		return NewEvaluationDetail(override.Value, nil, evalReasonOverrideInstance), true
+	}
+	return EvaluationDetail{}, false
+}
+
+// Same as evaluateInternal, but without checking for overrides.
+func (client *LDClient) evaluateFlag(key string, context Context, defaultVal ldvalue.Value, sendReasonsInEvents bool) (EvaluationDetail, *FeatureFlag, error) {
+	if user := context.eventUser(); user.Key != nil && *user.Key == "" {
 		client.config.Loggers.Warnf("User.Key is blank when evaluating flag: %s. Flag evaluation will proceed, but the user will not be stored in LaunchDarkly.", key)
 	}
 
@@ -587,12 +762,12 @@ func (client *LDClient) evaluateInternal(key string, user User, defaultVal ldval
 			fmt.Errorf("unknown feature key: %s. Verify that this feature key exists. Returning default value", key))
 	}
 
-	if user.Key == nil {
-		return evalErrorResult(EvalErrorUserNotSpecified, feature,
-			fmt.Errorf("user.Key cannot be nil when evaluating flag: %s. Returning default value", key))
+	if errKind, err := context.validate(); err != nil {
+		return evalErrorResult(errKind, feature,
+			fmt.Errorf("%s when evaluating flag: %s. Returning default value", err, key))
 	}
 
-	detail, prereqEvents := feature.EvaluateDetail(user, client.store, sendReasonsInEvents)
+	detail, prereqEvents := feature.evaluateDetailForContext(context, client.store, sendReasonsInEvents)
 	if detail.Reason != nil && detail.Reason.GetKind() == EvalReasonError && client.config.LogEvaluationErrors {
 		client.config.Loggers.Warnf("flag evaluation for %s failed with error %s, default value was returned",
 			key, detail.Reason.GetErrorKind())
@@ -1,9 +1,17 @@
 package ldclient
 
 import (
+	"crypto/hmac"
+	"crypto/sha256"
 	"crypto/x509"
+	"encoding/hex"
 	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
 	"net/http/httptest"
+	"strconv"
+	"sync"
 	"testing"
 	"time"
 
@@ -258,3 +266,143 @@ func TestClientUsesCustomTLSConfiguration(t *testing.T) {
 		assert.True(t, value)
 	})
 }
+
+func signRequestWithHMAC(req *http.Request) error {
+	mac := hmac.New(sha256.New, []byte("secret"))
+	mac.Write([]byte(req.Method + " " + req.URL.Path + "\n"))
+	if req.GetBody != nil {
+		body, err := req.GetBody()
+		if err != nil {
+			return err
+		}
+		defer body.Close()
+		if _, err := io.Copy(mac, body); err != nil {
+			return err
+		}
+	}
+	req.Header.Set("X-Signature", hex.EncodeToString(mac.Sum(nil)))
+	return nil
+}
+
+func assertRequestIsSigned(t *testing.T, r httphelpers.HTTPRequestInfo) {
+	mac := hmac.New(sha256.New, []byte("secret"))
+	mac.Write([]byte(r.Request.Method + " " + r.Request.URL.Path + "\n"))
+	mac.Write(r.Body)
+	assert.Equal(t, hex.EncodeToString(mac.Sum(nil)), r.Request.Header.Get("X-Signature"))
+}
+
+func TestClientSendsCustomHeadersAndSignatureForStreamAndEvents(t *testing.T) {
+	eventsHandler, eventRequestsCh := httphelpers.RecordingHandler(ldservices.ServerSideEventsServiceHandler())
+	httphelpers.WithServer(eventsHandler, func(eventsServer *httptest.Server) {
+		data := ldservices.NewServerSDKData().Flags(&alwaysTrueFlag)
+		streamHandler, _ := ldservices.ServerSideStreamingServiceHandler(data, nil)
+		handler, streamRequestsCh := httphelpers.RecordingHandler(streamHandler)
+		httphelpers.WithServer(handler, func(streamServer *httptest.Server) {
+			config := DefaultConfig
+			config.EventsUri = eventsServer.URL
+			config.StreamUri = streamServer.URL
+			config.Loggers = shared.NullLoggers()
+			config.HTTPHeaders = http.Header{"X-Proxy-Token": []string{"abc"}}
+			config.RequestModifier = signRequestWithHMAC
+
+			client, err := MakeCustomClient(testSdkKey, config, time.Second*5)
+			require.NoError(t, err)
+			defer client.Close()
+
+			r := <-streamRequestsCh
+			assert.Equal(t, "/all", r.Request.URL.Path)
+			assert.Equal(t, testSdkKey, r.Request.Header.Get("Authorization"))
+			assert.Equal(t, "abc", r.Request.Header.Get("X-Proxy-Token"))
+			assertRequestIsSigned(t, r)
+
+			d := <-eventRequestsCh
+			assert.Equal(t, "/diagnostic", d.Request.URL.Path)
+			assert.Equal(t, "abc", d.Request.Header.Get("X-Proxy-Token"))
+			assertRequestIsSigned(t, d)
+
+			client.Identify(testUser)
+			client.Flush()
+
+			e := <-eventRequestsCh
+			assert.Equal(t, "/bulk", e.Request.URL.Path)
+			assert.Equal(t, "abc", e.Request.Header.Get("X-Proxy-Token"))
+			assertRequestIsSigned(t, e)
+		})
+	})
+}
+
+func TestClientSendsCustomHeadersAndSignatureForPolling(t *testing.T) {
+	data := ldservices.NewServerSDKData().Flags(&alwaysTrueFlag)
+	pollHandler, requestsCh := httphelpers.RecordingHandler(ldservices.ServerSidePollingServiceHandler(data))
+	httphelpers.WithServer(pollHandler, func(pollServer *httptest.Server) {
+		config := DefaultConfig
+		config.Stream = false
+		config.BaseUri = pollServer.URL
+		config.SendEvents = false
+		config.Loggers = shared.NullLoggers()
+		config.HTTPHeaders = http.Header{"X-Proxy-Token": []string{"abc"}}
+		config.RequestModifier = signRequestWithHMAC
+
+		client, err := MakeCustomClient(testSdkKey, config, time.Second*5)
+		require.NoError(t, err)
+		defer client.Close()
+
+		value, _ := client.BoolVariation(alwaysTrueFlag.Key, testUser, false)
+		assert.True(t, value)
+
+		r := <-requestsCh
+		assert.Equal(t, testSdkKey, r.Request.Header.Get("Authorization"))
+		assert.Equal(t, "abc", r.Request.Header.Get("X-Proxy-Token"))
+		assertRequestIsSigned(t, r)
+	})
+}
+
+func TestRequestModifierIsCalledAgainWhenStreamReconnects(t *testing.T) {
+	data := ldservices.NewServerSDKData().Flags(&alwaysTrueFlag)
+	streamHandler, _ := ldservices.ServerSideStreamingServiceHandler(data, nil)
+	failThenSucceedHandler := httphelpers.SequentialHandler(httphelpers.HandlerWithStatus(503), streamHandler)
+	handler, requestsCh := httphelpers.RecordingHandler(failThenSucceedHandler)
+	httphelpers.WithServer(handler, func(streamServer *httptest.Server) {
+		var lock sync.Mutex
+		count := 0
+		config := DefaultConfig
+		config.StreamUri = streamServer.URL
+		config.SendEvents = false
+		config.Loggers = shared.NullLoggers()
+		config.RequestModifier = func(req *http.Request) error {
+			lock.Lock()
+			count++
+			req.Header.Set("X-Request-Count", strconv.Itoa(count))
+			lock.Unlock()
+			return nil
+		}
+
+		client, err := MakeCustomClient(testSdkKey, config, time.Second*5)
+		require.NoError(t, err)
+		defer client.Close()
+
+		r0 := <-requestsCh
+		assert.Equal(t, "1", r0.Request.Header.Get("X-Request-Count"))
+		r1 := <-requestsCh
+		assert.Equal(t, "2", r1.Request.Header.Get("X-Request-Count"))
+	})
+}
+
+func TestClientDoesNotSendRequestIfRequestModifierFails(t *testing.T) {
+	data := ldservices.NewServerSDKData().Flags(&alwaysTrueFlag)
+	pollHandler, requestsCh := httphelpers.RecordingHandler(ldservices.ServerSidePollingServiceHandler(data))
+	httphelpers.WithServer(pollHandler, func(pollServer *httptest.Server) {
+		config := DefaultConfig
+		config.Stream = false
+		config.BaseUri = pollServer.URL
+		config.SendEvents = false
+		config.Loggers = shared.NullLoggers()
+		config.RequestModifier = func(req *http.Request) error { return errors.New("no signing key") }
+
+		client, err := MakeCustomClient(testSdkKey, config, time.Millisecond*500)
+		require.Error(t, err)
+		defer client.Close()
+
+		assertNoMoreRequests(t, requestsCh)
+	})
+}
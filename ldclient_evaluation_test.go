@@ -537,6 +537,62 @@ func TestEventTrackingAndReasonAreNotForcedForFallthroughIfFlagIsNotSet(t *testi
 	assert.Nil(t, e.Reason.Reason)
 }
 
+func TestEventTrackingAndReasonAreForcedForExperimentRollout(t *testing.T) {
+	flag := FeatureFlag{
+		Key: "flagKey",
+		On:  true,
+		Fallthrough: VariationOrRollout{Rollout: &Rollout{
+			Kind:       RolloutKindExperiment,
+			Variations: []WeightedVariation{{Variation: 1, Weight: 100000}},
+		}},
+		Variations: []interface{}{"off", "on"},
+		Version:    1,
+	}
+
+	client := makeTestClient()
+	defer client.Close()
+	client.store.Upsert(Features, &flag)
+
+	value, err := client.StringVariation("flagKey", evalTestUser, "default")
+	assert.NoError(t, err)
+	assert.Equal(t, "on", value)
+
+	events := client.eventProcessor.(*testEventProcessor).events
+	assert.Equal(t, 1, len(events))
+
+	e := events[0].(FeatureRequestEvent)
+	assert.True(t, e.TrackEvents)
+	assert.Equal(t, reasonWithInExperiment(evalReasonFallthroughInstance), e.Reason.Reason)
+}
+
+func TestEventTrackingAndReasonAreNotForcedForUntrackedExperimentVariation(t *testing.T) {
+	flag := FeatureFlag{
+		Key: "flagKey",
+		On:  true,
+		Fallthrough: VariationOrRollout{Rollout: &Rollout{
+			Kind:       RolloutKindExperiment,
+			Variations: []WeightedVariation{{Variation: 1, Weight: 100000, Untracked: true}},
+		}},
+		Variations: []interface{}{"off", "on"},
+		Version:    1,
+	}
+
+	client := makeTestClient()
+	defer client.Close()
+	client.store.Upsert(Features, &flag)
+
+	value, err := client.StringVariation("flagKey", evalTestUser, "default")
+	assert.NoError(t, err)
+	assert.Equal(t, "on", value)
+
+	events := client.eventProcessor.(*testEventProcessor).events
+	assert.Equal(t, 1, len(events))
+
+	e := events[0].(FeatureRequestEvent)
+	assert.False(t, e.TrackEvents)
+	assert.Nil(t, e.Reason.Reason)
+}
+
 func TestEventTrackingAndReasonAreNotForcedForFallthroughIfReasonIsNotFallthrough(t *testing.T) {
 	flag := FeatureFlag{
 		Key:                    "flagKey",
@@ -618,6 +674,53 @@ func TestEvaluatingFlagWithNilUserKeySendsEvent(t *testing.T) {
 	assert.Equal(t, expectedEvent, e)
 }
 
+func TestStringVariationForMultiKindContext(t *testing.T) {
+	flag := makeTestFlag("flagKey", 0, "a", "b")
+	flag.Targets = []Target{{Values: []string{"org-key"}, Variation: 1, ContextKind: "org"}}
+	client := makeTestClient()
+	defer client.Close()
+	client.store.Upsert(Features, flag)
+
+	context := NewMultiContext(NewContextFromUser(evalTestUser), NewContext("org", "org-key"))
+	actual, detail, err := client.StringVariationDetailForContext(flag.Key, context, "x")
+
+	assert.NoError(t, err)
+	assert.Equal(t, "b", actual)
+	assert.Equal(t, evalReasonTargetMatchInstance, detail.Reason)
+
+	assertEvalEvent(t, client, flag, evalTestUser, ldvalue.String("b"), 1, ldvalue.String("x"), detail.Reason)
+}
+
+func TestEvaluatingFlagForContextWithoutUserSendsEventWithoutUser(t *testing.T) {
+	flag := makeTestFlag("flagKey", 0, "a", "b")
+	flag.Targets = []Target{{Values: []string{"org-key"}, Variation: 1, ContextKind: "org"}}
+	client := makeTestClient()
+	defer client.Close()
+	client.store.Upsert(Features, flag)
+
+	actual, detail, err := client.StringVariationDetailForContext(flag.Key, NewContext("org", "org-key"), "x")
+
+	assert.NoError(t, err)
+	assert.Equal(t, "b", actual)
+	assertEvalEvent(t, client, flag, User{}, ldvalue.String("b"), 1, ldvalue.String("x"), detail.Reason)
+}
+
+func TestEvaluatingFlagForContextWithNilKeyReturnsDefault(t *testing.T) {
+	flag := makeTestFlag("flagKey", 1, "a", "b")
+	client := makeTestClient()
+	defer client.Close()
+	client.store.Upsert(Features, flag)
+
+	context := NewMultiContext(NewContextFromUser(evalTestUser), NewContextWithAttributes("org", User{}))
+	actual, detail, err := client.StringVariationDetailForContext(flag.Key, context, "x")
+
+	if assert.Error(t, err) {
+		assert.Contains(t, err.Error(), `key of context kind "org" cannot be nil`)
+	}
+	assert.Equal(t, "x", actual)
+	assert.Equal(t, newEvalReasonError(EvalErrorUserNotSpecified), detail.Reason)
+}
+
 func TestEvaluatingFlagWithPrerequisiteSendsPrerequisiteEvent(t *testing.T) {
 	client := makeTestClient()
 	defer client.Close()
@@ -234,6 +234,7 @@ func newConsulFeatureStoreInternal(configuredOptions featureStoreOptions, ldConf
 	}
 	store.loggers.SetBaseLogger(configuredOptions.logger) // has no effect if it is nil
 	store.loggers.SetPrefix("ConsulFeatureStore:")
+	store.loggers = store.loggers.ForComponent(ldlog.ComponentStore)
 
 	if store.options.prefix == "" {
 		store.options.prefix = DefaultPrefix
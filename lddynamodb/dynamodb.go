@@ -311,6 +311,7 @@ func newDynamoDBFeatureStoreInternal(configuredOptions featureStoreOptions, ldCo
 	}
 	store.loggers.SetBaseLogger(configuredOptions.logger) // has no effect if it is nil
 	store.loggers.SetPrefix("DynamoDBFeatureStore:")
+	store.loggers = store.loggers.ForComponent(ldlog.ComponentStore)
 	store.loggers.Infof(`Using DynamoDB table %s`, configuredOptions.table)
 
 	if store.client == nil {
@@ -438,7 +439,8 @@ func (store *dynamoDBFeatureStore) GetInternal(kind ld.VersionedDataKind, key st
 	}
 
 	if len(result.Item) == 0 {
-		store.loggers.Debugf("Item not found (key=%s)", key)
+		store.loggers.Debugw("Item not found", ldlog.NewField(ldlog.FieldKind, kind.GetNamespace()),
+			ldlog.NewField(ldlog.FieldKey, key))
 		return nil, nil
 	}
 
@@ -479,8 +481,8 @@ func (store *dynamoDBFeatureStore) UpsertInternal(kind ld.VersionedDataKind, ite
 	})
 	if err != nil {
 		if aerr, ok := err.(awserr.Error); ok && aerr.Code() == dynamodb.ErrCodeConditionalCheckFailedException {
-			store.loggers.Debugf("Not updating item due to condition (namespace=%s key=%s version=%d)",
-				kind.GetNamespace(), item.GetKey(), item.GetVersion())
+			store.loggers.Debugw("Not updating item due to condition", ldlog.NewField(ldlog.FieldKind, kind.GetNamespace()),
+				ldlog.NewField(ldlog.FieldKey, item.GetKey()), ldlog.NewField(ldlog.FieldVersion, item.GetVersion()))
 			// We must now read the item that's in the database and return it, so FeatureStoreWrapper can cache it
 			oldItem, err := store.GetInternal(kind, item.GetKey())
 			return oldItem, err
@@ -0,0 +1,162 @@
+package ldfiledata
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+
+	ld "gopkg.in/launchdarkly/go-server-sdk.v4"
+)
+
+// itemUpdate is a change to a single flag or segment that is to be applied to the feature store.
+type itemUpdate struct {
+	kind    ld.VersionedDataKind
+	key     string
+	item    ld.VersionedData // nil if the item is to be deleted
+	version int
+}
+
+// dataChanges describes the differences between two sets of flags and segments.
+type dataChanges struct {
+	updates []itemUpdate
+	added   map[ld.VersionedDataKind][]string
+	changed map[ld.VersionedDataKind][]string
+	removed map[ld.VersionedDataKind][]string
+}
+
+// loadedData is the data that the file data source has most recently put in the feature store, along
+// with the version that it used for each key, including keys that have since been deleted.
+type loadedData struct {
+	items    map[ld.VersionedDataKind]map[string]ld.VersionedData
+	versions map[ld.VersionedDataKind]map[string]int
+}
+
+// Segments are updated before flags, and flags are deleted before segments, so that a flag in the
+// store never refers to a segment that has not been stored yet.
+var kindsInUpdateOrder = []ld.VersionedDataKind{ld.Segments, ld.Features} //nolint:megacheck // allow deprecated usage
+
+func newLoadedData(allData map[ld.VersionedDataKind]map[string]ld.VersionedData) *loadedData {
+	loaded := &loadedData{items: allData, versions: make(map[ld.VersionedDataKind]map[string]int)}
+	for kind, items := range allData {
+		loaded.versions[kind] = make(map[string]int, len(items))
+		for key, item := range items {
+			setKeyAndVersion(item, key, item.GetVersion())
+			loaded.versions[kind][key] = item.GetVersion()
+		}
+	}
+	return loaded
+}
+
+// computeChanges compares newly loaded data to the data that was previously stored. Items that are new
+// or different are given a version higher than any version previously used for the same key, so that
+// the feature store will accept them as updates. It then records the new data as the current data.
+func (l *loadedData) computeChanges(allData map[ld.VersionedDataKind]map[string]ld.VersionedData) dataChanges {
+	changes := dataChanges{
+		added:   make(map[ld.VersionedDataKind][]string),
+		changed: make(map[ld.VersionedDataKind][]string),
+		removed: make(map[ld.VersionedDataKind][]string),
+	}
+	var deletes []itemUpdate
+	for _, kind := range kindsInUpdateOrder {
+		oldItems, newItems := l.items[kind], allData[kind]
+		if l.versions[kind] == nil {
+			l.versions[kind] = make(map[string]int)
+		}
+		for _, key := range sortedKeys(newItems) {
+			item := newItems[key]
+			oldItem, exists := oldItems[key]
+			if exists && sameItemIgnoringVersion(oldItem, item) {
+				setKeyAndVersion(item, key, oldItem.GetVersion())
+				continue
+			}
+			version := item.GetVersion()
+			if lastVersion, found := l.versions[kind][key]; found && version <= lastVersion {
+				version = lastVersion + 1
+			}
+			setKeyAndVersion(item, key, version)
+			l.versions[kind][key] = version
+			changes.updates = append(changes.updates, itemUpdate{kind: kind, key: key, item: item, version: version})
+			if exists {
+				changes.changed[kind] = append(changes.changed[kind], key)
+			} else {
+				changes.added[kind] = append(changes.added[kind], key)
+			}
+		}
+		for _, key := range sortedKeys(oldItems) {
+			if _, exists := newItems[key]; !exists {
+				version := l.versions[kind][key] + 1
+				l.versions[kind][key] = version
+				deletes = append([]itemUpdate{{kind: kind, key: key, version: version}}, deletes...) // reverse kind order
+				changes.removed[kind] = append(changes.removed[kind], key)
+			}
+		}
+	}
+	changes.updates = append(changes.updates, deletes...)
+	l.items = allData
+	return changes
+}
+
+// apply makes the changes in the feature store.
+func (c dataChanges) apply(store ld.FeatureStore) error {
+	for _, u := range c.updates {
+		var err error
+		if u.item == nil {
+			err = store.Delete(u.kind, u.key, u.version)
+		} else {
+			err = store.Upsert(u.kind, u.item)
+		}
+		if err != nil {
+			return fmt.Errorf("unable to update %s '%s': %s", u.kind.GetNamespace(), u.key, err)
+		}
+	}
+	return nil
+}
+
+// String returns a summary of the changes, such as "added features [a b]; removed segments [c]".
+func (c dataChanges) String() string {
+	var parts []string
+	for _, kind := range []ld.VersionedDataKind{ld.Features, ld.Segments} { //nolint:megacheck // allow deprecated usage
+		for _, group := range []struct {
+			desc string
+			keys []string
+		}{{"added", c.added[kind]}, {"changed", c.changed[kind]}, {"removed", c.removed[kind]}} {
+			if len(group.keys) > 0 {
+				parts = append(parts, fmt.Sprintf("%s %s %v", group.desc, kind.GetNamespace(), group.keys))
+			}
+		}
+	}
+	if len(parts) == 0 {
+		return "no changes"
+	}
+	return strings.Join(parts, "; ")
+}
+
+func sortedKeys(items map[string]ld.VersionedData) []string {
+	keys := make([]string, 0, len(items))
+	for key := range items {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// setKeyAndVersion fills in the key of an item, which is often omitted in files since it is the same
+// as the map key, and sets its version.
+func setKeyAndVersion(item ld.VersionedData, key string, version int) {
+	switch i := item.(type) {
+	case *ld.FeatureFlag: //nolint:megacheck // allow deprecated usage
+		i.Key, i.Version = key, version
+	case *ld.Segment: //nolint:megacheck // allow deprecated usage
+		i.Key, i.Version = key, version
+	}
+}
+
+func sameItemIgnoringVersion(oldItem, newItem ld.VersionedData) bool {
+	newVersion := newItem.GetVersion()
+	setKeyAndVersion(newItem, oldItem.GetKey(), oldItem.GetVersion())
+	oldJSON, oldErr := json.Marshal(oldItem)
+	newJSON, newErr := json.Marshal(newItem)
+	setKeyAndVersion(newItem, oldItem.GetKey(), newVersion)
+	return oldErr == nil && newErr == nil && string(oldJSON) == string(newJSON)
+}
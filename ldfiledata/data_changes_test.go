@@ -0,0 +1,107 @@
+package ldfiledata
+
+import (
+	"io/ioutil"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	ld "gopkg.in/launchdarkly/go-server-sdk.v4"
+	"gopkg.in/launchdarkly/go-server-sdk.v4/ldlog"
+	shared "gopkg.in/launchdarkly/go-server-sdk.v4/shared_test"
+)
+
+type recordingStore struct {
+	ld.FeatureStore
+	initCount int
+	upserts   []string
+	deletes   []string
+}
+
+func (s *recordingStore) Init(allData map[ld.VersionedDataKind]map[string]ld.VersionedData) error {
+	s.initCount++
+	return s.FeatureStore.Init(allData)
+}
+
+func (s *recordingStore) Upsert(kind ld.VersionedDataKind, item ld.VersionedData) error {
+	s.upserts = append(s.upserts, kind.GetNamespace()+"/"+item.GetKey())
+	return s.FeatureStore.Upsert(kind, item)
+}
+
+func (s *recordingStore) Delete(kind ld.VersionedDataKind, key string, version int) error {
+	s.deletes = append(s.deletes, kind.GetNamespace()+"/"+key)
+	return s.FeatureStore.Delete(kind, key, version)
+}
+
+func TestReloadUpdatesOnlyChangedItems(t *testing.T) {
+	filename := makeTempFile(t, `{
+  "flagValues": {"same": 1, "changed": "a", "removed": true},
+  "segments": {"seg1": {"included": ["x"]}, "seg2": {"version": 5}}
+}`)
+	defer os.Remove(filename)
+
+	store := &recordingStore{FeatureStore: ld.NewInMemoryFeatureStore(nil)}
+	mockLog := shared.NewMockLoggers()
+	fs, err := newFileDataSource(ld.Config{FeatureStore: store, Loggers: mockLog.Loggers}, FilePaths(filename))
+	require.NoError(t, err)
+	fs.reload()
+	assert.Equal(t, 1, store.initCount)
+	assert.Contains(t, mockLog.Output[ldlog.Info], "FileDataSource: Loaded 3 flags and 2 segments")
+
+	require.NoError(t, ioutil.WriteFile(filename, []byte(`{
+  "flagValues": {"same": 1, "changed": "b", "added": 2},
+  "segments": {"seg1": {"included": ["y"]}}
+}`), 0600))
+	fs.reload()
+
+	assert.Equal(t, 1, store.initCount)
+	assert.Equal(t, []string{"segments/seg1", "features/added", "features/changed"}, store.upserts)
+	assert.Equal(t, []string{"features/removed", "segments/seg2"}, store.deletes)
+	assert.Contains(t, mockLog.Output[ldlog.Info], "FileDataSource: Reloaded data: added features [added]; "+
+		"changed features [changed]; removed features [removed]; changed segments [seg1]; removed segments [seg2]")
+
+	changed, err := store.Get(ld.Features, "changed")
+	require.NoError(t, err)
+	assert.Equal(t, "b", changed.(*ld.FeatureFlag).Variations[0])
+	assert.Equal(t, 1, changed.GetVersion())
+	removed, err := store.Get(ld.Segments, "seg2")
+	require.NoError(t, err)
+	assert.Nil(t, removed)
+}
+
+func TestReloadWithNoChangesDoesNotUpdateStore(t *testing.T) {
+	filename := makeTempFile(t, `{"flagValues": {"flag": 1}}`)
+	defer os.Remove(filename)
+
+	store := &recordingStore{FeatureStore: ld.NewInMemoryFeatureStore(nil)}
+	fs, err := newFileDataSource(ld.Config{FeatureStore: store, Loggers: shared.NullLoggers()}, FilePaths(filename))
+	require.NoError(t, err)
+	fs.reload()
+	fs.reload()
+
+	assert.Equal(t, 1, store.initCount)
+	assert.Empty(t, store.upserts)
+	assert.Empty(t, store.deletes)
+}
+
+func TestReloadUsesHigherVersionForItemThatWasDeletedAndAdded(t *testing.T) {
+	filename := makeTempFile(t, `{"flagValues": {"flag": 1}}`)
+	defer os.Remove(filename)
+
+	store := ld.NewInMemoryFeatureStore(nil)
+	fs, err := newFileDataSource(ld.Config{FeatureStore: store, Loggers: shared.NullLoggers()}, FilePaths(filename))
+	require.NoError(t, err)
+	fs.reload()
+	require.NoError(t, ioutil.WriteFile(filename, []byte(`{}`), 0600))
+	fs.reload()
+	require.NoError(t, ioutil.WriteFile(filename, []byte(`{"flagValues": {"flag": 1}}`), 0600))
+	fs.reload()
+
+	flag, err := store.Get(ld.Features, "flag")
+	require.NoError(t, err)
+	require.NotNil(t, flag)
+	assert.Equal(t, float64(1), flag.(*ld.FeatureFlag).Variations[0])
+	assert.Equal(t, 2, flag.GetVersion())
+}
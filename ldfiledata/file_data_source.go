@@ -5,9 +5,11 @@ import (
 	"encoding/json"
 	"fmt"
 	"io/ioutil"
+	"net/http"
 	"path/filepath"
 	"strings"
 	"sync"
+	"time"
 	"unicode"
 
 	"gopkg.in/ghodss/yaml.v1"
@@ -18,8 +20,12 @@ import (
 
 type fileDataSourceOptions struct {
 	absFilePaths    []string
+	inputs          []dataSourceInput
 	reloaderFactory ReloaderFactory
 	logger          ld.Logger
+	layeredMerge    bool
+	urlPollInterval time.Duration
+	httpClient      *http.Client
 }
 
 // FileDataSourceOption is the interface for optional configuration parameters that can be
@@ -38,6 +44,9 @@ func (o filePathsOption) apply(opts *fileDataSourceOptions) error {
 		return err
 	}
 	opts.absFilePaths = append(opts.absFilePaths, abs...)
+	for _, p := range abs {
+		opts.inputs = append(opts.inputs, &pathInput{path: p})
+	}
 	return nil
 }
 
@@ -64,7 +73,8 @@ func UseLogger(logger ld.Logger) FileDataSourceOption {
 }
 
 // ReloaderFactory is a function type used with UseReloader, to specify a mechanism for detecting when
-// data files should be reloaded. Its standard implementation is in the ldfilewatch package.
+// data files should be reloaded. Its standard implementations, WatchFiles and PollFiles, are in the
+// ldfilewatch package.
 type ReloaderFactory func(paths []string, loggers ld.Logger, reload func(), closeCh <-chan struct{}) error
 
 type reloaderOption struct {
@@ -80,19 +90,49 @@ func (o reloaderOption) apply(opts *fileDataSourceOptions) error {
 // data files. It is normally used with the ldfilewatch package, as follows:
 //
 //     ldfiledata.UseReloader(ldfilewatch.WatchFiles)
+//
+// When the files are reloaded, only the flags and segments that were added, changed, or removed are
+// updated in the feature store, with higher version numbers than before, and the changes are logged.
 func UseReloader(reloaderFactory ReloaderFactory) FileDataSourceOption {
 	return reloaderOption{reloaderFactory}
 }
 
+type layeredMergeOption struct{}
+
+func (o layeredMergeOption) apply(opts *fileDataSourceOptions) error {
+	opts.layeredMerge = true
+	return nil
+}
+
+// UseLayeredMerge creates an option for NewFileDataSourceFactory, to allow a set of files to be
+// layered on top of each other, such as a base file plus a file of overrides for each environment:
+//
+//     ldfiledata.FilePaths("./flags/base.yaml", "./flags/production.yaml"),
+//     ldfiledata.UseLayeredMerge()
+//
+// In this mode, a flag or segment key may appear in more than one file, and the file that is listed
+// last takes precedence; this also applies to the other kinds of input, such as URLs, in the order in
+// which their options were specified. Also, an error in one file does not prevent the others from
+// being loaded: if a file is missing or malformed, the data source logs an error for that file and
+// keeps using the last data that it successfully loaded from it, or ignores the file if it has never
+// been loaded successfully.
+func UseLayeredMerge() FileDataSourceOption {
+	return layeredMergeOption{}
+}
+
 type fileDataSource struct {
-	store           ld.FeatureStore
-	options         fileDataSourceOptions
-	loggers         ldlog.Loggers
-	isInitialized   bool
-	readyCh         chan<- struct{}
-	readyOnce       sync.Once
-	closeOnce       sync.Once
-	closeReloaderCh chan struct{}
+	store         ld.FeatureStore
+	options       fileDataSourceOptions
+	loggers       ldlog.Loggers
+	rejectInvalid bool
+	isInitialized bool
+	readyCh       chan<- struct{}
+	readyOnce     sync.Once
+	closeOnce     sync.Once
+	closeCh       chan struct{}
+	reloadLock    sync.Mutex
+	lastGoodData  map[dataSourceInput]fileData
+	loaded        *loadedData
 }
 
 // NewFileDataSourceFactory returns a function that allows the LaunchDarkly client to read feature
@@ -105,18 +145,21 @@ type fileDataSource struct {
 //     ldConfig.UpdateProcessorFactory = fileSource
 //     ldClient := ld.MakeCustomClient(mySdkKey, ldConfig, 5*time.Second)
 //
-// Use FilePaths to specify any number of file paths. The files are not actually loaded until the
-// client starts up. At that point, if any file does not exist or cannot be parsed, the FileDataSource
-// will log an error and will not load any data.
+// Use FilePaths to specify any number of file paths. Data in the same format can also be read from
+// URLs, from memory, or from environment variables; see URLs, Data, Reader, and EnvironmentVariables.
+// The files are not actually loaded until the client starts up. At that point, if any file does not
+// exist or cannot be parsed, the FileDataSource will log an error and will not load any data.
 //
 // Files may contain either JSON or YAML; if the first non-whitespace character is '{', the file is parsed
-// as JSON, otherwise it is parsed as YAML. The file data should consist of an object with up to three
+// as JSON, otherwise it is parsed as YAML. The file data should consist of an object with up to four
 // properties:
 //
 // - "flags": Feature flag definitions.
 //
 // - "flagValues": Simplified feature flags that contain only a value.
 //
+// - "simpleFlags": Feature flags with targeting, in a simplified format.
+//
 // - "segments": User segment definitions.
 //
 // The format of the data in "flags" and "segments" is defined by the LaunchDarkly application and is
@@ -163,12 +206,40 @@ type fileDataSource struct {
 //       my-boolean-flag-key: true
 //       my-integer-flag-key: 3
 //
-// It is also possible to specify both "flags" and "flagValues", if you want some flags to have simple
+// Between these two extremes, "simpleFlags" allows you to describe individual targets, rules that test a
+// single user attribute, percentage rollouts, and whether the flag is on, referring to variations by
+// their values instead of by index:
+//
+//     simpleFlags:
+//       my-flag-key:
+//         "on": true                       # the default is true; YAML requires quotes around "on"
+//         variations: [red, green, blue]   # if omitted, all of the values used below, in order
+//         offValue: red                    # if omitted, the application's default value is served when off
+//         targets:
+//           - values: [user-key-1, user-key-2]
+//             value: blue
+//         rules:
+//           - attribute: country           # "op" can be any clause operator; the default is "in"
+//             values: [US, CA]
+//             value: green
+//           - attribute: email
+//             op: endsWith
+//             values: ["@example.com"]
+//             rollout:                     # a percentage rollout can be used instead of a value
+//               - {value: green, percent: 50}
+//               - {value: blue, percent: 50}
+//         value: red                       # served if no target or rule matches; or use "rollout"
+//
+// It is also possible to use more than one of these properties, if you want some flags to have simple
 // values and others to have complex behavior. However, it is an error to use the same flag key or
-// segment key more than once, either in a single file or across multiple files.
+// segment key more than once, either in a single file or across multiple files, unless you use
+// UseLayeredMerge.
 //
 // If the data source encounters any error in any file-- malformed content, a missing file, or a
-// duplicate key-- it will not load flags from any of the files.
+// duplicate key-- it will not load flags from any of the files, unless you use UseLayeredMerge.
+//
+// Flags and segments that are syntactically valid but fail the checks of ld.ValidateData are logged
+// as warnings, and are discarded if Config.RejectInvalidData is true.
 func NewFileDataSourceFactory(options ...FileDataSourceOption) ld.UpdateProcessorFactory {
 	return func(sdkKey string, config ld.Config) (ld.UpdateProcessor, error) {
 		return newFileDataSource(config, options...)
@@ -180,17 +251,28 @@ func newFileDataSource(ldConfig ld.Config, options ...FileDataSourceOption) (*fi
 		return nil, fmt.Errorf("featureStore must not be nil")
 	}
 	fs := &fileDataSource{
-		store:   ldConfig.FeatureStore,
-		loggers: ldConfig.Loggers,
+		store:         ldConfig.FeatureStore,
+		loggers:       ldConfig.Loggers,
+		rejectInvalid: ldConfig.RejectInvalidData,
+		closeCh:       make(chan struct{}),
+		lastGoodData:  make(map[dataSourceInput]fileData),
 	}
+	fs.options.urlPollInterval = DefaultURLPollInterval
 	for _, o := range options {
 		err := o.apply(&fs.options)
 		if err != nil {
 			return nil, err
 		}
 	}
+	if fs.options.httpClient == nil && len(fs.urlInputs()) > 0 {
+		fs.options.httpClient = newDefaultHTTPClient(ldConfig)
+	}
+	for _, u := range fs.urlInputs() {
+		u.client = fs.options.httpClient
+	}
 	fs.loggers.SetBaseLogger(fs.options.logger) // has no effect if it is nil
 	fs.loggers.SetPrefix("FileDataSource:")
+	fs.loggers = fs.loggers.ForComponent(ldlog.ComponentFileData)
 	return fs, nil
 }
 
@@ -204,40 +286,63 @@ func (fs *fileDataSource) Start(closeWhenReady chan<- struct{}) {
 	fs.readyCh = closeWhenReady
 	fs.reload()
 
-	// If there is no reloader, then we signal readiness immediately regardless of whether the
-	// data load succeeded or failed.
-	if fs.options.reloaderFactory == nil {
+	urls := fs.urlInputs()
+	pollURLs := len(urls) > 0 && fs.options.urlPollInterval > 0
+
+	// If there is no reloader or URL polling, then we signal readiness immediately regardless of
+	// whether the data load succeeded or failed.
+	if fs.options.reloaderFactory == nil && !pollURLs {
 		fs.signalStartComplete(fs.isInitialized)
 		return
 	}
 
-	// If there is a reloader, and if we haven't yet successfully loaded data, then the
-	// readiness signal will happen the first time we do get valid data (in reload).
-	fs.closeReloaderCh = make(chan struct{})
-	err := fs.options.reloaderFactory(fs.options.absFilePaths, fs.loggers.ForLevel(ldlog.Error),
-		fs.reload, fs.closeReloaderCh)
-	if err != nil {
-		fs.loggers.Errorf("Unable to start reloader: %s\n", err)
+	// Otherwise, if we haven't yet successfully loaded data, then the readiness signal will happen
+	// the first time we do get valid data (in reload).
+	if pollURLs {
+		go fs.pollURLs(urls, fs.options.urlPollInterval, fs.closeCh)
+	}
+	if fs.options.reloaderFactory != nil {
+		err := fs.options.reloaderFactory(fs.options.absFilePaths, fs.loggers.ForLevel(ldlog.Error),
+			fs.reload, fs.closeCh)
+		if err != nil {
+			fs.loggers.Errorf("Unable to start reloader: %s\n", err)
+		}
 	}
 }
 
 // Reload tells the data source to immediately attempt to reread all of the configured source files
 // and update the feature flag state. If any file cannot be loaded or parsed, the flag state will not
-// be modified.
+// be modified, unless layered merging is enabled. Only the flags and segments that have changed are
+// updated in the store; see updateStore.
 func (fs *fileDataSource) reload() {
+	fs.reloadLock.Lock()
+	defer fs.reloadLock.Unlock()
 	filesData := make([]fileData, 0)
-	for _, path := range fs.options.absFilePaths {
-		data, err := readFile(path)
-		if err == nil {
-			filesData = append(filesData, data)
-		} else {
-			fs.loggers.Errorf("Unable to load flags: %s [%s]", err, path)
+	for _, input := range fs.options.inputs {
+		data, err := input.read()
+		switch {
+		case err == nil:
+			fs.lastGoodData[input] = data
+		case !fs.options.layeredMerge:
+			fs.loggers.Errorf("Unable to load flags: %s [%s]", err, input.name())
 			return
+		default:
+			lastData, ok := fs.lastGoodData[input]
+			if !ok {
+				fs.loggers.Errorf("Unable to load flags: %s [%s]; ignoring it", err, input.name())
+				continue
+			}
+			fs.loggers.Errorf("Unable to load flags: %s [%s]; using the last data loaded from it", err, input.name())
+			data = lastData
 		}
+		filesData = append(filesData, data)
 	}
-	storeData, err := mergeFileData(filesData...)
+	if len(filesData) == 0 && len(fs.options.inputs) > 0 {
+		return
+	}
+	storeData, err := mergeFileData(fs.options.layeredMerge, filesData...)
 	if err == nil {
-		err = fs.store.Init(storeData)
+		err = fs.updateStore(ld.FilterInvalidData(storeData, fs.loggers, fs.rejectInvalid))
 		fs.signalStartComplete(true)
 	}
 	if err != nil {
@@ -245,6 +350,34 @@ func (fs *fileDataSource) reload() {
 	}
 }
 
+// updateStore puts the data in the feature store. The first time, it replaces the contents of the store
+// with Init; after that, it only stores the flags and segments that were added, changed, or removed
+// since the last time, so that a persistent store is not rewritten and so that the changes can be
+// logged.
+func (fs *fileDataSource) updateStore(storeData map[ld.VersionedDataKind]map[string]ld.VersionedData) error {
+	if fs.loaded == nil {
+		loaded := newLoadedData(storeData)
+		if err := fs.store.Init(storeData); err != nil {
+			return err
+		}
+		fs.loaded = loaded
+		fs.loggers.Infof("Loaded %d flags and %d segments", len(storeData[ld.Features]), //nolint:megacheck // allow deprecated usage
+			len(storeData[ld.Segments])) //nolint:megacheck // allow deprecated usage
+		return nil
+	}
+	changes := fs.loaded.computeChanges(storeData)
+	if err := changes.apply(fs.store); err != nil {
+		fs.loaded = nil // we don't know what state the store is in, so the next reload will use Init
+		return err
+	}
+	if len(changes.updates) == 0 {
+		fs.loggers.Debug("Reloaded data: no changes")
+	} else {
+		fs.loggers.Infof("Reloaded data: %s", changes)
+	}
+	return nil
+}
+
 func (fs *fileDataSource) signalStartComplete(succeeded bool) {
 	fs.readyOnce.Do(func() {
 		fs.isInitialized = succeeded
@@ -267,14 +400,19 @@ func absFilePaths(paths []string) ([]string, error) {
 }
 
 type fileData struct {
-	Flags      *map[string]ld.FeatureFlag //nolint:megacheck // allow deprecated usage
-	FlagValues *map[string]interface{}
-	Segments   *map[string]ld.Segment //nolint:megacheck // allow deprecated usage
+	Flags       *map[string]ld.FeatureFlag //nolint:megacheck // allow deprecated usage
+	FlagValues  *map[string]interface{}
+	SimpleFlags *map[string]simpleFlag
+	Segments    *map[string]ld.Segment //nolint:megacheck // allow deprecated usage
+
+	// simpleFlagData holds the SimpleFlags converted to flags by parseFileData, so that an invalid simple
+	// flag is reported as an error in the file that contains it.
+	simpleFlagData map[string]ld.FeatureFlag //nolint:megacheck // allow deprecated usage
 }
 
 func insertData(all map[ld.VersionedDataKind]map[string]ld.VersionedData, kind ld.VersionedDataKind, key string,
-	data ld.VersionedData) error {
-	if _, exists := all[kind][key]; exists {
+	data ld.VersionedData, allowOverride bool) error {
+	if _, exists := all[kind][key]; exists && !allowOverride {
 		return fmt.Errorf("%s '%s' is specified by multiple files", kind.GetNamespace(), key)
 	}
 	all[kind][key] = data
@@ -282,21 +420,35 @@ func insertData(all map[ld.VersionedDataKind]map[string]ld.VersionedData, kind l
 }
 
 func readFile(path string) (fileData, error) {
+	rawData, err := ioutil.ReadFile(path) // nolint:gosec // G304: ok to read file into variable
+	if err != nil {
+		return fileData{}, fmt.Errorf("unable to read file: %s", err)
+	}
+	return parseFileData(rawData)
+}
+
+func parseFileData(rawData []byte) (fileData, error) {
 	var data fileData
-	var rawData []byte
 	var err error
-	if rawData, err = ioutil.ReadFile(path); err != nil { // nolint:gosec // G304: ok to read file into variable
-		return data, fmt.Errorf("unable to read file: %s", err)
-	}
 	if detectJSON(rawData) {
 		err = json.Unmarshal(rawData, &data)
 	} else {
 		err = yaml.Unmarshal(rawData, &data)
 	}
 	if err != nil {
-		err = fmt.Errorf("error parsing file: %s", err)
+		return data, fmt.Errorf("error parsing file: %s", err)
 	}
-	return data, err
+	if data.SimpleFlags != nil {
+		data.simpleFlagData = make(map[string]ld.FeatureFlag, len(*data.SimpleFlags)) //nolint:megacheck // allow deprecated usage
+		for key, sf := range *data.SimpleFlags {
+			flag, err := sf.toFeatureFlag(key)
+			if err != nil {
+				return fileData{}, fmt.Errorf("invalid simple flag '%s': %s", key, err)
+			}
+			data.simpleFlagData[key] = flag
+		}
+	}
+	return data, nil
 }
 
 func detectJSON(rawData []byte) bool {
@@ -304,7 +456,7 @@ func detectJSON(rawData []byte) bool {
 	return strings.HasPrefix("{", strings.TrimLeftFunc(string(rawData), unicode.IsSpace))
 }
 
-func mergeFileData(allFileData ...fileData) (map[ld.VersionedDataKind]map[string]ld.VersionedData, error) {
+func mergeFileData(allowOverrides bool, allFileData ...fileData) (map[ld.VersionedDataKind]map[string]ld.VersionedData, error) {
 	all := map[ld.VersionedDataKind]map[string]ld.VersionedData{
 		ld.Features: {}, //nolint:megacheck // allow deprecated usage
 		ld.Segments: {}, //nolint:megacheck // allow deprecated usage
@@ -313,7 +465,7 @@ func mergeFileData(allFileData ...fileData) (map[ld.VersionedDataKind]map[string
 		if d.Flags != nil {
 			for key, f := range *d.Flags {
 				data := f
-				if err := insertData(all, ld.Features, key, &data); err != nil { //nolint:megacheck // allow deprecated usage
+				if err := insertData(all, ld.Features, key, &data, allowOverrides); err != nil { //nolint:megacheck // allow deprecated usage
 					return nil, err
 				}
 			}
@@ -327,15 +479,21 @@ func mergeFileData(allFileData ...fileData) (map[ld.VersionedDataKind]map[string
 					On:          true,
 					Fallthrough: ld.VariationOrRollout{Variation: &zeroVariation}, //nolint:megacheck // allow deprecated usage
 				}
-				if err := insertData(all, ld.Features, key, &data); err != nil { //nolint:megacheck // allow deprecated usage
+				if err := insertData(all, ld.Features, key, &data, allowOverrides); err != nil { //nolint:megacheck // allow deprecated usage
 					return nil, err
 				}
 			}
 		}
+		for key, f := range d.simpleFlagData {
+			data := f
+			if err := insertData(all, ld.Features, key, &data, allowOverrides); err != nil { //nolint:megacheck // allow deprecated usage
+				return nil, err
+			}
+		}
 		if d.Segments != nil {
 			for key, s := range *d.Segments {
 				data := s
-				if err := insertData(all, ld.Segments, key, &data); err != nil { //nolint:megacheck // allow deprecated usage
+				if err := insertData(all, ld.Segments, key, &data, allowOverrides); err != nil { //nolint:megacheck // allow deprecated usage
 					return nil, err
 				}
 			}
@@ -347,9 +505,7 @@ func mergeFileData(allFileData ...fileData) (map[ld.VersionedDataKind]map[string
 // Close is called automatically when the client is closed.
 func (fs *fileDataSource) Close() (err error) {
 	fs.closeOnce.Do(func() {
-		if fs.closeReloaderCh != nil {
-			close(fs.closeReloaderCh)
-		}
+		close(fs.closeCh)
 	})
 	return nil
 }
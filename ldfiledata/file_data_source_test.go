@@ -70,6 +70,25 @@ func TestNewFileDataSourceJson(t *testing.T) {
 	assert.True(t, flag.(*ld.FeatureFlag).On)
 }
 
+func TestNewFileDataSourceRejectsInvalidFlags(t *testing.T) {
+	filename := makeTempFile(t, `{"flags": {"bad-flag": {"on": true, "offVariation": 1}}, "flagValues": {"good-flag": 1}}`)
+	defer os.Remove(filename)
+
+	store := ld.NewInMemoryFeatureStore(nil)
+
+	factory := NewFileDataSourceFactory(FilePaths(filename))
+	dataSource, err := factory("", ld.Config{FeatureStore: store, RejectInvalidData: true})
+	require.NoError(t, err)
+	closeWhenReady := make(chan struct{})
+	dataSource.Start(closeWhenReady)
+	<-closeWhenReady
+	require.True(t, dataSource.Initialized())
+	flags, err := store.All(ld.Features)
+	require.NoError(t, err)
+	assert.Equal(t, 1, len(flags))
+	assert.NotNil(t, flags["good-flag"])
+}
+
 func TestNewFileDataSourceJsonWithTwoFiles(t *testing.T) {
 	filename1 := makeTempFile(t, `{"flags": {"my-flag1": {"on": true}}}`)
 	defer os.Remove(filename1)
@@ -168,3 +187,94 @@ flagValues:
 	require.True(t, flag.(*ld.FeatureFlag).On)
 	assert.Equal(t, 0, *flag.(*ld.FeatureFlag).Fallthrough.Variation)
 }
+
+func TestNewFileDataSourceLayeredMergeOverridesEarlierFiles(t *testing.T) {
+	filename1 := makeTempFile(t, `{"flags": {"my-flag1": {"on": true}}, "flagValues": {"my-flag2": "base", "my-flag3": "base"}}`)
+	defer os.Remove(filename1)
+	filename2 := makeTempFile(t, `{"flags": {"my-flag1": {"on": false}}, "flagValues": {"my-flag2": "override"}}`)
+	defer os.Remove(filename2)
+
+	store := ld.NewInMemoryFeatureStore(nil)
+
+	factory := NewFileDataSourceFactory(FilePaths(filename1, filename2), UseLayeredMerge())
+	dataSource, err := factory("", ld.Config{FeatureStore: store})
+	require.NoError(t, err)
+	closeWhenReady := make(chan struct{})
+	dataSource.Start(closeWhenReady)
+	<-closeWhenReady
+	require.True(t, dataSource.Initialized())
+
+	flag1, err := store.Get(ld.Features, "my-flag1")
+	require.NoError(t, err)
+	assert.False(t, flag1.(*ld.FeatureFlag).On)
+	flag2, err := store.Get(ld.Features, "my-flag2")
+	require.NoError(t, err)
+	assert.Equal(t, []interface{}{"override"}, flag2.(*ld.FeatureFlag).Variations)
+	flag3, err := store.Get(ld.Features, "my-flag3")
+	require.NoError(t, err)
+	assert.Equal(t, []interface{}{"base"}, flag3.(*ld.FeatureFlag).Variations)
+}
+
+func TestNewFileDataSourceLayeredMergeIgnoresBadFile(t *testing.T) {
+	filename1 := makeTempFile(t, `{"flagValues": {"my-flag1": "base"}}`)
+	defer os.Remove(filename1)
+	filename2 := makeTempFile(t, `bad data`)
+	defer os.Remove(filename2)
+
+	store := ld.NewInMemoryFeatureStore(nil)
+
+	factory := NewFileDataSourceFactory(FilePaths(filename1, filename2), UseLayeredMerge())
+	dataSource, err := factory("", ld.Config{FeatureStore: store})
+	require.NoError(t, err)
+	closeWhenReady := make(chan struct{})
+	dataSource.Start(closeWhenReady)
+	<-closeWhenReady
+	require.True(t, dataSource.Initialized())
+
+	flag1, err := store.Get(ld.Features, "my-flag1")
+	require.NoError(t, err)
+	assert.Equal(t, []interface{}{"base"}, flag1.(*ld.FeatureFlag).Variations)
+}
+
+func TestLayeredMergeIgnoresFileWithInvalidSimpleFlag(t *testing.T) {
+	filename1 := makeTempFile(t, `{"flagValues": {"my-flag1": true}}`)
+	defer os.Remove(filename1)
+	filename2 := makeTempFile(t, `{"simpleFlags": {"my-flag2": {"rollout": [{"value": 1, "percent": 30}]}}}`)
+	defer os.Remove(filename2)
+
+	store := ld.NewInMemoryFeatureStore(nil)
+	fs, err := newFileDataSource(ld.Config{FeatureStore: store}, FilePaths(filename1, filename2), UseLayeredMerge())
+	require.NoError(t, err)
+	fs.reload()
+	require.True(t, fs.Initialized())
+
+	flag1, err := store.Get(ld.Features, "my-flag1")
+	require.NoError(t, err)
+	assert.Equal(t, []interface{}{true}, flag1.(*ld.FeatureFlag).Variations)
+	flag2, err := store.Get(ld.Features, "my-flag2")
+	require.NoError(t, err)
+	assert.Nil(t, flag2)
+}
+
+func TestLayeredMergeKeepsLastGoodDataForFileThatBecomesInvalid(t *testing.T) {
+	filename1 := makeTempFile(t, `{"flagValues": {"my-flag1": "base", "my-flag2": "base"}}`)
+	defer os.Remove(filename1)
+	filename2 := makeTempFile(t, `{"flagValues": {"my-flag1": "override"}}`)
+	defer os.Remove(filename2)
+
+	store := ld.NewInMemoryFeatureStore(nil)
+	fs, err := newFileDataSource(ld.Config{FeatureStore: store}, FilePaths(filename1, filename2), UseLayeredMerge())
+	require.NoError(t, err)
+	fs.reload()
+
+	require.NoError(t, ioutil.WriteFile(filename1, []byte(`{"flagValues": {"my-flag2": "changed"}}`), 0600))
+	require.NoError(t, ioutil.WriteFile(filename2, []byte(`bad data`), 0600))
+	fs.reload()
+
+	flag1, err := store.Get(ld.Features, "my-flag1")
+	require.NoError(t, err)
+	assert.Equal(t, []interface{}{"override"}, flag1.(*ld.FeatureFlag).Variations)
+	flag2, err := store.Get(ld.Features, "my-flag2")
+	require.NoError(t, err)
+	assert.Equal(t, []interface{}{"changed"}, flag2.(*ld.FeatureFlag).Variations)
+}
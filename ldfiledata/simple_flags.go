@@ -0,0 +1,117 @@
+package ldfiledata
+
+import (
+	"fmt"
+	"math"
+	"reflect"
+
+	ld "gopkg.in/launchdarkly/go-server-sdk.v4"
+)
+
+// simpleFlag is the shorthand format of the "simpleFlags" section of a data file. Variations are
+// referred to by value rather than by index; if the list of variations is not given, it consists of
+// every value that the flag can serve, in the order in which they appear.
+type simpleFlag struct {
+	On         *bool               `json:"on"`
+	Variations []interface{}       `json:"variations"`
+	OffValue   interface{}         `json:"offValue"`
+	Targets    []simpleFlagTarget  `json:"targets"`
+	Rules      []simpleFlagRule    `json:"rules"`
+	Value      interface{}         `json:"value"`
+	Rollout    []simpleFlagPercent `json:"rollout"`
+	BucketBy   *string             `json:"bucketBy"`
+}
+
+type simpleFlagTarget struct {
+	Values []string    `json:"values"`
+	Value  interface{} `json:"value"`
+}
+
+type simpleFlagRule struct {
+	Attribute string              `json:"attribute"`
+	Op        ld.Operator         `json:"op"` //nolint:megacheck // allow deprecated usage
+	Values    []interface{}       `json:"values"`
+	Negate    bool                `json:"negate"`
+	Value     interface{}         `json:"value"`
+	Rollout   []simpleFlagPercent `json:"rollout"`
+}
+
+type simpleFlagPercent struct {
+	Value   interface{} `json:"value"`
+	Percent float64     `json:"percent"`
+}
+
+// toFeatureFlag translates the shorthand into a complete flag. Its rules and rollouts are evaluated
+// exactly as if they had come from LaunchDarkly.
+func (sf simpleFlag) toFeatureFlag(key string) (ld.FeatureFlag, error) { //nolint:megacheck // allow deprecated usage
+	b := simpleFlagBuilder{variations: sf.Variations, fixedVariations: len(sf.Variations) > 0}
+	flag := ld.FeatureFlag{Key: key, Version: 1, On: sf.On == nil || *sf.On, Salt: key} //nolint:megacheck // allow deprecated usage
+	if sf.OffValue != nil {
+		flag.OffVariation = b.variation(sf.OffValue)
+	}
+	for _, t := range sf.Targets {
+		flag.Targets = append(flag.Targets, ld.Target{Values: t.Values, Variation: *b.variation(t.Value)}) //nolint:megacheck // allow deprecated usage
+	}
+	for i, r := range sf.Rules {
+		op := r.Op
+		if op == "" {
+			op = ld.OperatorIn //nolint:megacheck // allow deprecated usage
+		}
+		flag.Rules = append(flag.Rules, ld.Rule{ //nolint:megacheck // allow deprecated usage
+			ID:                 fmt.Sprintf("rule%d", i),
+			Clauses:            []ld.Clause{{Attribute: r.Attribute, Op: op, Values: r.Values, Negate: r.Negate}}, //nolint:megacheck // allow deprecated usage
+			VariationOrRollout: b.variationOrRollout(fmt.Sprintf("rule %d", i), r.Value, r.Rollout, sf.BucketBy),
+		})
+	}
+	flag.Fallthrough = b.variationOrRollout("fallthrough", sf.Value, sf.Rollout, sf.BucketBy)
+	flag.Variations = b.variations
+	return flag, b.err
+}
+
+type simpleFlagBuilder struct {
+	variations      []interface{}
+	fixedVariations bool
+	err             error
+}
+
+// variation returns the index of a value, adding it to the variations if necessary.
+func (b *simpleFlagBuilder) variation(value interface{}) *int {
+	for i, v := range b.variations {
+		if reflect.DeepEqual(v, value) {
+			return &i
+		}
+	}
+	if b.fixedVariations {
+		if b.err == nil {
+			b.err = fmt.Errorf("value %v is not one of the flag's variations", value)
+		}
+		return new(int)
+	}
+	b.variations = append(b.variations, value)
+	index := len(b.variations) - 1
+	return &index
+}
+
+func (b *simpleFlagBuilder) variationOrRollout(desc string, value interface{}, rollout []simpleFlagPercent,
+	bucketBy *string) ld.VariationOrRollout { //nolint:megacheck // allow deprecated usage
+	if len(rollout) == 0 {
+		if value == nil {
+			if b.err == nil {
+				b.err = fmt.Errorf("%s must have a value or a rollout", desc)
+			}
+			return ld.VariationOrRollout{} //nolint:megacheck // allow deprecated usage
+		}
+		return ld.VariationOrRollout{Variation: b.variation(value)} //nolint:megacheck // allow deprecated usage
+	}
+	r := ld.Rollout{BucketBy: bucketBy} //nolint:megacheck // allow deprecated usage
+	total := 0
+	for _, p := range rollout {
+		weight := int(math.Floor(p.Percent*1000 + 0.5))
+		r.Variations = append(r.Variations, ld.WeightedVariation{Variation: *b.variation(p.Value), Weight: weight}) //nolint:megacheck // allow deprecated usage
+		total += weight
+	}
+	if total != 100000 && b.err == nil {
+		b.err = fmt.Errorf("%s rollout percentages add up to %g instead of 100", desc, float64(total)/1000)
+	}
+	return ld.VariationOrRollout{Rollout: &r} //nolint:megacheck // allow deprecated usage
+}
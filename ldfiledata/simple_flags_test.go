@@ -0,0 +1,108 @@
+package ldfiledata
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	ld "gopkg.in/launchdarkly/go-server-sdk.v4"
+)
+
+const simpleFlagsYAML = `
+simpleFlags:
+  color:
+    offValue: red
+    targets:
+      - values: [user-1]
+        value: blue
+    rules:
+      - attribute: country
+        values: [US, CA]
+        value: green
+      - attribute: email
+        op: endsWith
+        values: ["@example.com"]
+        rollout:
+          - {value: green, percent: 0}
+          - {value: blue, percent: 100}
+    value: red
+  disabled:
+    "on": false
+    variations: [a, b]
+    offValue: b
+    value: a
+`
+
+func getSimpleFlag(t *testing.T, store ld.FeatureStore, key string) *ld.FeatureFlag {
+	item, err := store.Get(ld.Features, key)
+	require.NoError(t, err)
+	require.NotNil(t, item)
+	return item.(*ld.FeatureFlag)
+}
+
+func TestSimpleFlagIsTranslatedToFeatureFlag(t *testing.T) {
+	store := ld.NewInMemoryFeatureStore(nil)
+	dataSource := startDataSource(t, store, Data("simple.yaml", []byte(simpleFlagsYAML)))
+	defer dataSource.Close()
+	require.True(t, dataSource.Initialized())
+
+	flag := getSimpleFlag(t, store, "color")
+	assert.True(t, flag.On)
+	assert.Equal(t, []interface{}{"red", "blue", "green"}, flag.Variations)
+	assert.Equal(t, 0, *flag.OffVariation)
+	assert.Equal(t, []ld.Target{{Values: []string{"user-1"}, Variation: 1}}, flag.Targets)
+	require.Equal(t, 2, len(flag.Rules))
+	assert.Equal(t, []ld.Clause{{Attribute: "country", Op: ld.OperatorIn, Values: []interface{}{"US", "CA"}}},
+		flag.Rules[0].Clauses)
+	assert.Equal(t, 2, *flag.Rules[0].Variation)
+	assert.Equal(t, []ld.WeightedVariation{{Variation: 2, Weight: 0}, {Variation: 1, Weight: 100000}},
+		flag.Rules[1].Rollout.Variations)
+	assert.Equal(t, 0, *flag.Fallthrough.Variation)
+	assert.Empty(t, ld.ValidateData(map[ld.VersionedDataKind]map[string]ld.VersionedData{ld.Features: {"color": flag}}))
+
+	disabled := getSimpleFlag(t, store, "disabled")
+	assert.False(t, disabled.On)
+	assert.Equal(t, []interface{}{"a", "b"}, disabled.Variations)
+	assert.Equal(t, 1, *disabled.OffVariation)
+}
+
+func TestSimpleFlagIsEvaluatedWithTargetsAndRules(t *testing.T) {
+	store := ld.NewInMemoryFeatureStore(nil)
+	dataSource := startDataSource(t, store, Data("simple.yaml", []byte(simpleFlagsYAML)))
+	defer dataSource.Close()
+	flag := getSimpleFlag(t, store, "color")
+
+	expected := map[string]ld.User{
+		"blue":  ld.NewUser("user-1"),
+		"green": ld.NewUserBuilder("user-2").Country("CA").Build(),
+		"red":   ld.NewUser("user-3"),
+	}
+	for value, user := range expected {
+		result, _, _ := flag.Evaluate(user, store)
+		assert.Equal(t, value, result)
+	}
+	emailUser := ld.NewUserBuilder("user-4").Email("a@example.com").Build()
+	result, _, _ := flag.Evaluate(emailUser, store)
+	assert.Equal(t, "blue", result)
+
+	result, _, _ = getSimpleFlag(t, store, "disabled").Evaluate(ld.NewUser("user-1"), store)
+	assert.Equal(t, "b", result)
+}
+
+func TestSimpleFlagErrors(t *testing.T) {
+	for _, c := range []struct {
+		data    string
+		message string
+	}{
+		{`{"simpleFlags": {"f": {"variations": [1, 2], "value": 3}}}`,
+			"invalid simple flag 'f': value 3 is not one of the flag's variations"},
+		{`{"simpleFlags": {"f": {"rollout": [{"value": 1, "percent": 50}, {"value": 2, "percent": 40}]}}}`,
+			"invalid simple flag 'f': fallthrough rollout percentages add up to 90 instead of 100"},
+		{`{"simpleFlags": {"f": {"rules": [{"attribute": "key", "values": ["x"]}], "value": 1}}}`,
+			"invalid simple flag 'f': rule 0 must have a value or a rollout"},
+	} {
+		_, err := parseFileData([]byte(c.data))
+		assert.EqualError(t, err, c.message)
+	}
+}
@@ -0,0 +1,288 @@
+package ldfiledata
+
+import (
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"sync"
+	"time"
+
+	ld "gopkg.in/launchdarkly/go-server-sdk.v4"
+)
+
+// DefaultURLPollInterval is the default interval at which data from URLs is refreshed.
+const DefaultURLPollInterval = time.Minute
+
+// dataSourceInput is a location from which the file data source reads data in the file format.
+type dataSourceInput interface {
+	// name identifies the input in log messages.
+	name() string
+	read() (fileData, error)
+}
+
+type pathInput struct {
+	path string
+}
+
+func (i *pathInput) name() string {
+	return i.path
+}
+
+func (i *pathInput) read() (fileData, error) {
+	return readFile(i.path)
+}
+
+type bytesInput struct {
+	inputName string
+	data      []byte
+}
+
+func (i *bytesInput) name() string {
+	return i.inputName
+}
+
+func (i *bytesInput) read() (fileData, error) {
+	return parseFileData(i.data)
+}
+
+type envInput struct {
+	varName string
+}
+
+func (i *envInput) name() string {
+	return "$" + i.varName
+}
+
+func (i *envInput) read() (fileData, error) {
+	value, ok := os.LookupEnv(i.varName)
+	if !ok {
+		return fileData{}, fmt.Errorf("environment variable %s is not set", i.varName)
+	}
+	return parseFileData([]byte(value))
+}
+
+// urlInput reads data from a URL. The last data that was received is cached, and is refreshed only
+// when refresh is called; refresh uses a conditional request, so that it can tell whether the data
+// has changed.
+type urlInput struct {
+	url          string
+	client       *http.Client
+	data         fileData
+	hasData      bool
+	etag         string
+	lastModified string
+	lock         sync.Mutex
+}
+
+func (i *urlInput) name() string {
+	return i.url
+}
+
+func (i *urlInput) read() (fileData, error) {
+	i.lock.Lock()
+	hasData := i.hasData
+	i.lock.Unlock()
+	if !hasData {
+		if _, err := i.refresh(); err != nil {
+			return fileData{}, err
+		}
+	}
+	i.lock.Lock()
+	defer i.lock.Unlock()
+	return i.data, nil
+}
+
+// refresh requests the URL and returns true if new data was received.
+func (i *urlInput) refresh() (bool, error) {
+	i.lock.Lock()
+	defer i.lock.Unlock()
+	req, err := http.NewRequest("GET", i.url, nil)
+	if err != nil {
+		return false, fmt.Errorf("invalid URL: %s", err)
+	}
+	if i.hasData {
+		if i.etag != "" {
+			req.Header.Set("If-None-Match", i.etag)
+		}
+		if i.lastModified != "" {
+			req.Header.Set("If-Modified-Since", i.lastModified)
+		}
+	}
+	resp, err := i.client.Do(req)
+	if err != nil {
+		return false, fmt.Errorf("unable to read URL: %s", err)
+	}
+	defer resp.Body.Close() // nolint:errcheck
+	if resp.StatusCode == http.StatusNotModified && i.hasData {
+		return false, nil
+	}
+	if resp.StatusCode != http.StatusOK {
+		return false, fmt.Errorf("unexpected response status %d from URL", resp.StatusCode)
+	}
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return false, fmt.Errorf("unable to read URL: %s", err)
+	}
+	data, err := parseFileData(body)
+	if err != nil {
+		return false, err
+	}
+	i.data, i.hasData = data, true
+	i.etag, i.lastModified = resp.Header.Get("ETag"), resp.Header.Get("Last-Modified")
+	return true, nil
+}
+
+type inputsOption struct {
+	inputs []dataSourceInput
+}
+
+func (o inputsOption) apply(opts *fileDataSourceOptions) error {
+	opts.inputs = append(opts.inputs, o.inputs...)
+	return nil
+}
+
+// Data creates an option for NewFileDataSourceFactory, to provide data in the file format (JSON or
+// YAML) directly, for instance from a file that is embedded in the application binary. The name
+// identifies the data in log messages.
+//
+//     //go:embed flags.yaml
+//     var flagsYAML []byte
+//
+//     ldfiledata.Data("flags.yaml", flagsYAML)
+func Data(name string, data []byte) FileDataSourceOption {
+	return inputsOption{[]dataSourceInput{&bytesInput{inputName: name, data: data}}}
+}
+
+type readerOption struct {
+	name   string
+	reader io.Reader
+}
+
+func (o readerOption) apply(opts *fileDataSourceOptions) error {
+	data, err := ioutil.ReadAll(o.reader)
+	if err != nil {
+		return fmt.Errorf("unable to read data from %s: %s", o.name, err)
+	}
+	opts.inputs = append(opts.inputs, &bytesInput{inputName: o.name, data: data})
+	return nil
+}
+
+// Reader creates an option for NewFileDataSourceFactory, to read data in the file format (JSON or
+// YAML) from an io.Reader. The reader is read completely when the data source is created, so later
+// reloads use the same data. The name identifies the data in log messages.
+func Reader(name string, reader io.Reader) FileDataSourceOption {
+	return readerOption{name: name, reader: reader}
+}
+
+// EnvironmentVariables creates an option for NewFileDataSourceFactory, to read data in the file format
+// (JSON or YAML) from environment variables. The variables are read again whenever the data source
+// reloads its data; it is an error for a variable not to be set.
+func EnvironmentVariables(names ...string) FileDataSourceOption {
+	inputs := make([]dataSourceInput, 0, len(names))
+	for _, n := range names {
+		inputs = append(inputs, &envInput{varName: n})
+	}
+	return inputsOption{inputs}
+}
+
+// URLs creates an option for NewFileDataSourceFactory, to read data in the file format (JSON or YAML)
+// from one or more HTTP or HTTPS URLs. The data source requests each URL again at the interval set by
+// URLPollInterval, using the ETag and Last-Modified response headers to make a conditional request;
+// if any of the URLs has new data, all of the inputs are reloaded.
+//
+// If a URL cannot be read when it is refreshed, the data source logs an error and keeps the last data
+// that it received from that URL.
+func URLs(urls ...string) FileDataSourceOption {
+	inputs := make([]dataSourceInput, 0, len(urls))
+	for _, u := range urls {
+		inputs = append(inputs, &urlInput{url: u})
+	}
+	return inputsOption{inputs}
+}
+
+type urlPollIntervalOption struct {
+	interval time.Duration
+}
+
+func (o urlPollIntervalOption) apply(opts *fileDataSourceOptions) error {
+	opts.urlPollInterval = o.interval
+	return nil
+}
+
+// URLPollInterval creates an option for NewFileDataSourceFactory, to set how often data from URLs is
+// refreshed. The default is DefaultURLPollInterval. A value of zero or less turns off refreshing.
+func URLPollInterval(interval time.Duration) FileDataSourceOption {
+	return urlPollIntervalOption{interval}
+}
+
+type httpClientOption struct {
+	client *http.Client
+}
+
+func (o httpClientOption) apply(opts *fileDataSourceOptions) error {
+	opts.httpClient = o.client
+	return nil
+}
+
+// HTTPClient creates an option for NewFileDataSourceFactory, to specify the HTTP client that is used
+// to read URLs, for instance to add authentication. By default, the client is created with the
+// HTTPClientFactory of the SDK configuration (or ld.NewHTTPClientFactory, if that is not set), so it
+// uses the same proxy and TLS settings as the rest of the SDK, and Config.Timeout as its request timeout.
+//
+// The initial read of each URL happens when the client starts, so a client with no timeout could keep
+// the SDK client from starting if the server does not respond.
+func HTTPClient(client *http.Client) FileDataSourceOption {
+	return httpClientOption{client}
+}
+
+// newDefaultHTTPClient creates the HTTP client for reading URLs if none was specified with HTTPClient.
+// It always has a timeout, even if the SDK configuration or the HTTPClientFactory does not provide one.
+func newDefaultHTTPClient(ldConfig ld.Config) *http.Client {
+	if ldConfig.Timeout <= 0 {
+		ldConfig.Timeout = ld.DefaultConfig.Timeout
+	}
+	factory := ldConfig.HTTPClientFactory
+	if factory == nil {
+		factory = ld.NewHTTPClientFactory()
+	}
+	client := factory(ldConfig)
+	if client.Timeout <= 0 {
+		client.Timeout = ldConfig.Timeout
+	}
+	return &client
+}
+
+func (fs *fileDataSource) urlInputs() []*urlInput {
+	var ret []*urlInput
+	for _, input := range fs.options.inputs {
+		if u, ok := input.(*urlInput); ok {
+			ret = append(ret, u)
+		}
+	}
+	return ret
+}
+
+func (fs *fileDataSource) pollURLs(urls []*urlInput, interval time.Duration, closeCh <-chan struct{}) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-closeCh:
+			return
+		case <-ticker.C:
+			changed := false
+			for _, u := range urls {
+				uChanged, err := u.refresh()
+				if err != nil {
+					fs.loggers.Errorf("Unable to refresh flags: %s [%s]", err, u.name())
+				}
+				changed = changed || uChanged
+			}
+			if changed {
+				fs.reload()
+			}
+		}
+	}
+}
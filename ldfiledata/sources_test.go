@@ -0,0 +1,244 @@
+package ldfiledata
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	ld "gopkg.in/launchdarkly/go-server-sdk.v4"
+)
+
+func startDataSource(t *testing.T, store ld.FeatureStore, options ...FileDataSourceOption) ld.UpdateProcessor {
+	factory := NewFileDataSourceFactory(options...)
+	dataSource, err := factory("", ld.Config{FeatureStore: store})
+	require.NoError(t, err)
+	closeWhenReady := make(chan struct{})
+	dataSource.Start(closeWhenReady)
+	select {
+	case <-closeWhenReady:
+	case <-time.After(time.Second):
+		require.Fail(t, "timed out waiting for data source")
+	}
+	return dataSource
+}
+
+func getFlagValue(t *testing.T, store ld.FeatureStore, key string) interface{} {
+	flag, err := store.Get(ld.Features, key)
+	require.NoError(t, err)
+	if flag == nil {
+		return nil
+	}
+	return flag.(*ld.FeatureFlag).Variations[0]
+}
+
+func TestDataSourceFromBytes(t *testing.T) {
+	store := ld.NewInMemoryFeatureStore(nil)
+	dataSource := startDataSource(t, store, Data("embedded.yaml", []byte("flagValues:\n  my-flag: embedded\n")))
+	defer dataSource.Close()
+
+	require.True(t, dataSource.Initialized())
+	assert.Equal(t, "embedded", getFlagValue(t, store, "my-flag"))
+}
+
+func TestDataSourceFromReader(t *testing.T) {
+	store := ld.NewInMemoryFeatureStore(nil)
+	dataSource := startDataSource(t, store, Reader("reader", strings.NewReader(`{"flagValues": {"my-flag": 2}}`)))
+	defer dataSource.Close()
+
+	require.True(t, dataSource.Initialized())
+	assert.Equal(t, float64(2), getFlagValue(t, store, "my-flag"))
+}
+
+type failingReader struct{}
+
+func (r failingReader) Read(p []byte) (int, error) {
+	return 0, errors.New("sorry")
+}
+
+func TestDataSourceFromFailingReaderReturnsError(t *testing.T) {
+	factory := NewFileDataSourceFactory(Reader("reader", failingReader{}))
+	_, err := factory("", ld.Config{FeatureStore: ld.NewInMemoryFeatureStore(nil)})
+	assert.EqualError(t, err, "unable to read data from reader: sorry")
+}
+
+func TestDataSourceFromEnvironmentVariables(t *testing.T) {
+	require.NoError(t, os.Setenv("LDFILEDATA_TEST_FLAGS", `{"flagValues": {"my-flag": true}}`))
+	defer os.Unsetenv("LDFILEDATA_TEST_FLAGS")
+
+	store := ld.NewInMemoryFeatureStore(nil)
+	dataSource := startDataSource(t, store, EnvironmentVariables("LDFILEDATA_TEST_FLAGS"))
+	defer dataSource.Close()
+
+	require.True(t, dataSource.Initialized())
+	assert.Equal(t, true, getFlagValue(t, store, "my-flag"))
+}
+
+func TestDataSourceFromUnsetEnvironmentVariableFails(t *testing.T) {
+	store := ld.NewInMemoryFeatureStore(nil)
+	dataSource := startDataSource(t, store, EnvironmentVariables("LDFILEDATA_TEST_UNSET"))
+	defer dataSource.Close()
+
+	assert.False(t, dataSource.Initialized())
+}
+
+type testDataServer struct {
+	body             string
+	status           int
+	requestCount     int
+	conditionalCount int
+	lock             sync.Mutex
+}
+
+func (s *testDataServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	s.requestCount++
+	etag := `"` + s.body + `"`
+	if r.Header.Get("If-None-Match") != "" {
+		s.conditionalCount++
+	}
+	switch {
+	case s.status != 0:
+		w.WriteHeader(s.status)
+	case r.Header.Get("If-None-Match") == etag:
+		w.WriteHeader(http.StatusNotModified)
+	default:
+		w.Header().Set("ETag", etag)
+		_, _ = w.Write([]byte(s.body))
+	}
+}
+
+func (s *testDataServer) update(body string, status int) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	s.body, s.status = body, status
+}
+
+func (s *testDataServer) counts() (int, int) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	return s.requestCount, s.conditionalCount
+}
+
+func waitForFlagValue(t *testing.T, store ld.FeatureStore, key string, value interface{}) {
+	deadline := time.Now().Add(time.Second)
+	for getFlagValue(t, store, key) != value {
+		if time.Now().After(deadline) {
+			require.Fail(t, "timed out waiting for flag value", "expected %v", value)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestDataSourceFromURLRefreshesData(t *testing.T) {
+	handler := &testDataServer{body: `{"flagValues": {"my-flag": "a"}}`}
+	server := httptest.NewServer(handler)
+	defer server.Close()
+
+	store := ld.NewInMemoryFeatureStore(nil)
+	dataSource := startDataSource(t, store, URLs(server.URL), URLPollInterval(20*time.Millisecond))
+	defer dataSource.Close()
+
+	require.True(t, dataSource.Initialized())
+	assert.Equal(t, "a", getFlagValue(t, store, "my-flag"))
+
+	time.Sleep(100 * time.Millisecond)
+	requests, conditional := handler.counts()
+	assert.True(t, requests > 1)
+	assert.Equal(t, requests-1, conditional)
+
+	handler.update(`{"flagValues": {"my-flag": "b"}}`, 0)
+	waitForFlagValue(t, store, "my-flag", "b")
+
+	handler.update("", http.StatusInternalServerError)
+	time.Sleep(100 * time.Millisecond)
+	assert.Equal(t, "b", getFlagValue(t, store, "my-flag")) // keeps last good data
+}
+
+func TestDataSourceFromURLWaitsForFirstSuccessfulRequest(t *testing.T) {
+	handler := &testDataServer{status: http.StatusServiceUnavailable}
+	server := httptest.NewServer(handler)
+	defer server.Close()
+
+	store := ld.NewInMemoryFeatureStore(nil)
+	factory := NewFileDataSourceFactory(URLs(server.URL), URLPollInterval(20*time.Millisecond))
+	dataSource, err := factory("", ld.Config{FeatureStore: store})
+	require.NoError(t, err)
+	defer dataSource.Close()
+	closeWhenReady := make(chan struct{})
+	dataSource.Start(closeWhenReady)
+
+	handler.update(`{"flagValues": {"my-flag": "a"}}`, 0)
+	select {
+	case <-closeWhenReady:
+	case <-time.After(time.Second):
+		require.Fail(t, "timed out waiting for data source")
+	}
+	assert.True(t, dataSource.Initialized())
+	assert.Equal(t, "a", getFlagValue(t, store, "my-flag"))
+}
+
+func TestInputsAreMergedWithFiles(t *testing.T) {
+	filename := makeTempFile(t, `{"flagValues": {"file-flag": "file"}}`)
+	defer os.Remove(filename)
+
+	store := ld.NewInMemoryFeatureStore(nil)
+	dataSource := startDataSource(t, store, FilePaths(filename), Data("data", []byte(`{"flagValues": {"file-flag": "data"}}`)),
+		UseLayeredMerge())
+	defer dataSource.Close()
+
+	require.True(t, dataSource.Initialized())
+	assert.Equal(t, "data", getFlagValue(t, store, "file-flag"))
+}
+
+func TestDataSourceFromURLUsesConfiguredTimeout(t *testing.T) {
+	release := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-release
+	}))
+	defer server.Close()
+	defer close(release)
+
+	store := ld.NewInMemoryFeatureStore(nil)
+	factory := NewFileDataSourceFactory(URLs(server.URL))
+	dataSource, err := factory("", ld.Config{FeatureStore: store, Timeout: 50 * time.Millisecond})
+	require.NoError(t, err)
+	defer dataSource.Close()
+	closeWhenReady := make(chan struct{})
+	started := make(chan struct{})
+	go func() {
+		dataSource.Start(closeWhenReady)
+		close(started)
+	}()
+	select {
+	case <-started:
+	case <-time.After(time.Second):
+		require.Fail(t, "timed out waiting for data source to start")
+	}
+	assert.False(t, dataSource.Initialized())
+}
+
+func TestDataSourceFromURLUsesHTTPClientFactory(t *testing.T) {
+	handler := &testDataServer{body: `{"flagValues": {"my-flag": "a"}}`}
+	server := httptest.NewServer(handler)
+	defer server.Close()
+
+	factoryCalled := false
+	config := ld.Config{FeatureStore: ld.NewInMemoryFeatureStore(nil)}
+	config.HTTPClientFactory = func(c ld.Config) http.Client {
+		factoryCalled = true
+		return ld.NewHTTPClientFactory()(c)
+	}
+	dataSource, err := NewFileDataSourceFactory(URLs(server.URL))("", config)
+	require.NoError(t, err)
+	defer dataSource.Close()
+	assert.True(t, factoryCalled)
+}
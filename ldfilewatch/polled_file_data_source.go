@@ -0,0 +1,197 @@
+package ldfilewatch
+
+import (
+	"crypto/sha256"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"time"
+
+	ld "gopkg.in/launchdarkly/go-server-sdk.v4"
+	"gopkg.in/launchdarkly/go-server-sdk.v4/ldfiledata"
+)
+
+const (
+	// DefaultPollInterval is the default interval at which PollFiles checks the files for changes.
+	DefaultPollInterval = time.Second
+	// DefaultPollDebounce is the default time that PollFiles waits for files to stop changing before
+	// reloading them.
+	DefaultPollDebounce = 100 * time.Millisecond
+)
+
+// PollOption is the interface for optional configuration parameters that can be passed to PollFiles.
+type PollOption interface {
+	apply(p *filePoller)
+}
+
+type pollIntervalOption struct {
+	interval time.Duration
+}
+
+func (o pollIntervalOption) apply(p *filePoller) {
+	if o.interval > 0 {
+		p.interval = o.interval
+	}
+}
+
+// PollInterval creates an option for PollFiles, to set how often the files are checked for changes.
+// The default is DefaultPollInterval, which is also used if interval is zero or negative.
+func PollInterval(interval time.Duration) PollOption {
+	return pollIntervalOption{interval}
+}
+
+type pollDebounceOption struct {
+	debounce time.Duration
+}
+
+func (o pollDebounceOption) apply(p *filePoller) {
+	if o.debounce > 0 {
+		p.debounce = o.debounce
+	}
+}
+
+// PollDebounce creates an option for PollFiles, to set how long the files must remain unchanged after
+// a change is detected before they are reloaded. This avoids reloading a file that is only partly
+// written, or reloading several times when a group of files is updated. The default is
+// DefaultPollDebounce, which is also used if debounce is zero or negative.
+func PollDebounce(debounce time.Duration) PollOption {
+	return pollDebounceOption{debounce}
+}
+
+type fileState struct {
+	exists   bool
+	realPath string
+	modTime  time.Time
+	size     int64
+	hash     [sha256.Size]byte
+}
+
+type filePoller struct {
+	interval    time.Duration
+	debounce    time.Duration
+	errorLogger ld.Logger
+	reload      func()
+	paths       []string
+	states      map[string]fileState
+}
+
+// PollFiles returns a mechanism for the file data source to reload its source files whenever one of
+// them has been modified, by checking the files periodically instead of relying on file system
+// notifications as WatchFiles does. This works on network file systems and in other environments where
+// notifications are unreliable. Use it as follows:
+//
+//     factory := ldfiledata.NewFileDataSourceFactory(
+//         ldfiledata.FilePaths("./test-data/my-flags.json"),
+//         ldfiledata.UseReloader(ldfilewatch.PollFiles(ldfilewatch.PollInterval(5 * time.Second))))
+//
+// A file is considered to have changed if it is created or deleted, or if its modification time or
+// size changes and its content is different. Symbolic links are followed, so replacing a link to
+// point to a different file (as Kubernetes does when it updates a ConfigMap volume) is also detected.
+func PollFiles(options ...PollOption) ldfiledata.ReloaderFactory {
+	return func(paths []string, errorLogger ld.Logger, reload func(), closeCh <-chan struct{}) error {
+		p := &filePoller{
+			interval:    DefaultPollInterval,
+			debounce:    DefaultPollDebounce,
+			errorLogger: errorLogger,
+			reload:      reload,
+			paths:       paths,
+			states:      make(map[string]fileState),
+		}
+		for _, o := range options {
+			o.apply(p)
+		}
+		p.checkForChanges()
+		go p.run(closeCh)
+		return nil
+	}
+}
+
+func (p *filePoller) run(closeCh <-chan struct{}) {
+	// As in WatchFiles, we do a possibly redundant reload once we have recorded the initial state of the
+	// files, in case they changed after the data source loaded them but before we started polling.
+	p.reload()
+
+	ticker := time.NewTicker(p.interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-closeCh:
+			return
+		case <-ticker.C:
+		}
+		if !p.checkForChanges() {
+			continue
+		}
+		for {
+			select {
+			case <-closeCh:
+				return
+			case <-time.After(p.debounce):
+			}
+			if !p.checkForChanges() {
+				break
+			}
+		}
+		p.reload()
+	}
+}
+
+// checkForChanges updates the recorded state of each file, and returns true if any of them changed.
+func (p *filePoller) checkForChanges() bool {
+	changed := false
+	for _, path := range p.paths {
+		oldState := p.states[path]
+		newState := p.readState(path, oldState)
+		if newState.exists != oldState.exists || newState.hash != oldState.hash {
+			changed = true
+		}
+		p.states[path] = newState
+	}
+	return changed
+}
+
+func (p *filePoller) readState(path string, oldState fileState) fileState {
+	realPath, err := filepath.EvalSymlinks(path)
+	if err != nil {
+		return fileState{} // the file does not exist, or a link in the path is broken
+	}
+	info, err := os.Stat(realPath)
+	if err != nil {
+		return fileState{}
+	}
+	state := fileState{exists: true, realPath: realPath, modTime: info.ModTime(), size: info.Size()}
+	if oldState.exists && state.realPath == oldState.realPath && state.modTime.Equal(oldState.modTime) &&
+		state.size == oldState.size {
+		state.hash = oldState.hash
+		return state
+	}
+	data, err := ioutil.ReadFile(realPath) // nolint:gosec // G304: ok to read file into variable
+	if err != nil {
+		p.errorLogger.Printf(`Unable to read file "%s": %s`, realPath, err)
+		return oldState
+	}
+	state.hash = sha256.Sum256(data)
+	return state
+}
+
+// CombineReloaders returns a mechanism for the file data source to reload its source files that uses
+// all of the specified mechanisms, so that a change detected by any of them causes a reload. For
+// instance, this uses file system notifications where they are available, but also checks the files
+// every 30 seconds in case a notification is missed:
+//
+//     ldfiledata.UseReloader(ldfilewatch.CombineReloaders(
+//         ldfilewatch.WatchFiles,
+//         ldfilewatch.PollFiles(ldfilewatch.PollInterval(30 * time.Second))))
+//
+// If more than one of the mechanisms detects the same change, the files are simply reloaded more than
+// once.
+func CombineReloaders(factories ...ldfiledata.ReloaderFactory) ldfiledata.ReloaderFactory {
+	return func(paths []string, errorLogger ld.Logger, reload func(), closeCh <-chan struct{}) error {
+		for _, f := range factories {
+			if err := f(paths, errorLogger, reload, closeCh); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+}
@@ -0,0 +1,184 @@
+package ldfilewatch
+
+import (
+	"errors"
+	"io/ioutil"
+	"log"
+	"os"
+	"path"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	ld "gopkg.in/launchdarkly/go-server-sdk.v4"
+	"gopkg.in/launchdarkly/go-server-sdk.v4/ldfiledata"
+)
+
+type reloadCounter struct {
+	count int
+	lock  sync.Mutex
+}
+
+func (c *reloadCounter) reload() {
+	c.lock.Lock()
+	c.count++
+	c.lock.Unlock()
+}
+
+func (c *reloadCounter) get() int {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	return c.count
+}
+
+func startPolling(t *testing.T, paths []string, options ...PollOption) (*reloadCounter, chan struct{}) {
+	counter := &reloadCounter{}
+	closeCh := make(chan struct{})
+	options = append([]PollOption{PollInterval(10 * time.Millisecond), PollDebounce(10 * time.Millisecond)}, options...)
+	err := PollFiles(options...)(paths, log.New(ioutil.Discard, "", 0), counter.reload, closeCh)
+	require.NoError(t, err)
+	requireTrueWithinDuration(t, time.Second, func() bool { return counter.get() == 1 }) // initial reload
+	return counter, closeCh
+}
+
+func TestPolledFileDataSourceReloadsChangedFile(t *testing.T) {
+	filename := makeTempFile(t, `
+---
+flags:
+  my-flag:
+    "on": true
+`)
+	defer os.Remove(filename)
+
+	store := ld.NewInMemoryFeatureStore(nil)
+	factory := ldfiledata.NewFileDataSourceFactory(
+		ldfiledata.FilePaths(filename),
+		ldfiledata.UseReloader(PollFiles(PollInterval(10*time.Millisecond))))
+	dataSource, err := factory("", ld.Config{FeatureStore: store})
+	require.NoError(t, err)
+	defer dataSource.Close()
+
+	closeWhenReady := make(chan struct{})
+	dataSource.Start(closeWhenReady)
+	<-closeWhenReady
+	assert.True(t, hasFlag(t, store, "my-flag", func(f ld.FeatureFlag) bool { return f.On }))
+
+	replaceFileContents(t, filename, `
+---
+flags:
+  my-flag:
+    "on": false
+`)
+
+	requireTrueWithinDuration(t, time.Second, func() bool {
+		return hasFlag(t, store, "my-flag", func(f ld.FeatureFlag) bool {
+			return !f.On
+		})
+	})
+}
+
+func TestPolledFileDataSourceIgnoresTimestampChangeWithSameContent(t *testing.T) {
+	filename := makeTempFile(t, "{}")
+	defer os.Remove(filename)
+	counter, closeCh := startPolling(t, []string{filename})
+	defer close(closeCh)
+
+	later := time.Now().Add(time.Hour)
+	require.NoError(t, os.Chtimes(filename, later, later))
+	time.Sleep(100 * time.Millisecond)
+	assert.Equal(t, 1, counter.get())
+
+	replaceFileContents(t, filename, "[]")
+	requireTrueWithinDuration(t, time.Second, func() bool { return counter.get() == 2 })
+}
+
+func TestPolledFileDataSourceDetectsCreationAndDeletion(t *testing.T) {
+	filename := makeTempFile(t, "")
+	require.NoError(t, os.Remove(filename))
+	counter, closeCh := startPolling(t, []string{filename})
+	defer close(closeCh)
+
+	replaceFileContents(t, filename, "{}")
+	requireTrueWithinDuration(t, time.Second, func() bool { return counter.get() == 2 })
+
+	require.NoError(t, os.Remove(filename))
+	requireTrueWithinDuration(t, time.Second, func() bool { return counter.get() == 3 })
+}
+
+func TestPolledFileDataSourceDetectsSymlinkReplacement(t *testing.T) {
+	// This is how Kubernetes updates a ConfigMap volume: the file is a link to a file in a directory
+	// that is itself reached through a link, and the directory link is atomically replaced.
+	tempDir, err := ioutil.TempDir("", "file-source-test")
+	require.NoError(t, err)
+	defer os.RemoveAll(tempDir)
+	for _, dir := range []string{"v1", "v2"} {
+		require.NoError(t, os.Mkdir(path.Join(tempDir, dir), 0700))
+		require.NoError(t, ioutil.WriteFile(path.Join(tempDir, dir, "flags.json"), []byte(`{"x":"`+dir+`"}`), 0600))
+	}
+	require.NoError(t, os.Symlink("v1", path.Join(tempDir, "..data")))
+	filePath := path.Join(tempDir, "flags.json")
+	require.NoError(t, os.Symlink("..data/flags.json", filePath))
+
+	counter, closeCh := startPolling(t, []string{filePath})
+	defer close(closeCh)
+
+	require.NoError(t, os.Symlink("v2", path.Join(tempDir, "..data_tmp")))
+	require.NoError(t, os.Rename(path.Join(tempDir, "..data_tmp"), path.Join(tempDir, "..data")))
+	requireTrueWithinDuration(t, time.Second, func() bool { return counter.get() == 2 })
+}
+
+func TestPolledFileDataSourceDebouncesChanges(t *testing.T) {
+	filename := makeTempFile(t, "")
+	defer os.Remove(filename)
+	counter, closeCh := startPolling(t, []string{filename}, PollDebounce(300*time.Millisecond))
+	defer close(closeCh)
+
+	for i := 0; i < 5; i++ {
+		replaceFileContents(t, filename, string(rune('a'+i)))
+		time.Sleep(50 * time.Millisecond)
+	}
+	requireTrueWithinDuration(t, time.Second, func() bool { return counter.get() == 2 })
+	time.Sleep(400 * time.Millisecond)
+	assert.Equal(t, 2, counter.get())
+}
+
+func TestCombineReloadersStartsAllReloaders(t *testing.T) {
+	var started []string
+	makeFactory := func(name string, err error) ldfiledata.ReloaderFactory {
+		return func(paths []string, errorLogger ld.Logger, reload func(), closeCh <-chan struct{}) error {
+			started = append(started, name)
+			return err
+		}
+	}
+
+	combined := CombineReloaders(makeFactory("a", nil), makeFactory("b", nil))
+	require.NoError(t, combined(nil, log.New(ioutil.Discard, "", 0), func() {}, nil))
+	assert.Equal(t, []string{"a", "b"}, started)
+
+	started = nil
+	combined = CombineReloaders(makeFactory("a", errors.New("sorry")), makeFactory("b", nil))
+	assert.EqualError(t, combined(nil, log.New(ioutil.Discard, "", 0), func() {}, nil), "sorry")
+	assert.Equal(t, []string{"a"}, started)
+}
+
+func TestNonPositivePollOptionsAreIgnored(t *testing.T) {
+	p := &filePoller{interval: DefaultPollInterval, debounce: DefaultPollDebounce}
+	for _, o := range []PollOption{PollInterval(0), PollInterval(-time.Second), PollDebounce(0), PollDebounce(-time.Second)} {
+		o.apply(p)
+	}
+	assert.Equal(t, DefaultPollInterval, p.interval)
+	assert.Equal(t, DefaultPollDebounce, p.debounce)
+
+	// this would panic in the polling goroutine if the zero interval were passed to time.NewTicker
+	counter := &reloadCounter{}
+	closeCh := make(chan struct{})
+	defer close(closeCh)
+	err := PollFiles(PollInterval(0), PollDebounce(0))([]string{"not-a-real-file"}, log.New(ioutil.Discard, "", 0),
+		counter.reload, closeCh)
+	require.NoError(t, err)
+	requireTrueWithinDuration(t, time.Second, func() bool { return counter.get() == 1 })
+	time.Sleep(50 * time.Millisecond)
+}
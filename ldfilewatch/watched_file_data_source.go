@@ -1,5 +1,7 @@
 // Package ldfilewatch allows the LaunchDarkly client to read feature flag data from a
 // file, with automatic reloading. It should be used in conjunction with the ldfiledata package.
+// Files can be watched with file system notifications (WatchFiles), or checked periodically
+// (PollFiles) where notifications are not available.
 // The two packages are separate so as to avoid bringing additional dependencies for users who
 // do not need automatic reloading.
 package ldfilewatch
@@ -1,6 +1,6 @@
 // Package ldhttp provides helper functions for custom HTTP configuration. You will not need to use this package
 // unless you need to extend the default Go HTTP client behavior, for instance, to specify additional trusted CA
-// certificates.
+// certificates or a client certificate.
 package ldhttp
 
 import (
@@ -21,6 +21,15 @@ type transportExtraOptions struct {
 	caCerts        *x509.CertPool
 	connectTimeout time.Duration
 	proxyURL       *url.URL
+	getClientCert  func(*tls.CertificateRequestInfo) (*tls.Certificate, error)
+	minTLSVersion  uint16
+	serverName     string
+	tlsConfigHooks []func(*tls.Config)
+}
+
+func (opts transportExtraOptions) hasTLSConfig() bool {
+	return opts.caCerts != nil || opts.getClientCert != nil || opts.minTLSVersion != 0 ||
+		opts.serverName != "" || len(opts.tlsConfigHooks) > 0
 }
 
 // TransportOption is the interface for optional configuration parameters that can be passed to NewHTTPTransport.
@@ -121,8 +130,17 @@ func NewHTTPTransport(options ...TransportOption) (*http.Transport, *net.Dialer,
 	}
 	transport := newDefaultTransport()
 	transport.DialContext = dialer.DialContext
-	if extraOptions.caCerts != nil {
-		transport.TLSClientConfig = &tls.Config{RootCAs: extraOptions.caCerts}
+	if extraOptions.hasTLSConfig() {
+		tlsConfig := &tls.Config{
+			RootCAs:              extraOptions.caCerts,
+			GetClientCertificate: extraOptions.getClientCert,
+			MinVersion:           extraOptions.minTLSVersion,
+			ServerName:           extraOptions.serverName,
+		}
+		for _, configure := range extraOptions.tlsConfigHooks {
+			configure(tlsConfig)
+		}
+		transport.TLSClientConfig = tlsConfig
 	}
 	if extraOptions.proxyURL != nil {
 		transport.Proxy = http.ProxyURL(extraOptions.proxyURL)
@@ -1,7 +1,9 @@
 package ldhttp
 
 import (
+	"crypto/tls"
 	"crypto/x509"
+	"encoding/pem"
 	"io/ioutil"
 	"net/http"
 	"net/http/httptest"
@@ -9,6 +11,7 @@ import (
 	"os"
 	"reflect"
 	"testing"
+	"time"
 
 	helpers "github.com/launchdarkly/go-test-helpers"
 	"github.com/launchdarkly/go-test-helpers/httphelpers"
@@ -87,3 +90,135 @@ func TestCanSetProxyURL(t *testing.T) {
 	require.NoError(t, err)
 	assert.Equal(t, url, urlOut)
 }
+
+func TestCanSendClientCertFromData(t *testing.T) {
+	withClientCertFiles(t, func(certFile, keyFile string) {
+		withClientCertServer(t, func(server *httptest.Server, caCertData []byte) {
+			certData, err := ioutil.ReadFile(certFile)
+			require.NoError(t, err)
+			keyData, err := ioutil.ReadFile(keyFile)
+			require.NoError(t, err)
+			transport, _, err := NewHTTPTransport(CACertOption(caCertData), ClientCertOption(certData, keyData))
+			require.NoError(t, err)
+
+			assert.Equal(t, serialNumberOfCertFile(t, certFile), getClientCertSerialNumber(t, transport, server.URL))
+		})
+	})
+}
+
+func TestClientCertFileIsReloadedWhenChanged(t *testing.T) {
+	withClientCertFiles(t, func(certFile, keyFile string) {
+		withClientCertServer(t, func(server *httptest.Server, caCertData []byte) {
+			transport, _, err := NewHTTPTransport(CACertOption(caCertData), ClientCertFileOption(certFile, keyFile))
+			require.NoError(t, err)
+			assert.Equal(t, serialNumberOfCertFile(t, certFile), getClientCertSerialNumber(t, transport, server.URL))
+
+			require.NoError(t, httphelpers.MakeSelfSignedCert(certFile, keyFile))
+			later := time.Now().Add(time.Hour)
+			require.NoError(t, os.Chtimes(certFile, later, later))
+			transport.CloseIdleConnections()
+			assert.Equal(t, serialNumberOfCertFile(t, certFile), getClientCertSerialNumber(t, transport, server.URL))
+		})
+	})
+}
+
+func TestPreviousClientCertIsUsedIfReloadFails(t *testing.T) {
+	withClientCertFiles(t, func(certFile, keyFile string) {
+		withClientCertServer(t, func(server *httptest.Server, caCertData []byte) {
+			serialNumber := serialNumberOfCertFile(t, certFile)
+			transport, _, err := NewHTTPTransport(CACertOption(caCertData), ClientCertFileOption(certFile, keyFile))
+			require.NoError(t, err)
+
+			require.NoError(t, ioutil.WriteFile(certFile, []byte("sorry"), 0600))
+			assert.Equal(t, serialNumber, getClientCertSerialNumber(t, transport, server.URL))
+		})
+	})
+}
+
+func TestErrorForBadClientCertData(t *testing.T) {
+	_, _, err := NewHTTPTransport(ClientCertOption([]byte("sorry"), []byte("sorry")))
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "Invalid client certificate data")
+}
+
+func TestErrorForNonexistentClientCertFile(t *testing.T) {
+	_, _, err := NewHTTPTransport(ClientCertFileOption("not-a-real-file.pem", "not-a-real-file.pem"))
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "Can't load client certificate")
+}
+
+func TestTLSConfigOptions(t *testing.T) {
+	var hookCalls []string
+	transport, _, err := NewHTTPTransport(
+		MinTLSVersionOption(tls.VersionTLS12),
+		ServerNameOption("gateway.example.com"),
+		TLSConfigOption(func(c *tls.Config) {
+			hookCalls = append(hookCalls, "first:"+c.ServerName)
+			c.CipherSuites = []uint16{tls.TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256}
+		}),
+		TLSConfigOption(func(c *tls.Config) { hookCalls = append(hookCalls, "second") }),
+	)
+	require.NoError(t, err)
+	require.NotNil(t, transport.TLSClientConfig)
+	assert.Equal(t, uint16(tls.VersionTLS12), transport.TLSClientConfig.MinVersion)
+	assert.Equal(t, "gateway.example.com", transport.TLSClientConfig.ServerName)
+	assert.Equal(t, []uint16{tls.TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256}, transport.TLSClientConfig.CipherSuites)
+	assert.Equal(t, []string{"first:gateway.example.com", "second"}, hookCalls)
+}
+
+func TestNoTLSConfigByDefault(t *testing.T) {
+	transport, _, err := NewHTTPTransport()
+	require.NoError(t, err)
+	assert.Nil(t, transport.TLSClientConfig)
+}
+
+func withClientCertFiles(t *testing.T, action func(certFile, keyFile string)) {
+	helpers.WithTempFile(func(certFile string) {
+		helpers.WithTempFile(func(keyFile string) {
+			require.NoError(t, httphelpers.MakeSelfSignedCert(certFile, keyFile))
+			action(certFile, keyFile)
+		})
+	})
+}
+
+// withClientCertServer starts an HTTPS server that requires a client certificate, and responds with
+// the certificate's serial number.
+func withClientCertServer(t *testing.T, action func(server *httptest.Server, caCertData []byte)) {
+	withClientCertFiles(t, func(serverCertFile, serverKeyFile string) {
+		serverCert, err := tls.LoadX509KeyPair(serverCertFile, serverKeyFile)
+		require.NoError(t, err)
+		caCertData, err := ioutil.ReadFile(serverCertFile)
+		require.NoError(t, err)
+		server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Write([]byte(r.TLS.PeerCertificates[0].SerialNumber.String()))
+		}))
+		server.TLS = &tls.Config{
+			Certificates: []tls.Certificate{serverCert},
+			ClientAuth:   tls.RequireAnyClientCert,
+		}
+		server.StartTLS()
+		defer server.Close()
+		action(server, caCertData)
+	})
+}
+
+func getClientCertSerialNumber(t *testing.T, transport *http.Transport, url string) string {
+	client := *http.DefaultClient
+	client.Transport = transport
+	resp, err := client.Get(url)
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	body, err := ioutil.ReadAll(resp.Body)
+	require.NoError(t, err)
+	return string(body)
+}
+
+func serialNumberOfCertFile(t *testing.T, certFile string) string {
+	certData, err := ioutil.ReadFile(certFile)
+	require.NoError(t, err)
+	block, _ := pem.Decode(certData)
+	require.NotNil(t, block)
+	cert, err := x509.ParseCertificate(block.Bytes)
+	require.NoError(t, err)
+	return cert.SerialNumber.String()
+}
@@ -0,0 +1,159 @@
+package ldhttp
+
+import (
+	"crypto/tls"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+)
+
+type clientCertOption struct {
+	certData []byte
+	keyData  []byte
+}
+
+func (o clientCertOption) apply(opts *transportExtraOptions) error {
+	cert, err := tls.X509KeyPair(o.certData, o.keyData)
+	if err != nil {
+		return fmt.Errorf("Invalid client certificate data: %v", err)
+	}
+	opts.getClientCert = func(*tls.CertificateRequestInfo) (*tls.Certificate, error) {
+		return &cert, nil
+	}
+	return nil
+}
+
+// ClientCertOption specifies a client certificate and private key, in PEM format, to be presented to
+// any server that requests one during the TLS handshake, when used with NewHTTPTransport. This is
+// necessary if you connect through a proxy or gateway that requires mutual TLS authentication.
+func ClientCertOption(certData, keyData []byte) TransportOption {
+	return clientCertOption{certData: certData, keyData: keyData}
+}
+
+type clientCertFileOption struct {
+	certFilePath string
+	keyFilePath  string
+}
+
+func (o clientCertFileOption) apply(opts *transportExtraOptions) error {
+	loader := &clientCertFileLoader{certFilePath: o.certFilePath, keyFilePath: o.keyFilePath}
+	if _, err := loader.getClientCertificate(nil); err != nil {
+		return err
+	}
+	opts.getClientCert = loader.getClientCertificate
+	return nil
+}
+
+// ClientCertFileOption specifies a client certificate and private key to be presented to any server
+// that requests one during the TLS handshake, when used with NewHTTPTransport. It reads them from
+// files in PEM format.
+//
+// The files are checked again whenever a new connection is made, and are reloaded if their
+// modification time or size has changed, so a certificate can be renewed without restarting the
+// application. If the files cannot be read or parsed at that point, for instance because they are in
+// the middle of being rewritten, the previous certificate is used until the next connection.
+func ClientCertFileOption(certFilePath, keyFilePath string) TransportOption {
+	return clientCertFileOption{certFilePath: certFilePath, keyFilePath: keyFilePath}
+}
+
+// clientCertFileLoader provides the client certificate for ClientCertFileOption, reloading it when the
+// files change.
+type clientCertFileLoader struct {
+	certFilePath string
+	keyFilePath  string
+	cert         *tls.Certificate
+	certFileInfo fileVersion
+	keyFileInfo  fileVersion
+	lock         sync.Mutex
+}
+
+type fileVersion struct {
+	modTime time.Time
+	size    int64
+}
+
+func statFileVersion(path string) (fileVersion, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return fileVersion{}, err
+	}
+	return fileVersion{modTime: info.ModTime(), size: info.Size()}, nil
+}
+
+func (l *clientCertFileLoader) getClientCertificate(*tls.CertificateRequestInfo) (*tls.Certificate, error) {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+	certFileInfo, certErr := statFileVersion(l.certFilePath)
+	keyFileInfo, keyErr := statFileVersion(l.keyFilePath)
+	if l.cert != nil && (certErr != nil || keyErr != nil ||
+		(certFileInfo == l.certFileInfo && keyFileInfo == l.keyFileInfo)) {
+		return l.cert, nil
+	}
+	cert, err := tls.LoadX509KeyPair(l.certFilePath, l.keyFilePath)
+	if err != nil {
+		if l.cert != nil {
+			return l.cert, nil
+		}
+		return nil, fmt.Errorf("Can't load client certificate: %v", err)
+	}
+	l.cert = &cert
+	l.certFileInfo = certFileInfo
+	l.keyFileInfo = keyFileInfo
+	return l.cert, nil
+}
+
+type minTLSVersionOption struct {
+	version uint16
+}
+
+func (o minTLSVersionOption) apply(opts *transportExtraOptions) error {
+	opts.minTLSVersion = o.version
+	return nil
+}
+
+// MinTLSVersionOption specifies the lowest TLS version that will be accepted for HTTPS connections,
+// when used with NewHTTPTransport. The version is one of the constants defined in crypto/tls, such as
+// tls.VersionTLS12. If this is not set, Go's default minimum version is used.
+func MinTLSVersionOption(version uint16) TransportOption {
+	return minTLSVersionOption{version: version}
+}
+
+type serverNameOption struct {
+	serverName string
+}
+
+func (o serverNameOption) apply(opts *transportExtraOptions) error {
+	opts.serverName = o.serverName
+	return nil
+}
+
+// ServerNameOption specifies the server name to be sent with Server Name Indication (SNI) and checked
+// against the server's certificate, when used with NewHTTPTransport. By default, this is the host name
+// from the URL. Overriding it is only useful if all HTTPS connections go to the same server under a
+// different name, as with a TLS-terminating gateway.
+func ServerNameOption(serverName string) TransportOption {
+	return serverNameOption{serverName: serverName}
+}
+
+type tlsConfigOption struct {
+	configure func(*tls.Config)
+}
+
+func (o tlsConfigOption) apply(opts *transportExtraOptions) error {
+	opts.tlsConfigHooks = append(opts.tlsConfigHooks, o.configure)
+	return nil
+}
+
+// TLSConfigOption specifies a function that can make any other changes to the TLS configuration for
+// HTTPS connections, when used with NewHTTPTransport. It is called after all of the other options have
+// been applied. For instance, this restricts the allowable cipher suites:
+//
+//     ldhttp.TLSConfigOption(func(c *tls.Config) {
+//         c.CipherSuites = []uint16{tls.TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256}
+//     })
+//
+// If there is more than one TLSConfigOption, they are called in the order they were specified.
+func TLSConfigOption(configure func(*tls.Config)) TransportOption {
+	return tlsConfigOption{configure: configure}
+}
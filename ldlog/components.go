@@ -0,0 +1,86 @@
+package ldlog
+
+import "sync"
+
+// Names of the SDK components that have their own loggers. See Loggers.ForComponent.
+const (
+	// ComponentStream is the component that receives flag data from the LaunchDarkly streaming service.
+	ComponentStream = "stream"
+	// ComponentPolling is the component that requests flag data when streaming is disabled.
+	ComponentPolling = "polling"
+	// ComponentEvents is the component that sends analytics events.
+	ComponentEvents = "events"
+	// ComponentStore is the feature store, including persistent store integrations.
+	ComponentStore = "store"
+	// ComponentFileData is the file data source in the ldfiledata package.
+	ComponentFileData = "filedata"
+	// ComponentDiagnostics is the component that sends diagnostic events.
+	ComponentDiagnostics = "diagnostics"
+)
+
+// componentLevels holds the minimum levels that have been set for individual components. It is shared
+// by all copies of a Loggers instance, so that levels can be changed at any time.
+type componentLevels struct {
+	levels map[string]LogLevel
+	lock   sync.RWMutex
+}
+
+func (c *componentLevels) get(component string) (LogLevel, bool) {
+	if c == nil || component == "" {
+		return 0, false
+	}
+	c.lock.RLock()
+	defer c.lock.RUnlock()
+	level, ok := c.levels[component]
+	return level, ok
+}
+
+// ForComponent returns a copy of the Loggers for use by the named component, such as ComponentStream.
+// Its minimum level can be set independently with SetComponentMinLevel, and it adds a Field with the
+// component name to every message that is sent to a StructuredLogger.
+func (l Loggers) ForComponent(component string) Loggers {
+	fields := make([]Field, 0, len(l.fields)+1)
+	for _, f := range l.fields {
+		if f.Key != FieldComponent {
+			fields = append(fields, f)
+		}
+	}
+	l.fields = append(fields, Component(component))
+	l.component = component
+	return l
+}
+
+// SetComponentMinLevel specifies the minimum level for log output from the named component, such as
+// ComponentStream, overriding the level set by SetMinLevel. For instance, this enables Debug output
+// from the stream processor only:
+//
+//     config.Loggers = ldlog.NewDefaultLoggers()
+//     config.Loggers.SetComponentMinLevel(ldlog.ComponentStream, ldlog.Debug)
+//
+// The level can be changed at any time, including after the client has started. The change applies
+// to every copy of this Loggers instance, including the ones that the SDK makes from Config.Loggers,
+// as long as the instance was initialized (by NewDefaultLoggers, Init, or any Set method) before it
+// was copied.
+func (l *Loggers) SetComponentMinLevel(component string, minLevel LogLevel) {
+	l.ensureInited()
+	l.components.lock.Lock()
+	l.components.levels[component] = minLevel
+	l.components.lock.Unlock()
+}
+
+// ClearComponentMinLevel undoes the effect of SetComponentMinLevel, so that the named component uses
+// the level set by SetMinLevel again.
+func (l *Loggers) ClearComponentMinLevel(component string) {
+	l.ensureInited()
+	l.components.lock.Lock()
+	delete(l.components.levels, component)
+	l.components.lock.Unlock()
+}
+
+// GetComponentMinLevel returns the minimum level for log output from the named component.
+func (l Loggers) GetComponentMinLevel(component string) LogLevel {
+	if level, ok := l.components.get(component); ok {
+		return level
+	}
+	return l.minLevel
+}
@@ -0,0 +1,54 @@
+package ldlog
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestComponentMinLevelOverridesOverallMinLevel(t *testing.T) {
+	ls := logSink{}
+	l := NewDefaultLoggers()
+	l.SetBaseLogger(&ls)
+	stream := l.ForComponent(ComponentStream)
+	events := l.ForComponent(ComponentEvents)
+
+	l.SetComponentMinLevel(ComponentStream, Debug)
+	l.SetComponentMinLevel(ComponentEvents, Warn)
+	l.Debug("root debug")
+	l.Info("root info")
+	stream.Debug("stream debug")
+	stream.Debugw("stream debug with fields")
+	events.Info("events info")
+	events.Warn("events warn")
+
+	assert.Equal(t, []string{"INFO: root info", "DEBUG: stream debug", "DEBUG: stream debug with fields",
+		"WARN: events warn"}, ls.output)
+	assert.Equal(t, Debug, l.GetComponentMinLevel(ComponentStream))
+	assert.Equal(t, Info, l.GetComponentMinLevel(ComponentPolling))
+}
+
+func TestComponentMinLevelCanBeChangedAfterCopying(t *testing.T) {
+	ls := logSink{}
+	l := NewDefaultLoggers()
+	l.SetBaseLogger(&ls)
+	store := l.With(FlagKey("x")).ForComponent(ComponentStore)
+
+	store.Debug("a")
+	l.SetComponentMinLevel(ComponentStore, Debug)
+	store.Debug("b")
+	l.ClearComponentMinLevel(ComponentStore)
+	store.Debug("c")
+	l.SetComponentMinLevel(ComponentStore, None)
+	store.Error("d")
+
+	assert.Equal(t, []string{"DEBUG: b"}, ls.output)
+}
+
+func TestForComponentReplacesComponentField(t *testing.T) {
+	ss := structuredSink{}
+	l := NewDefaultLoggers()
+	l.SetStructuredLogger(&ss)
+	l.ForComponent(ComponentEvents).With(FlagKey("x")).ForComponent(ComponentDiagnostics).Info("a")
+	assert.Equal(t, []string{"Info|a|[{flagKey x} {component diagnostics}]"}, ss.output)
+}
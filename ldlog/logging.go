@@ -5,6 +5,7 @@
 package ldlog
 
 import (
+	"fmt"
 	"log"
 	"os"
 	"strings"
@@ -68,23 +69,35 @@ const (
 // Loggers is a configurable logging component with a level filter.
 //
 // By default, Loggers sends output to standard error and enables all levels except Debug.
-// You may call any of its Set methods to change this configuration.
+// You may call any of its Set methods to change this configuration. To receive the SDK's messages
+// with separate fields, such as the key of a flag, rather than as plain text, use
+// SetStructuredLogger.
 type Loggers struct {
-	debugLog   levelLogger
-	infoLog    levelLogger
-	warnLog    levelLogger
-	errorLog   levelLogger
-	baseLogger BaseLogger
-	minLevel   LogLevel
-	prefix     string
-	inited     bool
+	debugLog         levelLogger
+	infoLog          levelLogger
+	warnLog          levelLogger
+	errorLog         levelLogger
+	baseLogger       BaseLogger
+	structuredLogger StructuredLogger
+	fields           []Field
+	limiter          *rateLimiter
+	components       *componentLevels
+	component        string
+	minLevel         LogLevel
+	prefix           string
+	inited           bool
 }
 
 type levelLogger struct {
-	baseLogger     BaseLogger
-	enabled        bool
-	prefix         string
-	overrideLogger bool
+	baseLogger       BaseLogger
+	structuredLogger StructuredLogger // set only by ForLevel
+	fields           []Field          // set only by ForLevel
+	limiter          *rateLimiter     // set only by ForLevel
+	level            LogLevel
+	enabled          bool
+	prefix           string
+	messagePrefix    string
+	overrideLogger   bool
 }
 
 var nullLog = levelLogger{enabled: false}
@@ -196,10 +209,24 @@ func (l *Loggers) SetBaseLoggerForLevel(level LogLevel, baseLogger BaseLogger) {
 //
 // If the level is not a valid log level, the return value is non-nil but will produce no output.
 func (l Loggers) ForLevel(level LogLevel) BaseLogger {
-	if level >= l.minLevel {
+	minLevel := l.minLevel
+	componentLevel, hasComponentLevel := l.components.get(l.component)
+	if hasComponentLevel {
+		minLevel = componentLevel
+	}
+	if level >= minLevel {
 		lll := l.levelLogger(level)
 		if lll != nil {
-			return *lll
+			ll := *lll
+			if hasComponentLevel {
+				ll.enabled = true // it may have been disabled by the overall minimum level
+			}
+			if !ll.overrideLogger {
+				ll.structuredLogger = l.structuredLogger
+			}
+			ll.fields = l.fields
+			ll.limiter = l.limiter
+			return ll
 		}
 	}
 	return nullLog
@@ -228,6 +255,7 @@ func (l *Loggers) ensureInited() {
 	}
 	l.minLevel = Info
 	l.baseLogger = log.New(os.Stderr, "[LaunchDarkly] ", log.LstdFlags)
+	l.components = &componentLevels{levels: make(map[string]LogLevel)}
 	for _, levelLogger := range l.allLevels() {
 		levelLogger.baseLogger = l.baseLogger
 	}
@@ -237,7 +265,9 @@ func (l *Loggers) ensureInited() {
 
 func (l *Loggers) configureLevels() {
 	for level, levelLogger := range l.allLevels() {
+		levelLogger.level = level
 		levelLogger.enabled = level >= l.minLevel
+		levelLogger.messagePrefix = l.prefix
 		levelLogger.prefix = strings.ToUpper(level.Name()) + ":"
 		if l.prefix != "" {
 			levelLogger.prefix = levelLogger.prefix + " " + l.prefix
@@ -269,6 +299,22 @@ func (l *Loggers) levelLogger(level LogLevel) *levelLogger {
 }
 
 func (ll levelLogger) Println(values ...interface{}) {
+	if ll.enabled && (ll.limiter == nil || ll.allowed(strings.TrimSuffix(fmt.Sprintln(values...), "\n"))) {
+		ll.writeln(values...)
+	}
+}
+
+func (ll levelLogger) Printf(format string, args ...interface{}) {
+	if ll.enabled && ll.allowed(format) {
+		ll.writef(format, args...)
+	}
+}
+
+func (ll levelLogger) writeln(values ...interface{}) {
+	if ll.enabled && ll.structuredLogger != nil {
+		ll.logStructured(strings.TrimSuffix(fmt.Sprintln(values...), "\n"), nil)
+		return
+	}
 	if ll.enabled && ll.baseLogger != nil {
 		if len(values) == 1 {
 			ll.baseLogger.Println(ll.prefix, values[0])
@@ -283,7 +329,11 @@ func (ll levelLogger) Println(values ...interface{}) {
 	}
 }
 
-func (ll levelLogger) Printf(format string, args ...interface{}) {
+func (ll levelLogger) writef(format string, args ...interface{}) {
+	if ll.enabled && ll.structuredLogger != nil {
+		ll.logStructured(fmt.Sprintf(format, args...), nil)
+		return
+	}
 	if ll.enabled && ll.baseLogger != nil {
 		ll.baseLogger.Printf(ll.prefix+" "+format, args...)
 	}
@@ -0,0 +1,104 @@
+package ldlog
+
+import (
+	"fmt"
+	"sync"
+	"time"
+)
+
+// maxRateLimitWindows is the number of message templates above which expired rate limiting state is
+// discarded, so that messages with varying text do not use unlimited memory.
+const maxRateLimitWindows = 1000
+
+// rateLimiter counts the messages that have been logged with each template in the current interval.
+// It is shared by all copies of a Loggers instance.
+type rateLimiter struct {
+	maxPerInterval int
+	interval       time.Duration
+	windows        map[string]*rateLimitWindow
+	lock           sync.Mutex
+}
+
+type rateLimitWindow struct {
+	start      time.Time
+	count      int
+	suppressed int
+}
+
+// SetRateLimit limits how many times a message with the same template can be logged within each
+// interval. The template is the format string for methods such as Warnf, the message for methods such
+// as Warnw, and the entire text for methods such as Warn. Messages at different levels are counted
+// separately.
+//
+// Once the limit is reached, further messages with that template are suppressed until the interval
+// has passed; then a single message such as "Suppressed 25 similar messages in the last 1m0s: ..." is
+// logged at the same level. This prevents a problem that recurs many times, such as a feature store
+// outage or a flag that always fails to evaluate, from flooding the log.
+//
+// If maxPerInterval or interval is zero or negative, there is no limit; this is the default. The limit
+// applies to this Loggers instance and to any copies of it that are made afterward, including those
+// that the SDK components make from Config.Loggers.
+func (l *Loggers) SetRateLimit(maxPerInterval int, interval time.Duration) {
+	l.ensureInited()
+	if maxPerInterval <= 0 || interval <= 0 {
+		l.limiter = nil
+		return
+	}
+	l.limiter = &rateLimiter{
+		maxPerInterval: maxPerInterval,
+		interval:       interval,
+		windows:        make(map[string]*rateLimitWindow),
+	}
+}
+
+// allow returns true if a message with the given template can be logged now. If not, the message is
+// counted as suppressed, and report will be called with the number of suppressed messages at the end
+// of the current interval.
+func (r *rateLimiter) allow(level LogLevel, template string, report func(suppressed int)) bool {
+	key := level.String() + ":" + template
+	now := time.Now()
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	w := r.windows[key]
+	if w == nil || now.Sub(w.start) >= r.interval {
+		if len(r.windows) >= maxRateLimitWindows {
+			r.discardExpiredWindows(now)
+		}
+		r.windows[key] = &rateLimitWindow{start: now, count: 1}
+		return true
+	}
+	if w.count < r.maxPerInterval {
+		w.count++
+		return true
+	}
+	w.suppressed++
+	if w.suppressed == 1 {
+		time.AfterFunc(w.start.Add(r.interval).Sub(now), func() {
+			r.lock.Lock()
+			suppressed := w.suppressed
+			w.suppressed = 0
+			r.lock.Unlock()
+			report(suppressed)
+		})
+	}
+	return false
+}
+
+func (r *rateLimiter) discardExpiredWindows(now time.Time) {
+	for key, w := range r.windows {
+		if now.Sub(w.start) >= r.interval && w.suppressed == 0 {
+			delete(r.windows, key)
+		}
+	}
+}
+
+// allowed applies the rate limit, if any, to a message.
+func (ll levelLogger) allowed(template string) bool {
+	if ll.limiter == nil {
+		return true
+	}
+	return ll.limiter.allow(ll.level, template, func(suppressed int) {
+		ll.writeln(fmt.Sprintf("Suppressed %d similar messages in the last %s: %s", suppressed,
+			ll.limiter.interval, template))
+	})
+}
@@ -0,0 +1,98 @@
+package ldlog
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type syncLogSink struct {
+	output []string
+	lock   sync.Mutex
+}
+
+func (l *syncLogSink) Println(values ...interface{}) {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+	l.output = append(l.output, strings.TrimSpace(fmt.Sprintln(values...)))
+}
+
+func (l *syncLogSink) Printf(format string, values ...interface{}) {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+	l.output = append(l.output, fmt.Sprintf(format, values...))
+}
+
+func (l *syncLogSink) getOutput() []string {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+	return append([]string(nil), l.output...)
+}
+
+func TestRateLimitSuppressesMessagesWithSameTemplate(t *testing.T) {
+	ls := syncLogSink{}
+	l := Loggers{}
+	l.SetBaseLogger(&ls)
+	l.SetRateLimit(2, 100*time.Millisecond)
+	for i := 0; i < 5; i++ {
+		l.Warnf("error %d", i)
+		l.Errorw("store failed", FlagKey("x"))
+		l.Info("same text")
+	}
+	l.Warn("other text")
+
+	assert.Equal(t, []string{
+		"WARN: error 0", "ERROR: store failed flagKey=x", "INFO: same text",
+		"WARN: error 1", "ERROR: store failed flagKey=x", "INFO: same text",
+		"WARN: other text",
+	}, ls.getOutput())
+
+	time.Sleep(200 * time.Millisecond)
+	output := ls.getOutput()[7:]
+	assert.ElementsMatch(t, []string{
+		"WARN: Suppressed 3 similar messages in the last 100ms: error %d",
+		"ERROR: Suppressed 3 similar messages in the last 100ms: store failed",
+		"INFO: Suppressed 3 similar messages in the last 100ms: same text",
+	}, output)
+
+	l.Warnf("error %d", 5)
+	assert.Equal(t, "WARN: error 5", ls.getOutput()[10])
+}
+
+func TestRateLimitCountsLevelsSeparately(t *testing.T) {
+	ls := syncLogSink{}
+	l := Loggers{}
+	l.SetBaseLogger(&ls)
+	l.SetRateLimit(1, time.Minute)
+	l.Warn("a")
+	l.Error("a")
+	l.Warn("a")
+	assert.Equal(t, []string{"WARN: a", "ERROR: a"}, ls.getOutput())
+}
+
+func TestRateLimitIsSharedByCopies(t *testing.T) {
+	ls := syncLogSink{}
+	l := Loggers{}
+	l.SetBaseLogger(&ls)
+	l.SetRateLimit(1, time.Minute)
+	l1 := l.With(Component("x"))
+	l.Warn("a")
+	l1.Warn("a")
+	l1.ForLevel(Warn).Println("a")
+	assert.Equal(t, []string{"WARN: a"}, ls.getOutput())
+}
+
+func TestRateLimitCanBeDisabled(t *testing.T) {
+	ls := syncLogSink{}
+	l := Loggers{}
+	l.SetBaseLogger(&ls)
+	l.SetRateLimit(1, time.Minute)
+	l.SetRateLimit(0, 0)
+	l.Warn("a")
+	l.Warn("a")
+	assert.Equal(t, []string{"WARN: a", "WARN: a"}, ls.getOutput())
+}
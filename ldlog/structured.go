@@ -0,0 +1,211 @@
+package ldlog
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Keys of the fields that the SDK adds to log messages.
+const (
+	// FieldComponent identifies the SDK component that logged a message, such as "stream".
+	FieldComponent = "component"
+	// FieldFlagKey is the key of a feature flag.
+	FieldFlagKey = "flagKey"
+	// FieldKind is the kind of data item, "features" or "segments".
+	FieldKind = "kind"
+	// FieldKey is the key of a data item.
+	FieldKey = "key"
+	// FieldVersion is the version of a data item.
+	FieldVersion = "version"
+	// FieldStatusCode is an HTTP response status code.
+	FieldStatusCode = "statusCode"
+	// FieldError is an error.
+	FieldError = "error"
+)
+
+// Field is a named value that describes the context of a log message, such as the key of the flag
+// that it refers to. See StructuredLogger.
+type Field struct {
+	Key   string
+	Value interface{}
+}
+
+// NewField creates a Field.
+func NewField(key string, value interface{}) Field {
+	return Field{Key: key, Value: value}
+}
+
+// Component creates a Field that identifies an SDK component.
+func Component(name string) Field {
+	return Field{Key: FieldComponent, Value: name}
+}
+
+// FlagKey creates a Field for the key of a feature flag.
+func FlagKey(key string) Field {
+	return Field{Key: FieldFlagKey, Value: key}
+}
+
+// StatusCode creates a Field for an HTTP response status code.
+func StatusCode(code int) Field {
+	return Field{Key: FieldStatusCode, Value: code}
+}
+
+// Err creates a Field for an error.
+func Err(err error) Field {
+	return Field{Key: FieldError, Value: err}
+}
+
+// StructuredLogger is a logger that receives each message along with a level and a list of fields,
+// rather than as a single line of text. Use Loggers.SetStructuredLogger to send the SDK's log output to
+// one.
+//
+// Messages that the SDK logs with methods such as Loggers.Infow have their fields passed separately,
+// along with any fields that were added with Loggers.With. Messages logged with methods such as
+// Loggers.Infof are passed as formatted text, with only the fields from Loggers.With.
+type StructuredLogger interface {
+	Log(level LogLevel, message string, fields []Field)
+}
+
+// KeyValueLogger is the interface of loggers such as zap's SugaredLogger, which take a message and a
+// list of alternating keys and values. Use NewKeyValueLoggerAdapter to use one as a StructuredLogger.
+//
+// For other logging frameworks, it is simple to implement StructuredLogger directly. For instance, with
+// logrus:
+//
+//     func (a logrusAdapter) Log(level ldlog.LogLevel, message string, fields []ldlog.Field) {
+//         lf := make(logrus.Fields, len(fields))
+//         for _, f := range fields {
+//             lf[f.Key] = f.Value
+//         }
+//         a.logger.WithFields(lf).Log(logrusLevels[level], message)
+//     }
+type KeyValueLogger interface {
+	Debugw(message string, keysAndValues ...interface{})
+	Infow(message string, keysAndValues ...interface{})
+	Warnw(message string, keysAndValues ...interface{})
+	Errorw(message string, keysAndValues ...interface{})
+}
+
+type keyValueLoggerAdapter struct {
+	logger KeyValueLogger
+}
+
+// NewKeyValueLoggerAdapter returns a StructuredLogger that sends output to a KeyValueLogger:
+//
+//     zapLogger, _ := zap.NewProduction()
+//     config.Loggers.SetStructuredLogger(ldlog.NewKeyValueLoggerAdapter(zapLogger.Sugar()))
+func NewKeyValueLoggerAdapter(logger KeyValueLogger) StructuredLogger {
+	return keyValueLoggerAdapter{logger}
+}
+
+func (a keyValueLoggerAdapter) Log(level LogLevel, message string, fields []Field) {
+	keysAndValues := make([]interface{}, 0, len(fields)*2)
+	for _, f := range fields {
+		keysAndValues = append(keysAndValues, f.Key, f.Value)
+	}
+	switch level {
+	case Debug:
+		a.logger.Debugw(message, keysAndValues...)
+	case Info:
+		a.logger.Infow(message, keysAndValues...)
+	case Warn:
+		a.logger.Warnw(message, keysAndValues...)
+	case Error:
+		a.logger.Errorw(message, keysAndValues...)
+	}
+}
+
+type baseLoggerAdapter struct {
+	baseLogger BaseLogger
+}
+
+// NewBaseLoggerAdapter returns a StructuredLogger that writes each message as a single line to a
+// BaseLogger, such as a log.Logger from the standard library, followed by all of its fields in the form
+// key=value:
+//
+//     config.Loggers.SetStructuredLogger(ldlog.NewBaseLoggerAdapter(log.New(os.Stderr, "", log.LstdFlags)))
+//
+// This differs from using SetBaseLogger in that the fields added with Loggers.With are included.
+func NewBaseLoggerAdapter(baseLogger BaseLogger) StructuredLogger {
+	return baseLoggerAdapter{baseLogger}
+}
+
+func (a baseLoggerAdapter) Log(level LogLevel, message string, fields []Field) {
+	a.baseLogger.Println(strings.ToUpper(level.Name())+":", appendFields(message, fields))
+}
+
+// appendFields adds fields to a message in the form key=value, quoting values that contain spaces.
+func appendFields(message string, fields []Field) string {
+	for _, f := range fields {
+		value := fmt.Sprint(f.Value)
+		if value == "" || strings.ContainsAny(value, " =\"\t\n") {
+			value = strconv.Quote(value)
+		}
+		message += " " + f.Key + "=" + value
+	}
+	return message
+}
+
+// SetStructuredLogger specifies a StructuredLogger that receives output at all log levels, instead of
+// the BaseLogger. This does not apply to any levels whose BaseLogger has been overridden with
+// SetBaseLoggerForLevel. Level filtering still applies.
+//
+// If structuredLogger is nil, output goes to the BaseLogger again.
+func (l *Loggers) SetStructuredLogger(structuredLogger StructuredLogger) {
+	l.ensureInited()
+	l.structuredLogger = structuredLogger
+}
+
+// With returns a copy of the Loggers that adds the specified fields to every message that is sent to a
+// StructuredLogger. The fields are not included in output to a BaseLogger.
+func (l Loggers) With(fields ...Field) Loggers {
+	l.fields = append(append(make([]Field, 0, len(l.fields)+len(fields)), l.fields...), fields...)
+	return l
+}
+
+// Debugw logs a message at Debug level with fields, if that level is enabled. If there is no
+// StructuredLogger, the fields are written after the message in the form key=value.
+func (l Loggers) Debugw(message string, fields ...Field) {
+	l.logWithFields(Debug, message, fields)
+}
+
+// Infow logs a message at Info level with fields, if that level is enabled. If there is no
+// StructuredLogger, the fields are written after the message in the form key=value.
+func (l Loggers) Infow(message string, fields ...Field) {
+	l.logWithFields(Info, message, fields)
+}
+
+// Warnw logs a message at Warn level with fields, if that level is enabled. If there is no
+// StructuredLogger, the fields are written after the message in the form key=value.
+func (l Loggers) Warnw(message string, fields ...Field) {
+	l.logWithFields(Warn, message, fields)
+}
+
+// Errorw logs a message at Error level with fields, if that level is enabled. If there is no
+// StructuredLogger, the fields are written after the message in the form key=value.
+func (l Loggers) Errorw(message string, fields ...Field) {
+	l.logWithFields(Error, message, fields)
+}
+
+func (l Loggers) logWithFields(level LogLevel, message string, fields []Field) {
+	ll, ok := l.ForLevel(level).(levelLogger)
+	if !ok || !ll.enabled || !ll.allowed(message) {
+		return
+	}
+	if ll.structuredLogger != nil {
+		ll.logStructured(message, fields)
+		return
+	}
+	ll.writeln(appendFields(message, fields))
+}
+
+func (ll levelLogger) logStructured(message string, fields []Field) {
+	if ll.messagePrefix != "" {
+		message = ll.messagePrefix + " " + message
+	}
+	if len(ll.fields) > 0 {
+		fields = append(append(make([]Field, 0, len(ll.fields)+len(fields)), ll.fields...), fields...)
+	}
+	ll.structuredLogger.Log(ll.level, message, fields)
+}
@@ -0,0 +1,123 @@
+package ldlog
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type structuredSink struct {
+	output []string
+}
+
+func (s *structuredSink) Log(level LogLevel, message string, fields []Field) {
+	s.output = append(s.output, fmt.Sprintf("%s|%s|%v", level, message, fields))
+}
+
+type keyValueSink struct {
+	output []string
+}
+
+func (s *keyValueSink) add(level, message string, keysAndValues []interface{}) {
+	s.output = append(s.output, fmt.Sprintf("%s|%s|%v", level, message, keysAndValues))
+}
+
+func (s *keyValueSink) Debugw(message string, kv ...interface{}) { s.add("debug", message, kv) }
+func (s *keyValueSink) Infow(message string, kv ...interface{})  { s.add("info", message, kv) }
+func (s *keyValueSink) Warnw(message string, kv ...interface{})  { s.add("warn", message, kv) }
+func (s *keyValueSink) Errorw(message string, kv ...interface{}) { s.add("error", message, kv) }
+
+func TestFieldsAreAppendedToMessageForBaseLogger(t *testing.T) {
+	ls := logSink{}
+	l := Loggers{}
+	l.SetBaseLogger(&ls)
+	l = l.With(Component("stream")) // not shown in base logger output
+	l.Debugw("0", FlagKey("x"))
+	l.Infow("a", FlagKey("x"), StatusCode(401))
+	l.Warnw("b", Err(errors.New("sorry")), NewField("empty", ""))
+	l.Errorw("c")
+	assert.Equal(t, []string{`INFO: a flagKey=x statusCode=401`, `WARN: b error=sorry empty=""`, "ERROR: c"}, ls.output)
+}
+
+func TestStructuredLoggerReceivesMessagesAndFields(t *testing.T) {
+	ss := structuredSink{}
+	l := Loggers{}
+	l.SetStructuredLogger(&ss)
+	l.SetPrefix("Prefix:")
+	l = l.With(Component("stream"))
+	l.Infow("a", FlagKey("x"))
+	l.Warnf("%s!", "b")
+	l.Error("c", "d")
+	l.ForLevel(Info).Println("e")
+	l.Debug("not enabled")
+	assert.Equal(t, []string{
+		"Info|Prefix: a|[{component stream} {flagKey x}]",
+		"Warn|Prefix: b!|[{component stream}]",
+		"Error|Prefix: c d|[{component stream}]",
+		"Info|Prefix: e|[{component stream}]",
+	}, ss.output)
+}
+
+func TestWithDoesNotModifyOriginalLoggers(t *testing.T) {
+	ss := structuredSink{}
+	l := Loggers{}
+	l.SetStructuredLogger(&ss)
+	l1 := l.With(Component("a"))
+	l2 := l1.With(FlagKey("b"))
+	l3 := l1.With(FlagKey("c"))
+	l.Info("0")
+	l2.Info("2")
+	l3.Info("3")
+	assert.Equal(t, []string{
+		"Info|0|[]",
+		"Info|2|[{component a} {flagKey b}]",
+		"Info|3|[{component a} {flagKey c}]",
+	}, ss.output)
+}
+
+func TestBaseLoggerForSpecificLevelOverridesStructuredLogger(t *testing.T) {
+	ss := structuredSink{}
+	lsWarn := logSink{}
+	l := Loggers{}
+	l.SetStructuredLogger(&ss)
+	l.SetBaseLoggerForLevel(Warn, &lsWarn)
+	l.Infow("a", FlagKey("x"))
+	l.Warnw("b", FlagKey("x"))
+	assert.Equal(t, []string{"Info|a|[{flagKey x}]"}, ss.output)
+	assert.Equal(t, []string{"WARN: b flagKey=x"}, lsWarn.output)
+
+	l.SetStructuredLogger(nil)
+	ls := logSink{}
+	l.SetBaseLogger(&ls)
+	l.Info("c")
+	assert.Equal(t, []string{"INFO: c"}, ls.output)
+}
+
+func TestKeyValueLoggerAdapter(t *testing.T) {
+	kv := keyValueSink{}
+	l := Loggers{}
+	l.SetStructuredLogger(NewKeyValueLoggerAdapter(&kv))
+	l.SetMinLevel(Debug)
+	l = l.With(Component("events"))
+	l.Debugw("a", StatusCode(500))
+	l.Info("b")
+	l.Warn("c")
+	l.Errorw("d", FlagKey("x"))
+	assert.Equal(t, []string{
+		"debug|a|[component events statusCode 500]",
+		"info|b|[component events]",
+		"warn|c|[component events]",
+		"error|d|[component events flagKey x]",
+	}, kv.output)
+}
+
+func TestBaseLoggerAdapter(t *testing.T) {
+	ls := logSink{}
+	l := Loggers{}
+	l.SetStructuredLogger(NewBaseLoggerAdapter(&ls))
+	l = l.With(Component("events"))
+	l.Warnw("a", NewField("event", "put it"))
+	assert.Equal(t, []string{`WARN: a component=events event="put it"`}, ls.output)
+}
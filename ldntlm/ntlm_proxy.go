@@ -13,13 +13,20 @@
 //     config.HTTPClientFactory = clientFactory
 //     client, err := ld.MakeCustomClient("sdk-key", config, 5*time.Second)
 //
-// You can also specify TLS configuration options from the ldhttp package:
+// You can also specify TLS configuration options from the ldhttp package, such as an additional CA
+// certificate or a client certificate:
 //
 //     clientFactory, err := ldntlm.NewNTLMProxyHTTPClientFactory("http://my-proxy.com", "username",
-//         "password", "domain", ldhttp.CACertFileOption("extra-ca-cert.pem"))
+//         "password", "domain", ldhttp.CACertFileOption("extra-ca-cert.pem"),
+//         ldhttp.ClientCertFileOption("client-cert.pem", "client-key.pem"))
+//
+// The TLS options also apply to the connection to the proxy, if its URL is https, except for
+// ldhttp.ServerNameOption and the client certificate: those are meant for the server at the other end
+// of the tunnel, so the proxy connection uses the proxy's host name and presents no client certificate.
 package ldntlm
 
 import (
+	"crypto/tls"
 	"errors"
 	"fmt"
 	"net/http"
@@ -55,9 +62,22 @@ func NewNTLMProxyHTTPClientFactory(proxyURL, username, password, domain string,
 		allOpts = append(allOpts, options...)
 		if transport, dialer, err := ldhttp.NewHTTPTransport(allOpts...); err == nil {
 			transport.DialContext = ntlm.NewNTLMProxyDialContext(dialer, *parsedProxyURL,
-				username, password, domain, transport.TLSClientConfig)
+				username, password, domain, proxyTLSConfig(transport.TLSClientConfig))
 			client.Transport = transport
 		}
 		return client
 	}, nil
 }
+
+// proxyTLSConfig returns the TLS configuration for connecting to the proxy itself. It is the same as the
+// configuration for the target server, minus the settings that only make sense for that server.
+func proxyTLSConfig(config *tls.Config) *tls.Config {
+	if config == nil {
+		return nil
+	}
+	proxyConfig := config.Clone()
+	proxyConfig.ServerName = ""
+	proxyConfig.Certificates = nil
+	proxyConfig.GetClientCertificate = nil
+	return proxyConfig
+}
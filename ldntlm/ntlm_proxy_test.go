@@ -1,6 +1,7 @@
 package ldntlm
 
 import (
+	"crypto/tls"
 	"crypto/x509"
 	"fmt"
 	"io/ioutil"
@@ -9,6 +10,7 @@ import (
 	"regexp"
 	"testing"
 
+	helpers "github.com/launchdarkly/go-test-helpers"
 	"github.com/launchdarkly/go-test-helpers/httphelpers"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
@@ -66,6 +68,39 @@ func TestCanConnectSecurelyToNTLMProxyServerWithSelfSignedCert(t *testing.T) {
 	})
 }
 
+func TestTargetServerTLSOptionsAreNotUsedForProxyConnection(t *testing.T) {
+	helpers.WithTempFile(func(certFile string) {
+		helpers.WithTempFile(func(keyFile string) {
+			require.NoError(t, httphelpers.MakeSelfSignedCert(certFile, keyFile))
+			cert, err := tls.LoadX509KeyPair(certFile, keyFile)
+			require.NoError(t, err)
+			certData, err := ioutil.ReadFile(certFile)
+			require.NoError(t, err)
+
+			proxyHandler := makeFakeNTLMProxyHandler()
+			clientCertsSeen := 0
+			server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+				clientCertsSeen += len(req.TLS.PeerCertificates)
+				proxyHandler.ServeHTTP(w, req)
+			}))
+			server.TLS = &tls.Config{Certificates: []tls.Certificate{cert}, ClientAuth: tls.RequestClientCert}
+			server.StartTLS()
+			defer server.Close()
+
+			factory, err := NewNTLMProxyHTTPClientFactory(server.URL, username, password, domain,
+				ldhttp.CACertOption(certData), ldhttp.ServerNameOption("gateway.example.com"),
+				ldhttp.ClientCertFileOption(certFile, keyFile))
+			require.NoError(t, err)
+			client := factory(ld.DefaultConfig)
+
+			resp, err := client.Get(targetURL)
+			require.NoError(t, err)
+			assert.Equal(t, 200, resp.StatusCode)
+			assert.Equal(t, 0, clientCertsSeen)
+		})
+	})
+}
+
 func makeFakeNTLMProxyHandler() http.Handler {
 	step := 0
 	// This is an extremely minimal simulation of an NTLM proxy exchange:
@@ -3,6 +3,8 @@ package ldclient
 import (
 	"sync"
 	"time"
+
+	"gopkg.in/launchdarkly/go-server-sdk.v4/ldlog"
 )
 
 type pollingProcessor struct {
@@ -13,9 +15,11 @@ type pollingProcessor struct {
 	isInitialized      bool
 	quit               chan struct{}
 	closeOnce          sync.Once
+	errors             dataSourceErrorTracker
 }
 
 func newPollingProcessor(config Config, requestor *requestor) *pollingProcessor {
+	config.Loggers = config.Loggers.ForComponent(ldlog.ComponentPolling)
 	pp := &pollingProcessor{
 		store:     config.FeatureStore,
 		requestor: requestor,
@@ -50,9 +54,10 @@ func (pp *pollingProcessor) Start(closeWhenReady chan<- struct{}) {
 				return
 			case <-ticker.C:
 				if err := pp.poll(); err != nil {
-					pp.config.Loggers.Errorf("Error when requesting feature updates: %+v", err)
+					pp.errors.record(err)
+					pp.config.Loggers.Errorw("Error when requesting feature updates", ldlog.Err(err))
 					if hse, ok := err.(HttpStatusError); ok {
-						pp.config.Loggers.Error(httpErrorMessage(hse.Code, "polling request", "will retry"))
+						pp.config.Loggers.Errorw(httpErrorMessage(hse.Code, "polling request", "will retry"), ldlog.StatusCode(hse.Code))
 						if !isHTTPErrorRecoverable(hse.Code) {
 							notifyReady()
 							return
@@ -79,7 +84,8 @@ func (pp *pollingProcessor) poll() error {
 
 	// We initialize the store only if the request wasn't cached
 	if !cached {
-		return pp.store.Init(MakeAllVersionedDataMap(allData.Flags, allData.Segments))
+		return pp.store.Init(FilterInvalidData(MakeAllVersionedDataMap(allData.Flags, allData.Segments),
+			pp.config.Loggers, pp.config.RejectInvalidData))
 	}
 	return nil
 }
@@ -95,6 +101,10 @@ func (pp *pollingProcessor) Initialized() bool {
 	return pp.isInitialized
 }
 
+func (pp *pollingProcessor) getLastError() *dataSourceErrorInfo {
+	return pp.errors.get()
+}
+
 type tickerWithInitialTick struct {
 	*time.Ticker
 	C <-chan time.Time
@@ -359,6 +359,7 @@ func newRedisFeatureStoreInternal(configuredOptions redisFeatureStoreOptions, ld
 	}
 	core.loggers.SetBaseLogger(configuredOptions.logger) // has no effect if it is nil
 	core.loggers.SetPrefix("RedisFeatureStore:")
+	core.loggers = core.loggers.ForComponent(ldlog.ComponentStore)
 
 	if core.pool == nil {
 		core.loggers.Infof("Using url: %s", configuredOptions.redisURL)
@@ -412,7 +413,8 @@ func (store *redisFeatureStoreCore) GetInternal(kind ld.VersionedDataKind, key s
 
 	if err != nil {
 		if err == r.ErrNil {
-			store.loggers.Debugf("Key: %s not found in \"%s\"", key, kind.GetNamespace())
+			store.loggers.Debugw("Key not found", ldlog.NewField(ldlog.FieldKind, kind.GetNamespace()),
+				ldlog.NewField(ldlog.FieldKey, key))
 			return nil, nil
 		}
 		return nil, err
@@ -509,8 +511,9 @@ func (store *redisFeatureStoreCore) UpsertInternal(kind ld.VersionedDataKind, ne
 			if newItem.IsDeleted() {
 				updateOrDelete = "delete"
 			}
-			store.loggers.Debugf(`Attempted to %s key: %s version: %d in "%s" with a version that is the same or older: %d`,
-				updateOrDelete, key, oldItem.GetVersion(), kind.GetNamespace(), newItem.GetVersion())
+			store.loggers.Debugw("Attempted to "+updateOrDelete+" item with a version that is the same or older",
+				ldlog.NewField(ldlog.FieldKind, kind.GetNamespace()), ldlog.NewField(ldlog.FieldKey, key),
+				ldlog.NewField(ldlog.FieldVersion, newItem.GetVersion()), ldlog.NewField("storedVersion", oldItem.GetVersion()))
 			return oldItem, nil
 		}
 
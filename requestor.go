@@ -25,9 +25,9 @@ type requestor struct {
 func newRequestor(sdkKey string, config Config, httpClient *http.Client) *requestor {
 	var decoratedClient http.Client
 	if httpClient != nil {
-		decoratedClient = *httpClient
+		decoratedClient = *config.withRequestModifier(httpClient)
 	} else {
-		decoratedClient = *config.newHTTPClient()
+		decoratedClient = *config.withRequestModifier(config.newHTTPClient())
 	}
 	decoratedClient.Transport = &httpcache.Transport{
 		Cache:               httpcache.NewMemoryCache(),
@@ -91,8 +91,7 @@ func (r *requestor) makeRequest(resource string) ([]byte, bool, error) {
 	}
 	url := req.URL.String()
 
-	req.Header.Add("Authorization", r.sdkKey)
-	req.Header.Add("User-Agent", r.config.UserAgent)
+	addBaseHeaders(req, r.sdkKey, r.config)
 
 	res, resErr := r.httpClient.Do(req)
 
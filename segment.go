@@ -84,7 +84,15 @@ type SegmentExplanation struct {
 
 // ContainsUser returns whether a user belongs to the segment
 func (s Segment) ContainsUser(user User) (bool, *SegmentExplanation) {
-	if user.Key == nil {
+	return s.containsContext(NewContextFromUser(user))
+}
+
+// Segments are based on users: the Included and Excluded lists are compared to the key of the context's
+// "user" entity, and rules are bucketed by that entity. Clauses in segment rules may still refer to other
+// kinds with Clause.ContextKind.
+func (s Segment) containsContext(context Context) (bool, *SegmentExplanation) {
+	user, ok := context.Individual(DefaultContextKind)
+	if !ok || user.Key == nil {
 		return false, nil
 	}
 
@@ -104,7 +112,7 @@ func (s Segment) ContainsUser(user User) (bool, *SegmentExplanation) {
 
 	// Check if any of the segment rules match
 	for _, rule := range s.Rules {
-		if rule.MatchesUser(user, s.Key, s.Salt) {
+		if rule.matchesContext(context, s.Key, s.Salt) {
 			reason := rule
 			return true, &SegmentExplanation{Kind: "rule", MatchedRule: &reason}
 		}
@@ -115,8 +123,12 @@ func (s Segment) ContainsUser(user User) (bool, *SegmentExplanation) {
 
 // MatchesUser returns whether a rule applies to a user
 func (r SegmentRule) MatchesUser(user User, key, salt string) bool {
+	return r.matchesContext(NewContextFromUser(user), key, salt)
+}
+
+func (r SegmentRule) matchesContext(context Context, key, salt string) bool {
 	for _, clause := range r.Clauses {
-		if !clause.matchesUserNoSegments(user) {
+		if !clause.matchesContextNoSegments(context, nil) {
 			return false
 		}
 	}
@@ -134,7 +146,8 @@ func (r SegmentRule) MatchesUser(user User, key, salt string) bool {
 	}
 
 	// Check whether the user buckets into the segment
-	bucket := bucketUser(user, key, bucketBy, salt)
+	user, _ := context.Individual(DefaultContextKind)
+	bucket := bucketUser(user, key, bucketBy, salt, nil)
 	weight := float32(*r.Weight) / 100000.0
 
 	return bucket < weight
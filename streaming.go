@@ -39,6 +39,7 @@ type streamProcessor struct {
 	connectionAttemptLock      sync.Mutex
 	readyOnce                  sync.Once
 	closeOnce                  sync.Once
+	errors                     dataSourceErrorTracker
 }
 
 type putData struct {
@@ -66,6 +67,10 @@ func (sp *streamProcessor) Initialized() bool {
 	return sp.isInitialized
 }
 
+func (sp *streamProcessor) getLastError() *dataSourceErrorInfo {
+	return sp.errors.get()
+}
+
 func (sp *streamProcessor) Start(closeWhenReady chan<- struct{}) {
 	sp.config.Loggers.Info("Starting LaunchDarkly streaming connection")
 	if fss, ok := sp.store.(internal.FeatureStoreStatusProvider); ok {
@@ -93,6 +98,10 @@ func parsePath(path string) (parsedPath, error) {
 	return parsedPath, nil
 }
 
+func (p parsedPath) logFields() []ldlog.Field {
+	return []ldlog.Field{ldlog.NewField(ldlog.FieldKind, p.kind.GetNamespace()), ldlog.NewField(ldlog.FieldKey, p.key)}
+}
+
 // Process events from the stream until it's time to close the stream.
 //
 // This returns true if we should recreate the stream and start over, or false if we should give up and never retry.
@@ -142,16 +151,20 @@ func (sp *streamProcessor) consumeStream(stream *es.Stream, closeWhenReady chan<
 			shouldRestart := false
 
 			gotMalformedEvent := func(event es.Event, err error) {
-				sp.config.Loggers.Errorf("Received streaming \"%s\" event with malformed JSON data (%s); will restart stream", event.Event(), err)
+				sp.config.Loggers.Errorw("Received streaming event with malformed JSON data; will restart stream",
+					ldlog.NewField("event", event.Event()), ldlog.Err(err))
 				shouldRestart = true // scenario 1 above
 			}
 
-			storeUpdateFailed := func(updateDesc string, err error) {
+			storeUpdateFailed := func(updateDesc string, err error, fields ...ldlog.Field) {
+				fields = append(fields, ldlog.Err(err))
 				if sp.storeStatusSub != nil {
-					sp.config.Loggers.Errorf("Failed to store %s in data store (%s); will try again once data store is working", updateDesc, err)
+					sp.config.Loggers.Errorw("Failed to store "+updateDesc+" in data store; will try again once data store is working",
+						fields...)
 					// scenario 2a above
 				} else {
-					sp.config.Loggers.Errorf("Failed to store %s in data store (%s); will restart stream until successful", updateDesc, err)
+					sp.config.Loggers.Errorw("Failed to store "+updateDesc+" in data store; will restart stream until successful",
+						fields...)
 					shouldRestart = true // scenario 2b above
 				}
 			}
@@ -163,7 +176,8 @@ func (sp *streamProcessor) consumeStream(stream *es.Stream, closeWhenReady chan<
 					gotMalformedEvent(event, err)
 					break
 				}
-				err := sp.store.Init(MakeAllVersionedDataMap(put.Data.Flags, put.Data.Segments))
+				err := sp.store.Init(FilterInvalidData(MakeAllVersionedDataMap(put.Data.Flags, put.Data.Segments),
+					sp.config.Loggers, sp.config.RejectInvalidData))
 				if err == nil {
 					sp.setInitializedAndNotifyClient(true, closeWhenReady)
 				} else {
@@ -186,8 +200,11 @@ func (sp *streamProcessor) consumeStream(stream *es.Stream, closeWhenReady chan<
 					gotMalformedEvent(event, err)
 					break
 				}
+				if !validateUpdatedItem(path.kind, item, sp.store, sp.config.Loggers, sp.config.RejectInvalidData) {
+					break
+				}
 				if err = sp.store.Upsert(path.kind, item); err != nil {
-					storeUpdateFailed("streaming update of "+path.key, err)
+					storeUpdateFailed("streaming update", err, path.logFields()...)
 				}
 
 			case deleteEvent:
@@ -202,7 +219,7 @@ func (sp *streamProcessor) consumeStream(stream *es.Stream, closeWhenReady chan<
 					break
 				}
 				if err = sp.store.Delete(path.kind, path.key, data.Version); err != nil {
-					storeUpdateFailed("streaming deletion of "+path.key, err)
+					storeUpdateFailed("streaming deletion", err, path.logFields()...)
 				}
 
 			case indirectPatchEvent:
@@ -212,14 +229,17 @@ func (sp *streamProcessor) consumeStream(stream *es.Stream, closeWhenReady chan<
 				}
 				item, requestErr := sp.requestor.requestResource(path.kind, path.key)
 				if requestErr != nil {
-					sp.config.Loggers.Errorf(`Unexpected error requesting %s item "%s": %+v`, path.kind, path.key, err)
+					sp.config.Loggers.Errorw("Unexpected error requesting item", append(path.logFields(), ldlog.Err(requestErr))...)
+					break
+				}
+				if !validateUpdatedItem(path.kind, item, sp.store, sp.config.Loggers, sp.config.RejectInvalidData) {
 					break
 				}
 				if err = sp.store.Upsert(path.kind, item); err != nil {
-					storeUpdateFailed("streaming update of "+path.key, err)
+					storeUpdateFailed("streaming update", err, path.logFields()...)
 				}
 			default:
-				sp.config.Loggers.Infof("Unexpected event found in stream: %s", event.Event())
+				sp.config.Loggers.Infow("Unexpected event found in stream", ldlog.NewField("event", event.Event()))
 			}
 
 			if shouldRestart {
@@ -249,6 +269,7 @@ func (sp *streamProcessor) consumeStream(stream *es.Stream, closeWhenReady chan<
 }
 
 func newStreamProcessor(sdkKey string, config Config, requestor *requestor) *streamProcessor {
+	config.Loggers = config.Loggers.ForComponent(ldlog.ComponentStream)
 	sp := &streamProcessor{
 		store:     config.FeatureStore,
 		config:    config,
@@ -257,7 +278,7 @@ func newStreamProcessor(sdkKey string, config Config, requestor *requestor) *str
 		halt:      make(chan struct{}),
 	}
 
-	sp.client = config.newHTTPClient()
+	sp.client = config.withRequestModifier(config.newHTTPClient())
 	// Client.Timeout isn't just a connect timeout, it will break the connection if a full response
 	// isn't received within that time (which, with the stream, it never will be), so we must make
 	// sure it's zero and not the usual configured default. What we do want is a *connection* timeout,
@@ -281,6 +302,7 @@ func (sp *streamProcessor) subscribe(closeWhenReady chan<- struct{}) {
 
 	errorHandler := func(err error) es.StreamErrorHandlerResult {
 		sp.logConnectionResult(false)
+		sp.errors.record(err)
 		shouldStreamShutDown := sp.checkIfPermanentFailure(err) // this also logs the error
 		if !shouldStreamShutDown {
 			sp.logConnectionStarted()
@@ -302,6 +324,7 @@ func (sp *streamProcessor) subscribe(closeWhenReady chan<- struct{}) {
 
 	if err != nil {
 		sp.logConnectionResult(false)
+		sp.errors.record(err)
 
 		close(closeWhenReady)
 		return
@@ -324,10 +347,10 @@ func (sp *streamProcessor) setInitializedAndNotifyClient(success bool, closeWhen
 
 func (sp *streamProcessor) checkIfPermanentFailure(err error) bool {
 	if se, ok := err.(es.SubscriptionError); ok {
-		sp.config.Loggers.Error(httpErrorMessage(se.Code, "streaming connection", "will retry"))
+		sp.config.Loggers.Errorw(httpErrorMessage(se.Code, "streaming connection", "will retry"), ldlog.StatusCode(se.Code))
 		return !isHTTPErrorRecoverable(se.Code)
 	}
-	sp.config.Loggers.Errorf("Network error on streaming connection: %s", err.Error())
+	sp.config.Loggers.Errorw("Network error on streaming connection", ldlog.Err(err))
 	return false
 }
 
@@ -19,6 +19,11 @@ import (
 )
 
 func runStreamingTest(t *testing.T, initialEvent eventsource.Event, test func(events chan<- eventsource.Event, store FeatureStore)) {
+	runStreamingTestWithConfig(t, nil, initialEvent, test)
+}
+
+func runStreamingTestWithConfig(t *testing.T, modConfig func(*Config), initialEvent eventsource.Event,
+	test func(events chan<- eventsource.Event, store FeatureStore)) {
 	events := make(chan eventsource.Event, 1000)
 	streamHandler, _ := ldservices.ServerSideStreamingServiceHandler(initialEvent, events)
 	httphelpers.WithServer(streamHandler, func(streamServer *httptest.Server) {
@@ -36,6 +41,9 @@ func runStreamingTest(t *testing.T, initialEvent eventsource.Event, test func(ev
 				BaseUri:      sdkServer.URL,
 				Loggers:      ldlog.NewDefaultLoggers(),
 			}
+			if modConfig != nil {
+				modConfig(&cfg)
+			}
 
 			requestor := newRequestor("sdkKey", cfg, nil)
 			sp := newStreamProcessor("sdkKey", cfg, requestor)
@@ -108,6 +116,19 @@ func TestStreamProcessor(t *testing.T) {
 			waitForVersion(t, store, Features, "my-flag", 5)
 		})
 	})
+
+	t.Run("invalid flag patch is rejected", func(t *testing.T) {
+		rejectInvalid := func(c *Config) { c.RejectInvalidData = true }
+		runStreamingTestWithConfig(t, rejectInvalid, initialData, func(events chan<- eventsource.Event, store FeatureStore) {
+			events <- ldservices.NewSSEEvent("", patchEvent,
+				`{"path": "/flags/my-flag", "data": {"key": "my-flag", "version": 3, "offVariation": 1}}`)
+			events <- ldservices.NewSSEEvent("", patchEvent, `{"path": "/segments/my-segment", "data": {"key": "my-segment", "version": 3}}`)
+
+			waitForVersion(t, store, Segments, "my-segment", 3)
+			flag, _ := store.Get(Features, "my-flag")
+			assert.Equal(t, 2, flag.GetVersion())
+		})
+	})
 }
 
 func waitForVersion(t *testing.T, store FeatureStore, kind VersionedDataKind, key string, version int) VersionedData {
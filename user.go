@@ -2,6 +2,7 @@ package ldclient
 
 import (
 	"encoding/json"
+	"strings"
 	"time"
 
 	"gopkg.in/launchdarkly/go-sdk-common.v1/ldvalue"
@@ -257,9 +258,25 @@ func (u User) String() string {
 	return string(bytes)
 }
 
-// Used internally in evaluations. The second return value is true if the attribute exists for this user,
-// false if not.
+// Used internally in evaluations. The attr parameter is an attribute reference, which may be either a
+// plain attribute name or a slash-delimited path into a JSON object or array (see attribute_ref.go). The
+// second return value is true if the attribute exists for this user, false if not.
 func (u User) valueOf(attr string) (interface{}, bool) {
+	if strings.HasPrefix(attr, "/") {
+		path, ok := parseAttributeRef(attr)
+		if !ok {
+			return nil, false
+		}
+		value, found := u.topLevelValueOf(path[0])
+		if !found || len(path) == 1 {
+			return value, found
+		}
+		return getValueAtPath(value, path[1:])
+	}
+	return u.topLevelValueOf(attr)
+}
+
+func (u User) topLevelValueOf(attr string) (interface{}, bool) {
 	if attr == "key" {
 		if u.Key != nil {
 			return *u.Key, true
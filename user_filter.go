@@ -14,6 +14,7 @@ type serializableUser struct {
 type userFilter struct {
 	allAttributesPrivate    bool
 	globalPrivateAttributes []string
+	redactionRules          []AttributeRedactionRule
 	loggers                 ldlog.Loggers
 	logUserKeyInErrors      bool
 }
@@ -22,6 +23,7 @@ func newUserFilter(config Config) userFilter {
 	return userFilter{
 		allAttributesPrivate:    config.AllAttributesPrivate,
 		globalPrivateAttributes: config.PrivateAttributeNames,
+		redactionRules:          config.AttributeRedactionRules,
 		loggers:                 config.Loggers,
 		logUserKeyInErrors:      config.LogUserKeyInErrors,
 	}
@@ -34,7 +36,8 @@ const userSerializationErrorMessage = "An error occurred while processing custom
 // Returns a version of the user data that is suitable for JSON serialization in event data.
 // If neither the configuration nor the user specifies any private attributes, then this is the same
 // as the original user. Otherwise, it is a copy which may have some attributes removed (with the
-// PrivateAttributes property set to a list of their names).
+// PrivateAttributes property set to a list of their names), and may have some attribute values
+// transformed by the configured AttributeRedactionRules.
 //
 // This function, and the custom marshaller for serializableUser, also guard against a potential
 // concurrent modification error on the user's custom attributes map. We can't prevent someone in
@@ -44,16 +47,25 @@ const userSerializationErrorMessage = "An error occurred while processing custom
 // way to know whether they are still correct after the concurrent modification).
 func (uf *userFilter) scrubUser(user User) (ret *serializableUser) {
 	ret = &serializableUser{User: user, filter: uf}
-	if len(user.PrivateAttributeNames) == 0 && len(uf.globalPrivateAttributes) == 0 && !uf.allAttributesPrivate {
+	if len(user.PrivateAttributeNames) == 0 && len(uf.globalPrivateAttributes) == 0 && !uf.allAttributesPrivate &&
+		len(uf.redactionRules) == 0 {
 		return
 	}
 
 	isPrivate := map[string]bool{}
-	for _, n := range uf.globalPrivateAttributes {
-		isPrivate[n] = true
-	}
-	for _, n := range user.PrivateAttributeNames {
-		isPrivate[n] = true
+	privatePaths := map[string][]string{} // nested references within custom attributes, keyed by reference
+	for _, names := range [][]string{uf.globalPrivateAttributes, user.PrivateAttributeNames} {
+		for _, n := range names {
+			path, ok := parseAttributeRef(n)
+			switch {
+			case !ok:
+				continue
+			case len(path) == 1:
+				isPrivate[path[0]] = true
+			default:
+				privatePaths[n] = path
+			}
+		}
 	}
 	ret.User.PrivateAttributeNames = nil // this property is not used in the output schema for events
 	ret.User.PrivateAttributes = nil     // see below
@@ -117,6 +129,14 @@ func (uf *userFilter) scrubUser(user User) (ret *serializableUser) {
 			if uf.allAttributesPrivate || isPrivate[k] {
 				ret.User.PrivateAttributes = append(ret.User.PrivateAttributes, k)
 			} else {
+				for ref, path := range privatePaths {
+					if path[0] == k {
+						var removed bool
+						if v, removed = removeValueAtPath(v, path[1:]); removed {
+							ret.User.PrivateAttributes = append(ret.User.PrivateAttributes, ref)
+						}
+					}
+				}
 				custom[k] = v
 			}
 		}
@@ -127,6 +147,10 @@ func (uf *userFilter) scrubUser(user User) (ret *serializableUser) {
 		}
 	}
 
+	if len(uf.redactionRules) > 0 {
+		uf.applyRedactionRules(&ret.User)
+	}
+
 	return ret
 }
 
@@ -69,6 +69,32 @@ func TestScrubUser(t *testing.T) {
 		assert.NotContains(t, *scrubbedUser.Custom, "my-secret-attr")
 	})
 
+	t.Run("private nested custom attribute", func(t *testing.T) {
+		filter := newUserFilter(Config{PrivateAttributeNames: []string{"/address/street", "/address/missing"}})
+		address := ldvalue.ObjectBuild().
+			Set("street", ldvalue.String("1 Main St")).
+			Set("city", ldvalue.String("Oakland")).
+			Build()
+		user := NewUserBuilder("user-key").Custom("address", address).Build()
+
+		scrubbedUser := *filter.scrubUser(user)
+
+		assert.Equal(t, []string{"/address/street"}, scrubbedUser.PrivateAttributes)
+		assert.Equal(t, map[string]interface{}{"city": "Oakland"}, (*scrubbedUser.Custom)["address"])
+		original, _ := user.GetCustom("address")
+		assert.Equal(t, "1 Main St", original.GetByKey("street").StringValue())
+	})
+
+	t.Run("single-component reference is the same as plain name", func(t *testing.T) {
+		filter := newUserFilter(Config{PrivateAttributeNames: []string{"/email"}})
+		user := NewUserBuilder("user-key").Email("me@example.com").Build()
+
+		scrubbedUser := *filter.scrubUser(user)
+
+		assert.Equal(t, []string{"email"}, scrubbedUser.PrivateAttributes)
+		assert.Nil(t, scrubbedUser.Email)
+	})
+
 	t.Run("all attributes private", func(t *testing.T) {
 		filter := newUserFilter(Config{AllAttributesPrivate: true})
 		userKey := "userKey"
@@ -160,6 +160,49 @@ func addBaseHeaders(req *http.Request, sdkKey string, config Config) {
 		}
 		req.Header.Add("X-LaunchDarkly-Wrapper", w)
 	}
+	for name, values := range config.HTTPHeaders {
+		for _, value := range values {
+			req.Header.Add(name, value)
+		}
+	}
+}
+
+// requestModifierTransport is an http.RoundTripper that applies Config.RequestModifier to each request.
+type requestModifierTransport struct {
+	modifier  RequestModifier
+	transport http.RoundTripper
+}
+
+func (t requestModifierTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	// A RoundTripper must not modify the original request, so the modifier gets a copy.
+	modifiedReq := new(http.Request)
+	*modifiedReq = *req
+	modifiedReq.Header = make(http.Header, len(req.Header))
+	for name, values := range req.Header {
+		modifiedReq.Header[name] = append([]string(nil), values...)
+	}
+	if err := t.modifier(modifiedReq); err != nil {
+		if req.Body != nil {
+			_ = req.Body.Close()
+		}
+		return nil, err
+	}
+	transport := t.transport
+	if transport == nil {
+		transport = http.DefaultTransport
+	}
+	return transport.RoundTrip(modifiedReq)
+}
+
+// withRequestModifier returns a copy of the HTTP client that applies Config.RequestModifier to each
+// request, or the same client if there is no RequestModifier.
+func (c Config) withRequestModifier(client *http.Client) *http.Client {
+	if c.RequestModifier == nil {
+		return client
+	}
+	modifiedClient := *client
+	modifiedClient.Transport = requestModifierTransport{modifier: c.RequestModifier, transport: client.Transport}
+	return &modifiedClient
 }
 
 // Tests whether an HTTP error status represents a condition that might resolve on its own if we retry,
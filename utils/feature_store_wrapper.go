@@ -195,7 +195,7 @@ func newBaseWrapper(core FeatureStoreCoreBase, config ld.Config) *FeatureStoreWr
 	w := &FeatureStoreWrapper{
 		core:    core,
 		cache:   myCache,
-		loggers: config.Loggers,
+		loggers: config.Loggers.ForComponent(ldlog.ComponentStore),
 	}
 	if cs, ok := core.(FeatureStoreCoreStatus); ok {
 		w.coreStatus = cs
@@ -204,7 +204,7 @@ func newBaseWrapper(core FeatureStoreCoreBase, config ld.Config) *FeatureStoreWr
 		true,
 		w.pollAvailabilityAfterOutage,
 		myCache == nil || core.GetCacheTTL() > 0, // needsRefresh=true unless we're in infinite cache mode
-		config.Loggers,
+		w.loggers,
 	)
 
 	return w
@@ -497,7 +497,7 @@ func (w *FeatureStoreWrapper) pollAvailabilityAfterOutage() bool {
 			// We failed to write the cached data to the underlying store. In this case,
 			// w.initCore() has already put us back into the failed state. The only further
 			// thing we can do is to log a note about what just happened.
-			w.loggers.Errorf("Tried to write cached data to persistent store after a store outage, but failed: %s", err)
+			w.loggers.Errorw("Tried to write cached data to persistent store after a store outage, but failed", ldlog.Err(err))
 		} else {
 			w.loggers.Warn("Successfully updated persistent store from cached data")
 			// Note that w.inited should have already been set when InitInternal was originally called -
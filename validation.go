@@ -0,0 +1,341 @@
+package ldclient
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+
+	"gopkg.in/launchdarkly/go-server-sdk.v4/ldlog"
+)
+
+const totalRolloutWeight = 100000
+
+// DataValidationIssue describes a problem with a feature flag or segment that was found by ValidateData.
+// Such problems are not detected by the JSON parser, but would cause evaluations to fail with a
+// MALFORMED_FLAG error or to behave in a way that was probably not intended.
+type DataValidationIssue struct {
+	// Kind is either Features or Segments.
+	Kind VersionedDataKind
+	// Key is the key of the flag or segment.
+	Key string
+	// Message describes the problem.
+	Message string
+}
+
+// Error returns a description of the issue that includes the kind and key of the item.
+func (i DataValidationIssue) Error() string {
+	return fmt.Sprintf("%s '%s': %s", i.Kind.GetNamespace(), i.Key, i.Message)
+}
+
+// ValidateData checks a complete set of feature flags and segments, as would be passed to
+// FeatureStore.Init, and returns any problems that it finds. These include:
+//
+// - variation indexes that do not refer to an existing variation;
+//
+// - rules, and fallthroughs of flags that are on, that have neither a variation nor a rollout;
+//
+// - rollouts whose weights do not add up to 100000;
+//
+// - prerequisites that refer to each other in a cycle;
+//
+// - clauses with an unknown operator, or with a "matches" operator and a value that is not a valid
+// regular expression;
+//
+// - segment rules whose weights are outside the range 0 to 100000.
+//
+// A flag whose prerequisite does not exist is not considered invalid, since evaluation handles that
+// case in a well-defined way. The issues are sorted by kind and key.
+func ValidateData(allData map[VersionedDataKind]map[string]VersionedData) []DataValidationIssue {
+	var issues []DataValidationIssue
+	flags := make(map[string]*FeatureFlag)
+	for kind, items := range allData {
+		for key, item := range items {
+			issues = append(issues, validateItem(kind, key, item)...)
+			if flag, ok := item.(*FeatureFlag); ok && kind == Features {
+				flags[key] = flag
+			}
+		}
+	}
+	for key, cycle := range findPrerequisiteCycles(flags) {
+		issues = append(issues, newPrerequisiteCycleIssue(key, cycle))
+	}
+	sort.SliceStable(issues, func(i, j int) bool {
+		if issues[i].Kind.GetNamespace() != issues[j].Kind.GetNamespace() {
+			return issues[i].Kind.GetNamespace() < issues[j].Kind.GetNamespace()
+		}
+		return issues[i].Key < issues[j].Key
+	})
+	return issues
+}
+
+// FilterInvalidData checks a complete set of feature flags and segments with ValidateData, and logs a
+// warning for each problem that it finds. If reject is true, it returns a copy of the data that omits
+// the invalid items; otherwise it returns the data unchanged. The SDK's data sources call this before
+// passing data to the feature store (see Config.RejectInvalidData), and custom UpdateProcessor
+// implementations can do the same.
+func FilterInvalidData(allData map[VersionedDataKind]map[string]VersionedData, loggers ldlog.Loggers,
+	reject bool) map[VersionedDataKind]map[string]VersionedData {
+	issues := ValidateData(allData)
+	if len(issues) == 0 {
+		return allData
+	}
+	logDataValidationIssues(issues, loggers, reject)
+	if !reject {
+		return allData
+	}
+	invalid := make(map[VersionedDataKind]map[string]bool)
+	for _, issue := range issues {
+		if invalid[issue.Kind] == nil {
+			invalid[issue.Kind] = make(map[string]bool)
+		}
+		invalid[issue.Kind][issue.Key] = true
+	}
+	filtered := make(map[VersionedDataKind]map[string]VersionedData, len(allData))
+	for kind, items := range allData {
+		filtered[kind] = make(map[string]VersionedData, len(items))
+		for key, item := range items {
+			if !invalid[kind][key] {
+				filtered[kind][key] = item
+			}
+		}
+	}
+	return filtered
+}
+
+// validateUpdatedItem checks a single flag or segment that is about to be stored with Upsert. Besides
+// checking the item itself, it checks whether an updated flag would create a prerequisite cycle with
+// the flags that are already in the store. It returns false if the item should be discarded.
+func validateUpdatedItem(kind VersionedDataKind, item VersionedData, store FeatureStore,
+	loggers ldlog.Loggers, reject bool) bool {
+	issues := validateItem(kind, item.GetKey(), item)
+	if flag, ok := item.(*FeatureFlag); ok && kind == Features && len(flag.Prerequisites) > 0 && !flag.Deleted {
+		if cycle, found := findPrerequisiteCycleInStore(flag, store); found {
+			issues = append(issues, newPrerequisiteCycleIssue(flag.Key, cycle))
+		}
+	}
+	logDataValidationIssues(issues, loggers, reject)
+	return len(issues) == 0 || !reject
+}
+
+func logDataValidationIssues(issues []DataValidationIssue, loggers ldlog.Loggers, reject bool) {
+	for _, issue := range issues {
+		if reject {
+			loggers.Warnf("Invalid data will be ignored: %s", issue)
+		} else {
+			loggers.Warnf("Invalid data: %s", issue)
+		}
+	}
+}
+
+func validateItem(kind VersionedDataKind, key string, item VersionedData) []DataValidationIssue {
+	var messages []string
+	switch i := item.(type) {
+	case *FeatureFlag:
+		if !i.Deleted {
+			messages = i.validate()
+		}
+	case *Segment:
+		if !i.Deleted {
+			messages = i.validate()
+		}
+	}
+	issues := make([]DataValidationIssue, 0, len(messages))
+	for _, m := range messages {
+		issues = append(issues, DataValidationIssue{Kind: kind, Key: key, Message: m})
+	}
+	return issues
+}
+
+func newPrerequisiteCycleIssue(key string, cycle []string) DataValidationIssue {
+	return DataValidationIssue{
+		Kind:    Features,
+		Key:     key,
+		Message: "prerequisites form a cycle: " + strings.Join(cycle, " -> "),
+	}
+}
+
+func (f FeatureFlag) validate() []string {
+	var messages []string
+	checkVariation := func(desc string, index int) {
+		if index < 0 || index >= len(f.Variations) {
+			messages = append(messages, fmt.Sprintf("%s refers to variation %d, but there are %d variations",
+				desc, index, len(f.Variations)))
+		}
+	}
+	if f.OffVariation != nil {
+		checkVariation("offVariation", *f.OffVariation)
+	}
+	for i, target := range f.Targets {
+		checkVariation(fmt.Sprintf("target %d", i), target.Variation)
+	}
+	for i, rule := range f.Rules {
+		desc := fmt.Sprintf("rule %d", i)
+		if rule.ID != "" {
+			desc = fmt.Sprintf("rule %d (%s)", i, rule.ID)
+		}
+		for _, m := range validateClauses(rule.Clauses) {
+			messages = append(messages, desc+": "+m)
+		}
+		messages = append(messages, rule.VariationOrRollout.validate(desc, checkVariation)...)
+	}
+	if f.On { // the fallthrough of a flag that is off is never used, and is often omitted
+		messages = append(messages, f.Fallthrough.validate("fallthrough", checkVariation)...)
+	}
+	return messages
+}
+
+func (r VariationOrRollout) validate(desc string, checkVariation func(string, int)) []string {
+	switch {
+	case r.Variation != nil:
+		checkVariation(desc, *r.Variation)
+	case r.Rollout != nil:
+		if len(r.Rollout.Variations) == 0 {
+			return []string{desc + " has a rollout with no variations"}
+		}
+		total := 0
+		for _, wv := range r.Rollout.Variations {
+			checkVariation(desc+" rollout", wv.Variation)
+			total += wv.Weight
+		}
+		if total != totalRolloutWeight {
+			return []string{fmt.Sprintf("%s has rollout weights that add up to %d instead of %d",
+				desc, total, totalRolloutWeight)}
+		}
+	default:
+		return []string{desc + " has neither a variation nor a rollout"}
+	}
+	return nil
+}
+
+func (s Segment) validate() []string {
+	var messages []string
+	for i, rule := range s.Rules {
+		desc := fmt.Sprintf("rule %d", i)
+		if rule.Id != "" {
+			desc = fmt.Sprintf("rule %d (%s)", i, rule.Id)
+		}
+		for _, m := range validateClauses(rule.Clauses) {
+			messages = append(messages, desc+": "+m)
+		}
+		if rule.Weight != nil && (*rule.Weight < 0 || *rule.Weight > totalRolloutWeight) {
+			messages = append(messages, fmt.Sprintf("%s has weight %d, which is not between 0 and %d",
+				desc, *rule.Weight, totalRolloutWeight))
+		}
+	}
+	return messages
+}
+
+func validateClauses(clauses []Clause) []string {
+	var messages []string
+	for i, clause := range clauses {
+		if _, known := allOps[clause.Op]; !known && clause.Op != OperatorSegmentMatch {
+			messages = append(messages, fmt.Sprintf("clause %d has unknown operator %q", i, clause.Op))
+			continue
+		}
+		if clause.Op == OperatorMatches {
+			for _, value := range clause.Values {
+				pattern, ok := value.(string)
+				if !ok {
+					continue // non-string values are ignored by the operator, rather than being an error
+				}
+				if _, err := regexp.Compile(pattern); err != nil {
+					messages = append(messages, fmt.Sprintf("clause %d has invalid regular expression %q: %s",
+						i, pattern, err))
+				}
+			}
+		}
+	}
+	return messages
+}
+
+// findPrerequisiteCycleInStore checks whether a flag that is about to be stored would be part of a
+// prerequisite cycle. Only the flags that can be reached from its prerequisites are read from the store,
+// since reading all flags could be expensive for a persistent store that is not cached.
+func findPrerequisiteCycleInStore(flag *FeatureFlag, store FeatureStore) ([]string, bool) {
+	visited := map[string]bool{flag.Key: true}
+	path := []string{flag.Key}
+	var visit func(f *FeatureFlag) bool
+	visit = func(f *FeatureFlag) bool {
+		for _, prereq := range f.Prerequisites {
+			if prereq.Key == flag.Key {
+				path = append(path, flag.Key)
+				return true
+			}
+			if visited[prereq.Key] {
+				continue
+			}
+			visited[prereq.Key] = true
+			data, err := store.Get(Features, prereq.Key)
+			if err != nil {
+				continue
+			}
+			prereqFlag, ok := data.(*FeatureFlag)
+			if !ok || prereqFlag.Deleted {
+				continue
+			}
+			path = append(path, prereq.Key)
+			if visit(prereqFlag) {
+				return true
+			}
+			path = path[:len(path)-1]
+		}
+		return false
+	}
+	if visit(flag) {
+		return path, true
+	}
+	return nil, false
+}
+
+// findPrerequisiteCycles returns the key of every flag that is part of a prerequisite cycle, mapped
+// to the sequence of keys that forms the cycle, starting and ending with that flag's key.
+func findPrerequisiteCycles(flags map[string]*FeatureFlag) map[string][]string {
+	const (
+		unvisited = iota
+		inProgress
+		done
+	)
+	state := make(map[string]int, len(flags))
+	cycles := make(map[string][]string)
+	var path []string
+	var visit func(key string)
+	visit = func(key string) {
+		state[key] = inProgress
+		path = append(path, key)
+		for _, prereq := range flags[key].Prerequisites {
+			if _, exists := flags[prereq.Key]; !exists {
+				continue
+			}
+			switch state[prereq.Key] {
+			case unvisited:
+				visit(prereq.Key)
+			case inProgress:
+				start := len(path) - 1
+				for path[start] != prereq.Key {
+					start--
+				}
+				cycle := path[start:]
+				for i, k := range cycle {
+					if _, found := cycles[k]; !found {
+						rotated := append(append([]string{}, cycle[i:]...), cycle[:i]...)
+						cycles[k] = append(rotated, k)
+					}
+				}
+			}
+		}
+		path = path[:len(path)-1]
+		state[key] = done
+	}
+	keys := make([]string, 0, len(flags))
+	for key := range flags {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys) // so that the reported cycles are deterministic
+	for _, key := range keys {
+		if state[key] == unvisited {
+			visit(key)
+		}
+	}
+	return cycles
+}
@@ -0,0 +1,218 @@
+package ldclient
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"gopkg.in/launchdarkly/go-server-sdk.v4/ldlog"
+	shared "gopkg.in/launchdarkly/go-server-sdk.v4/shared_test"
+)
+
+func makeValidFlag(key string) *FeatureFlag {
+	return &FeatureFlag{
+		Key:          key,
+		On:           true,
+		OffVariation: intPtr(0),
+		Fallthrough:  VariationOrRollout{Variation: intPtr(1)},
+		Variations:   []interface{}{false, true},
+	}
+}
+
+func validationMessages(issues []DataValidationIssue) []string {
+	messages := make([]string, 0, len(issues))
+	for _, i := range issues {
+		messages = append(messages, i.Error())
+	}
+	return messages
+}
+
+func validateFlags(flags ...*FeatureFlag) []DataValidationIssue {
+	items := make(map[string]VersionedData)
+	for _, f := range flags {
+		items[f.Key] = f
+	}
+	return ValidateData(map[VersionedDataKind]map[string]VersionedData{Features: items})
+}
+
+func TestValidateDataAcceptsValidData(t *testing.T) {
+	flag := makeValidFlag("flag")
+	flag.Targets = []Target{{Values: []string{"a"}, Variation: 0}}
+	flag.Rules = []Rule{{
+		Clauses:            []Clause{{Attribute: "name", Op: OperatorMatches, Values: []interface{}{"^b.*", 3}}},
+		VariationOrRollout: VariationOrRollout{Rollout: &Rollout{Variations: []WeightedVariation{{0, 60000, false}, {1, 40000, false}}}},
+	}}
+	flag.Prerequisites = []Prerequisite{{Key: "other", Variation: 0}, {Key: "missing", Variation: 0}}
+	segment := &Segment{Key: "segment", Rules: []SegmentRule{{
+		Clauses: []Clause{{Attribute: "key", Op: OperatorIn, Values: []interface{}{"x"}}},
+		Weight:  intPtr(50000),
+	}}}
+
+	issues := ValidateData(map[VersionedDataKind]map[string]VersionedData{
+		Features: {"flag": flag, "other": makeValidFlag("other")},
+		Segments: {"segment": segment},
+	})
+
+	assert.Nil(t, issues)
+}
+
+func TestValidateDataFindsNonexistentVariations(t *testing.T) {
+	flag := makeValidFlag("flag")
+	flag.OffVariation = intPtr(2)
+	flag.Targets = []Target{{Values: []string{"a"}, Variation: -1}}
+	flag.Rules = []Rule{{ID: "id0", VariationOrRollout: VariationOrRollout{Variation: intPtr(5)}}}
+	flag.Fallthrough = VariationOrRollout{Rollout: &Rollout{Variations: []WeightedVariation{{3, 100000, false}}}}
+
+	assert.Equal(t, []string{
+		"features 'flag': offVariation refers to variation 2, but there are 2 variations",
+		"features 'flag': target 0 refers to variation -1, but there are 2 variations",
+		"features 'flag': rule 0 (id0) refers to variation 5, but there are 2 variations",
+		"features 'flag': fallthrough rollout refers to variation 3, but there are 2 variations",
+	}, validationMessages(validateFlags(flag)))
+}
+
+func TestValidateDataFindsMalformedRollouts(t *testing.T) {
+	flag := makeValidFlag("flag")
+	flag.Rules = []Rule{
+		{VariationOrRollout: VariationOrRollout{}},
+		{VariationOrRollout: VariationOrRollout{Rollout: &Rollout{}}},
+	}
+	flag.Fallthrough = VariationOrRollout{Rollout: &Rollout{Variations: []WeightedVariation{{0, 50000, false}, {1, 40000, false}}}}
+
+	assert.Equal(t, []string{
+		"features 'flag': rule 0 has neither a variation nor a rollout",
+		"features 'flag': rule 1 has a rollout with no variations",
+		"features 'flag': fallthrough has rollout weights that add up to 90000 instead of 100000",
+	}, validationMessages(validateFlags(flag)))
+}
+
+func TestValidateDataFindsBadClauses(t *testing.T) {
+	flag := makeValidFlag("flag")
+	flag.Rules = []Rule{{
+		Clauses: []Clause{
+			{Attribute: "key", Op: "isOneOf", Values: []interface{}{"x"}},
+			{Attribute: "name", Op: OperatorMatches, Values: []interface{}{"ok", "(unclosed"}},
+		},
+		VariationOrRollout: VariationOrRollout{Variation: intPtr(0)},
+	}}
+	segment := &Segment{Key: "segment", Rules: []SegmentRule{
+		{Id: "r", Clauses: []Clause{{Attribute: "key", Op: "", Values: []interface{}{"x"}}}},
+		{Clauses: []Clause{{Attribute: "key", Op: OperatorIn}}, Weight: intPtr(100001)},
+	}}
+
+	issues := ValidateData(map[VersionedDataKind]map[string]VersionedData{
+		Features: {"flag": flag},
+		Segments: {"segment": segment},
+	})
+
+	assert.Equal(t, []string{
+		`features 'flag': rule 0: clause 0 has unknown operator "isOneOf"`,
+		"features 'flag': rule 0: clause 1 has invalid regular expression \"(unclosed\": " +
+			"error parsing regexp: missing closing ): `(unclosed`",
+		`segments 'segment': rule 0 (r): clause 0 has unknown operator ""`,
+		"segments 'segment': rule 1 has weight 100001, which is not between 0 and 100000",
+	}, validationMessages(issues))
+}
+
+func TestValidateDataIgnoresDeletedItems(t *testing.T) {
+	flag := &FeatureFlag{Key: "flag", Deleted: true}
+
+	assert.Nil(t, validateFlags(flag))
+}
+
+func TestValidateDataIgnoresFallthroughOfFlagThatIsOff(t *testing.T) {
+	flag := &FeatureFlag{Key: "flag", On: false, OffVariation: intPtr(0), Variations: []interface{}{true}}
+
+	assert.Nil(t, validateFlags(flag))
+}
+
+func TestValidateDataFindsPrerequisiteCycles(t *testing.T) {
+	a, b, c, d := makeValidFlag("a"), makeValidFlag("b"), makeValidFlag("c"), makeValidFlag("d")
+	a.Prerequisites = []Prerequisite{{Key: "b", Variation: 1}}
+	b.Prerequisites = []Prerequisite{{Key: "a", Variation: 1}}
+	c.Prerequisites = []Prerequisite{{Key: "c", Variation: 1}}
+	d.Prerequisites = []Prerequisite{{Key: "a", Variation: 1}}
+
+	assert.Equal(t, []string{
+		"features 'a': prerequisites form a cycle: a -> b -> a",
+		"features 'b': prerequisites form a cycle: b -> a -> b",
+		"features 'c': prerequisites form a cycle: c -> c",
+	}, validationMessages(validateFlags(a, b, c, d)))
+}
+
+func TestFilterInvalidDataLogsAndOptionallyRejects(t *testing.T) {
+	bad := makeValidFlag("bad")
+	bad.OffVariation = intPtr(9)
+	allData := map[VersionedDataKind]map[string]VersionedData{
+		Features: {"good": makeValidFlag("good"), "bad": bad},
+		Segments: {},
+	}
+
+	mockLog := shared.NewMockLoggers()
+	result := FilterInvalidData(allData, mockLog.Loggers, false)
+	assert.Equal(t, allData, result)
+	assert.Equal(t, []string{"Invalid data: features 'bad': offVariation refers to variation 9, but there are 2 variations"},
+		mockLog.Output[ldlog.Warn])
+
+	mockLog = shared.NewMockLoggers()
+	result = FilterInvalidData(allData, mockLog.Loggers, true)
+	assert.Equal(t, map[VersionedDataKind]map[string]VersionedData{
+		Features: {"good": allData[Features]["good"]},
+		Segments: {},
+	}, result)
+	assert.Equal(t, 2, len(allData[Features])) // original map is not modified
+	assert.Equal(t, 1, len(mockLog.Output[ldlog.Warn]))
+}
+
+func TestValidateUpdatedItemChecksForCycleWithStoredFlags(t *testing.T) {
+	a, b := makeValidFlag("a"), makeValidFlag("b")
+	a.Prerequisites = []Prerequisite{{Key: "b", Variation: 1}}
+	store := NewInMemoryFeatureStore(nil)
+	require.NoError(t, store.Init(map[VersionedDataKind]map[string]VersionedData{Features: {"a": a}}))
+
+	assert.True(t, validateUpdatedItem(Features, b, store, shared.NullLoggers(), true))
+
+	b.Prerequisites = []Prerequisite{{Key: "a", Variation: 1}}
+	mockLog := shared.NewMockLoggers()
+	assert.False(t, validateUpdatedItem(Features, b, store, mockLog.Loggers, true))
+	assert.Equal(t, []string{"Invalid data will be ignored: features 'b': prerequisites form a cycle: b -> a -> b"},
+		mockLog.Output[ldlog.Warn])
+
+	assert.True(t, validateUpdatedItem(Features, b, store, shared.NullLoggers(), false))
+}
+
+type storeWithoutAll struct {
+	FeatureStore
+	gets []string
+}
+
+func (s *storeWithoutAll) All(kind VersionedDataKind) (map[string]VersionedData, error) {
+	panic("All should not be called")
+}
+
+func (s *storeWithoutAll) Get(kind VersionedDataKind, key string) (VersionedData, error) {
+	s.gets = append(s.gets, key)
+	return s.FeatureStore.Get(kind, key)
+}
+
+func TestValidateUpdatedItemReadsOnlyReachablePrerequisites(t *testing.T) {
+	a, b, c, d := makeValidFlag("a"), makeValidFlag("b"), makeValidFlag("c"), makeValidFlag("d")
+	a.Prerequisites = []Prerequisite{{Key: "b", Variation: 1}, {Key: "missing", Variation: 1}}
+	b.Prerequisites = []Prerequisite{{Key: "c", Variation: 1}}
+	c.Prerequisites = []Prerequisite{{Key: "d", Variation: 1}}
+	store := &storeWithoutAll{FeatureStore: NewInMemoryFeatureStore(nil)}
+	require.NoError(t, store.Init(map[VersionedDataKind]map[string]VersionedData{
+		Features: {"b": b, "c": c, "d": d, "unrelated": makeValidFlag("unrelated")},
+	}))
+
+	assert.True(t, validateUpdatedItem(Features, a, store, shared.NullLoggers(), true))
+	assert.Equal(t, []string{"b", "c", "d", "missing"}, store.gets)
+
+	d.Prerequisites = []Prerequisite{{Key: "a", Variation: 1}}
+	require.NoError(t, store.Upsert(Features, d))
+	mockLog := shared.NewMockLoggers()
+	assert.False(t, validateUpdatedItem(Features, a, store, mockLog.Loggers, true))
+	assert.Equal(t, []string{"Invalid data will be ignored: features 'a': prerequisites form a cycle: a -> b -> c -> d -> a"},
+		mockLog.Output[ldlog.Warn])
+}